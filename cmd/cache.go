@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/cache"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/pflag"
+)
+
+// Cache modes keep the entries of --write apart from those of the checks.
+// Both compare byte for byte, but an entry only vouches for the output of
+// its own path: --write formats with formatDocument, which honors --color,
+// while the checks use sqlfmt.Check, which counts input of only whitespace
+// as formatted.
+const (
+	cacheModeWrite = "write"
+	cacheModeCheck = "check"
+)
+
+// cacheOptions holds the flags of the commands that can skip files an earlier
+// run found formatted.
+type cacheOptions struct {
+	enabled  bool
+	location string
+}
+
+// cacheOpts is bound to the cache flags of format, validate and check.
+var cacheOpts cacheOptions
+
+func (o *cacheOptions) register(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.enabled, "cache", false, "Skip files that an earlier run found formatted")
+	flags.StringVar(&o.location, "cache-location", "",
+		"Cache file, implies --cache (default: sqlfmt/cache.json in the user cache directory)")
+}
+
+// open returns the cache selected by the flags. It returns nil, which caches
+// nothing, when caching is disabled or the cache cannot be opened.
+func (o *cacheOptions) open() *cache.Cache {
+	if !o.enabled && o.location == "" {
+		return nil
+	}
+
+	path := o.location
+	if path == "" {
+		var err error
+		if path, err = cache.DefaultPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cache disabled: %v\n", err)
+			return nil
+		}
+	}
+
+	c, err := cache.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cache disabled: %v\n", err)
+		return nil
+	}
+	return c
+}
+
+// saveCache writes c, reporting failures as warnings since the cache only
+// speeds up later runs.
+func saveCache(c *cache.Cache) {
+	if err := c.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// cacheKey identifies the result of checking content with config in mode. It
+// returns an empty key, which is never cached, if config cannot be encoded.
+func cacheKey(mode, content string, config *sqlfmt.Config) string {
+	settings, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	return cache.Key(mode, string(settings), content)
+}
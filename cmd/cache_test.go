@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/cache"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheKey(t *testing.T) {
+	config := sqlfmt.NewDefaultConfig()
+	key := cacheKey(cacheModeCheck, testSQL, config)
+
+	assert.Equal(t, key, cacheKey(cacheModeCheck, testSQL, sqlfmt.NewDefaultConfig()))
+	assert.NotEqual(t, key, cacheKey(cacheModeWrite, testSQL, config))
+	assert.NotEqual(t, key, cacheKey(cacheModeCheck, testSQL+";", config))
+	assert.NotEqual(t, key, cacheKey(cacheModeCheck, testSQL, sqlfmt.NewDefaultConfig().WithIndent("\t")))
+}
+
+// TestValidateFileUsesCache tests that validate skips files the cache knows to
+// be formatted and records files it finds formatted.
+func TestValidateFileUsesCache(t *testing.T) {
+	tmpDir := chdirTemp(t, nil)
+	formatOpts = formatOptions{}
+	require.NoError(t, os.WriteFile("formatted.sql", []byte("SELECT\n  1\n"), 0o644))
+	require.NoError(t, os.WriteFile("messy.sql", []byte("select 1"), 0o644))
+
+	path := filepath.Join(tmpDir, "cache.json")
+	fileCache, err := cache.Open(path)
+	require.NoError(t, err)
+	cmd := &cobra.Command{}
+
+	result := validateFileWithResult(cmd, "formatted.sql", sqlfmt.NewDefaultConfig(), fileCache)
+	assert.True(t, result.Valid)
+	result = validateFileWithResult(cmd, "messy.sql", sqlfmt.NewDefaultConfig(), fileCache)
+	assert.False(t, result.Valid)
+	require.NoError(t, fileCache.Save())
+
+	fileCache, err = cache.Open(path)
+	require.NoError(t, err)
+	config := inputConfig(cmd, sqlfmt.NewDefaultConfig(), "formatted.sql", "SELECT\n  1\n")
+	assert.True(t, fileCache.Has(cacheKey(cacheModeCheck, "SELECT\n  1\n", config)))
+	assert.False(t, fileCache.Has(cacheKey(cacheModeCheck, "select 1", config)))
+
+	// A cached entry is trusted without formatting the file
+	fileCache.Add(cacheKey(cacheModeCheck, "select 1", config))
+	result = validateFileWithResult(cmd, "messy.sql", sqlfmt.NewDefaultConfig(), fileCache)
+	assert.True(t, result.Valid)
+}
+
+func TestCacheOptionsOpen(t *testing.T) {
+	opts := &cacheOptions{}
+	assert.Nil(t, opts.open())
+
+	opts.location = filepath.Join(t.TempDir(), "cache.json")
+	assert.NotNil(t, opts.open())
+}
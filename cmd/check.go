@@ -31,21 +31,10 @@ func init() {
 	rootCmd.AddCommand(checkCmd)
 
 	// Add the same flags as validate command
-	checkCmd.Flags().StringVar(&lang, "lang", "sql", "Dialect")
-	checkCmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	checkCmd.Flags().BoolVar(
-		&uppercase,
-		"uppercase",
-		false,
-		"Uppercase keywords (deprecated)",
-	)
-	checkCmd.Flags().StringVar(
-		&keywordCase,
-		"keyword-case",
-		"preserve",
-		"Keyword case",
-	)
-	checkCmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	formatOpts.register(checkCmd.Flags())
+	cacheOpts.register(checkCmd.Flags())
+	gitOpts.register(checkCmd.Flags())
+	registerStdinFilepath(checkCmd.Flags())
 	checkCmd.Flags().StringVar(
 		&outputFormat,
 		"output",
@@ -36,10 +36,10 @@ func TestCheckCommand(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags before each test
-			lang = "sql"
-			indent = "  "
-			uppercase = false
-			linesBetween = 2
+			formatOpts.lang = "sql"
+			formatOpts.indent = "  "
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
 			outputFormat = "text"
 			showDiff = false
 
@@ -49,10 +49,10 @@ func TestCheckCommand(t *testing.T) {
 				Args: cobra.ArbitraryArgs,
 				RunE: runValidateTest, // Use the same test function as validate
 			}
-			cmd.Flags().StringVar(&lang, "lang", "sql", "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", "sql", "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 			cmd.Flags().StringVar(&outputFormat, "output", "text", "Output format")
 			cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
@@ -111,10 +111,10 @@ func TestCheckCommandFile(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = "sql"
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = "sql"
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = "text"
 	showDiff = false
 
@@ -124,10 +124,10 @@ func TestCheckCommandFile(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", "sql", "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", "sql", "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", "text", "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
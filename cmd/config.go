@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFileName is the file created by config init.
+const defaultConfigFileName = ".sqlfmt.yaml"
+
+var initForce bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, inspect and validate configuration files",
+	Long: `Create, inspect and validate sqlfmt configuration files.
+
+Examples:
+  sqlfmt config init                       # Write a commented .sqlfmt.yaml
+  sqlfmt config show queries/report.sql    # Show the settings used for a file
+  sqlfmt config validate                   # Validate the config files in use
+  sqlfmt config schema                     # Print the JSON Schema for editors`,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init [file]",
+	Short: "Write a configuration file listing all options",
+	Long: `Write a configuration file that lists every option with its default value
+and a short description. The file is named .sqlfmt.yaml unless a name is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigInit,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [file]",
+	Short: "Show the effective configuration and where each value comes from",
+	Long: `Show the configuration that formatting a file would use, resolved from the
+defaults, all applicable config files and the given flags. Each setting is
+annotated with the config file and line, flag or default that set it. Without a
+file, the configuration for standard input in the current directory is shown.
+
+The output is itself a valid configuration file.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigShow,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [files...]",
+	Short: "Validate configuration files",
+	Long: `Validate configuration files, including the files they extend. Unknown keys,
+values of the wrong type and invalid values are reported with their file and
+line. Without arguments, the config files that apply to the current directory
+are validated.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runConfigValidate,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of configuration files",
+	Long: `Print the JSON Schema of configuration files. Editors use it to validate
+and complete settings in YAML, TOML and JSON config files.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd, configShowCmd, configValidateCmd, configSchemaCmd)
+
+	configInitCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite an existing file")
+	formatOpts.register(configShowCmd.Flags())
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) error {
+	path := defaultConfigFileName
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	if _, err := os.Stat(path); err == nil && !initForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+	if err := os.WriteFile(path, []byte(configTemplate), 0o644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Printf("Created %s\n", path)
+	return nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	if formatOpts.noConfig && formatOpts.configPath != "" {
+		return fmt.Errorf("--config cannot be combined with --no-config")
+	}
+
+	filename := ""
+	if len(args) == 1 {
+		filename = args[0]
+	}
+
+	config, configFile, err := loadInputConfig(cmd, filename)
+	if err != nil {
+		return err
+	}
+
+	sources := settingSources(cmd.Flags(), configFile, filename)
+
+	// Inline hints and auto-detection choose the language of existing files
+	if content, err := os.ReadFile(filename); err == nil {
+		if lang, found := sqlfmt.ParseInlineDialectHint(string(content)); found {
+			config.WithLang(lang)
+			sources["language"] = "inline dialect hint"
+		}
+		if formatOpts.autoDetect {
+			if lang, detected := sqlfmt.DetectDialect(filename, string(content)); detected {
+				config.WithLang(lang)
+				sources["language"] = "auto-detection"
+			}
+		}
+	}
+
+	var doc yaml.Node
+	if err := doc.Encode(sqlfmt.NewConfigSettings(config)); err != nil {
+		return err
+	}
+	doc.HeadComment = configFilesComment(configFile.Files())
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key := doc.Content[i]
+		key.LineComment = sources[key.Value]
+		useFlowLists(doc.Content[i+1])
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// settingSources names the origin of every setting: the config file or
+// .editorconfig and line, the flag or the default.
+func settingSources(flags *pflag.FlagSet, configFile *sqlfmt.ConfigFile, filename string) map[string]string {
+	sources := map[string]string{}
+	for _, key := range settingKeys() {
+		sources[key] = "default"
+	}
+	if editorConfig, err := formatOpts.loadEditorConfig(filename); err == nil {
+		for key, source := range editorConfig.SettingSources() {
+			sources[key] = source
+		}
+	}
+	for key, source := range configFile.SettingSources(filename) {
+		sources[key] = source
+	}
+
+	flags.Visit(func(flag *pflag.Flag) {
+		switch {
+		case flag.Name == "lang" && formatOpts.autoDetect:
+			return
+		case flag.Name == "uppercase" && !formatOpts.uppercase:
+			return
+		}
+		if key, ok := flagSettingKey(flag.Name); ok {
+			sources[key] = "--" + flag.Name
+		}
+	})
+
+	return sources
+}
+
+// settingKeys lists the top-level keys of a configuration file's settings.
+func settingKeys() []string {
+	var doc yaml.Node
+	_ = doc.Encode(sqlfmt.NewConfigSettings(sqlfmt.NewDefaultConfig()))
+
+	keys := make([]string, 0, len(doc.Content)/2)
+	for i := 0; i < len(doc.Content); i += 2 {
+		keys = append(keys, doc.Content[i].Value)
+	}
+	return keys
+}
+
+// flagSettingKey maps a formatting flag to the config file key it overrides.
+func flagSettingKey(flagName string) (string, bool) {
+	switch flagName {
+	case "lang":
+		return "language", true
+	case "uppercase":
+		return "keyword_case", true
+	case "lines-between":
+		return "lines_between_queries", true
+	case "param", "positional-param":
+		return "params", true
+	case "auto-detect", "config", "no-config":
+		return "", false
+	default:
+		return strings.ReplaceAll(flagName, "-", "_"), true
+	}
+}
+
+func configFilesComment(files []string) string {
+	if len(files) == 0 {
+		return "No config files found"
+	}
+	return "Config files, lowest precedence first:\n  " + strings.Join(files, "\n  ")
+}
+
+// useFlowLists writes the lists below node in flow style, e.g. [cyan, bold].
+func useFlowLists(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode {
+		node.Style = yaml.FlowStyle
+		return
+	}
+	for _, child := range node.Content {
+		useFlowLists(child)
+	}
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	var configFiles []*sqlfmt.ConfigFile
+	var failed bool
+
+	report := func(err error) {
+		failed = true
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if len(args) == 0 {
+		configFile, err := sqlfmt.LoadConfigFile()
+		if err != nil {
+			report(err)
+		} else {
+			configFiles = append(configFiles, configFile)
+		}
+	}
+	for _, path := range args {
+		configFile, err := sqlfmt.ReadConfigFile(path)
+		if err != nil {
+			report(err)
+			continue
+		}
+		configFiles = append(configFiles, configFile)
+	}
+
+	for _, configFile := range configFiles {
+		files := configFile.Files()
+		if len(files) == 0 {
+			fmt.Println("No config file found")
+			continue
+		}
+		if err := configFile.Validate(); err != nil {
+			report(err)
+			continue
+		}
+		for _, file := range files {
+			fmt.Printf("%s: ok\n", file)
+		}
+	}
+
+	if failed {
+		cmd.SilenceUsage = true
+		return &exitStatusError{code: exitFailure}
+	}
+	return nil
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	_, err := os.Stdout.Write(sqlfmt.ConfigSchema())
+	return err
+}
+
+// configTemplate is written by config init. It sets every option to its
+// default value, so that it can be edited without consulting the documentation.
+const configTemplate = `# sqlfmt configuration file
+#
+# Settings apply to SQL files in this directory and below. Config files in
+# parent directories (up to the git root) and ~/.sqlfmtrc are merged in, with
+# nearer files taking precedence. Command-line flags override all of them.
+
+# Stop searching parent directories and the home directory for config files
+# root: true
+
+# Build on another config file (relative path) or a built-in preset:
+# default, uppercase, lowercase, aligned, compact
+# extends: ../shared/sqlfmt.yaml
+
+# SQL dialect: sql, postgresql, mysql, pl/sql, db2, n1ql, sqlite
+language: sql
+
+# Indentation string (spaces or tabs)
+indent: "  "
+
+# Keyword casing: preserve, uppercase, lowercase, dialect
+keyword_case: preserve
+
+# Number of blank lines between queries
+lines_between_queries: 2
+
+# Vertically align SELECT columns, UPDATE assignments and INSERT values
+align_column_names: false
+align_assignments: false
+align_values: false
+
+# Maximum line length before long expressions are wrapped (0 = unlimited)
+max_line_length: 0
+
+# Keep the relative indentation of comments
+preserve_comment_indent: false
+
+# Minimum number of spaces before an inline comment
+comment_min_spacing: 1
+
+# JOIN indentation style: default, root-level
+join_indent_style: default
+
+# Keep empty lines between consecutive comments
+preserve_empty_lines_between_comments: false
+
+# Line ending of the output: auto (keep the input's), lf, crlf
+line_ending: auto
+
+# Always end the output with exactly one newline; otherwise a final newline
+# of the input is kept
+insert_final_newline: false
+
+# Colors used by --color and the pretty commands. Categories that are not
+# listed keep their default colors; an empty list disables coloring.
+# color:
+#   reserved_words: [cyan, bold]
+#   strings: [green]
+#   numbers: [bright-blue]
+#   booleans: [purple, bold]
+#   comments: [gray]
+#   function_calls: [bright-cyan]
+
+# Placeholder replacements, either named or positional
+# params:
+#   named:
+#     tenant: "'acme'"
+#   positional: ["1", "'active'"]
+
+# Entries that extend the tokenizer of the selected dialect
+# tokenizer:
+#   reserved_words: []
+#   reserved_top_level_words: []
+#   reserved_newline_words: []
+#   reserved_top_level_words_no_indent: []
+#   string_types: []
+#   open_parens: []
+#   close_parens: []
+#   indexed_placeholder_types: []
+#   named_placeholder_types: []
+#   line_comment_types: []
+#   special_word_chars: []
+
+# Settings for files matching glob patterns, relative to this file.
+# Matching blocks are applied in order after the settings above.
+# overrides:
+#   - files: ["migrations/**/*.sql"]
+#     language: postgresql
+#     max_line_length: 120
+`
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runConfigCommand runs a config subcommand in a fresh command and returns its stdout.
+func runConfigCommand(t *testing.T, runE func(*cobra.Command, []string) error, args ...string) (string, error) {
+	t.Helper()
+
+	formatOpts = formatOptions{}
+	initForce = false
+
+	cmd := &cobra.Command{
+		Use:  "config",
+		Args: cobra.ArbitraryArgs,
+		RunE: runE,
+	}
+	formatOpts.register(cmd.Flags())
+	cmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite an existing file")
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	return buf.String(), err
+}
+
+func TestConfigInitCommand(t *testing.T) {
+	chdirTemp(t, nil)
+
+	output, err := runConfigCommand(t, runConfigInit)
+	require.NoError(t, err)
+	assert.Equal(t, "Created .sqlfmt.yaml\n", output)
+
+	content, err := os.ReadFile(".sqlfmt.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, configTemplate, string(content))
+
+	// The template is valid and lists the defaults
+	configFile, err := sqlfmt.ParseConfigFile(".sqlfmt.yaml", content)
+	require.NoError(t, err)
+	require.NoError(t, configFile.Validate())
+	config := sqlfmt.NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	assert.Equal(t, sqlfmt.NewDefaultConfig(), config)
+
+	_, err = runConfigCommand(t, runConfigInit)
+	require.EqualError(t, err, ".sqlfmt.yaml already exists (use --force to overwrite)")
+
+	require.NoError(t, os.WriteFile(".sqlfmt.yaml", []byte("language: mysql\n"), 0o644))
+	_, err = runConfigCommand(t, runConfigInit, "--force")
+	require.NoError(t, err)
+	content, err = os.ReadFile(".sqlfmt.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, configTemplate, string(content))
+
+	output, err = runConfigCommand(t, runConfigInit, "custom.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "Created custom.yaml\n", output)
+	assert.FileExists(t, "custom.yaml")
+}
+
+func TestConfigShowCommand(t *testing.T) {
+	tmpDir := chdirTemp(t, nil)
+	config := "language: mysql\nmax_line_length: 100\noverrides:\n  - files: [\"*.pg.sql\"]\n    language: postgresql\n"
+	require.NoError(t, os.WriteFile(".sqlfmt.yaml", []byte(config), 0o644))
+	configPath := filepath.Join(tmpDir, ".sqlfmt.yaml")
+
+	output, err := runConfigCommand(t, runConfigShow)
+	require.NoError(t, err)
+	assert.Contains(t, output, "# Config files, lowest precedence first:\n#   "+configPath+"\n")
+	assert.Contains(t, output, "language: mysql # "+configPath+":1\n")
+	assert.Contains(t, output, "max_line_length: 100 # "+configPath+":2\n")
+	assert.Contains(t, output, "indent: '  ' # default\n")
+
+	output, err = runConfigCommand(t, runConfigShow, "--indent", "\t", "report.pg.sql")
+	require.NoError(t, err)
+	assert.Contains(t, output, "language: postgresql # "+configPath+":5 (overrides[0])\n")
+	assert.Contains(t, output, "indent: \"\\t\" # --indent\n")
+
+	require.NoError(t, os.WriteFile(".editorconfig", []byte("root = true\n[*.sql]\nindent_size = 4\n"), 0o644))
+	output, err = runConfigCommand(t, runConfigShow, "report.sql")
+	require.NoError(t, err)
+	assert.Contains(t, output, "indent: '    ' # "+filepath.Join(tmpDir, ".editorconfig")+":3\n")
+
+	output, err = runConfigCommand(t, runConfigShow, "--no-config")
+	require.NoError(t, err)
+	assert.Contains(t, output, "# No config files found\n")
+	assert.Contains(t, output, "language: sql # default\n")
+}
+
+func TestConfigValidateCommand(t *testing.T) {
+	chdirTemp(t, map[string]string{"good.yaml": "language: mysql\n", "bad.yaml": "langauge: mysql\n"})
+
+	output, err := runConfigCommand(t, runConfigValidate)
+	require.NoError(t, err)
+	assert.Equal(t, "No config file found\n", output)
+
+	output, err = runConfigCommand(t, runConfigValidate, "good.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "good.yaml: ok\n", output)
+
+	output, err = runConfigCommand(t, runConfigValidate, "good.yaml", "bad.yaml")
+	var exitErr *exitStatusError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitFailure, exitErr.code)
+	assert.Equal(t, "good.yaml: ok\n", output)
+
+	// Values of the wrong type fail like unknown keys
+	writeTestFiles(t, map[string]string{"indent.yaml": "indent: 4\n"})
+	_, err = runConfigCommand(t, runConfigValidate, "indent.yaml")
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitFailure, exitErr.code)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/daemon"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Format requests read as JSON lines from standard input",
+	Long: `Answer formatting requests read from standard input, one JSON object per
+line, with one JSON line each on standard output. Editor plugins keep the
+process running instead of starting sqlfmt for every buffer; compiled
+formatters and resolved configurations are reused between requests.
+
+Requests have an "id", which the response repeats, a "method" and "params":
+
+  format   {"query", "path", "language", "cursor"} formats query like the
+           file at path, the same as "sqlfmt format path" would. With a
+           byte offset cursor, the result says where the cursor belongs.
+           Result: {"formatted", "language", "cursor"}
+  detect   {"query", "path"} detects the dialect.
+           Result: {"language", "detected"}
+
+Failed requests get {"id", "error": {"code", "message"}}. The daemon exits
+when standard input is closed.
+
+Examples:
+  echo '{"id":1,"method":"format","params":{"query":"select 1"}}' | sqlfmt daemon`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	formatOpts.register(daemonCmd.Flags())
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	baseConfig, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	server := daemon.NewServer(os.Stdin, os.Stdout,
+		func(path string) *sqlfmt.Config {
+			return fileConfig(cmd, baseConfig, path)
+		},
+		inputLanguage)
+	cmd.SilenceUsage = true
+	return server.Run()
+}
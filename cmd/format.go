@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 
+	"github.com/MeKo-Christian/go-sqlfmt/internal/cache"
+	"github.com/MeKo-Christian/go-sqlfmt/internal/diff"
+	"github.com/MeKo-Christian/go-sqlfmt/internal/watch"
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
 	"github.com/spf13/cobra"
 )
@@ -15,22 +19,17 @@ const (
 )
 
 var (
-	lang                  string
-	indent                string
-	write                 bool
-	color                 bool
-	uppercase             bool
-	keywordCase           string
-	linesBetween          int
-	autoDetect            bool
-	alignColumnNames      bool
-	alignAssignments      bool
-	alignValues           bool
-	maxLineLength         int
-	preserveCommentIndent bool
-	commentMinSpacing     int
+	write         bool
+	color         bool
+	listDifferent bool
+	printDiff     bool
+	checkOnly     bool
+	sourceMapPath string
 )
 
+// stdinName is the name reported for standard input by --list and --diff.
+const stdinName = "<standard input>"
+
 var formatCmd = &cobra.Command{
 	Use:   "format [files...]",
 	Short: "Format SQL files or stdin",
@@ -39,9 +38,19 @@ var formatCmd = &cobra.Command{
 Examples:
   sqlfmt format file.sql                    # Format file to stdout
   sqlfmt format --write file.sql           # Format file in place
+  sqlfmt format -w --backup=.orig file.sql # Keep the original as file.sql.orig
   cat file.sql | sqlfmt format -            # Format stdin
+  sqlfmt format --stdin-filepath=db/q.sql < buffer.sql # Format stdin like db/q.sql
   sqlfmt format --lang=postgresql file.sql # Format with PostgreSQL dialect
-  sqlfmt format --color file.sql           # Format with ANSI colors`,
+  sqlfmt format --color file.sql           # Format with ANSI colors
+  sqlfmt format -l *.sql                   # List files whose formatting differs
+  sqlfmt format -d file.sql                # Print a unified diff instead
+  sqlfmt format --check *.sql              # Exit with status 1 if any file differs
+  sqlfmt format --write --cache *.sql      # Skip files formatted in an earlier run
+  sqlfmt format --write --changed          # Format SQL files changed in git
+  sqlfmt format --write --staged           # Format and re-stage staged SQL files
+  sqlfmt format --source-map=q.map.json q.sql # Also write where each output token came from
+  sqlfmt format --watch queries/           # Format SQL files in place whenever they are saved`,
 	Args: cobra.ArbitraryArgs,
 	RunE: runFormat,
 }
@@ -49,38 +58,43 @@ Examples:
 func init() {
 	rootCmd.AddCommand(formatCmd)
 
-	formatCmd.Flags().StringVar(&lang, "lang", defaultSQLDialect,
-		"SQL dialect (sql, postgresql, mysql, pl/sql, db2, n1ql, sqlite)")
-	formatCmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	formatOpts.register(formatCmd.Flags())
+	cacheOpts.register(formatCmd.Flags())
+	gitOpts.register(formatCmd.Flags())
 	formatCmd.Flags().BoolVarP(&write, "write", "w", false, "Write result to file instead of stdout")
+	formatCmd.Flags().StringVar(&backupSuffix, "backup", "",
+		"With --write, keep the original of each changed file under its name plus this suffix")
 	formatCmd.Flags().BoolVar(&color, "color", false, "Enable ANSI color formatting")
-	formatCmd.Flags().BoolVar(
-		&uppercase,
-		"uppercase",
-		false,
-		"Deprecated: convert keywords to uppercase",
-	)
-	formatCmd.Flags().StringVar(
-		&keywordCase,
-		"keyword-case",
-		"preserve",
-		"Keyword casing options",
-	)
-	formatCmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	formatCmd.Flags().BoolVar(&autoDetect, "auto-detect", false,
-		"Automatically detect SQL dialect from file extension and content")
-	formatCmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names vertically")
-	formatCmd.Flags().BoolVar(&alignAssignments, "align-assignments", false,
-		"Align UPDATE assignment operators vertically")
-	formatCmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT VALUES vertically")
-	formatCmd.Flags().IntVar(&maxLineLength, "max-line-length", 0, "Maximum line length (0 = unlimited)")
-	formatCmd.Flags().BoolVar(&preserveCommentIndent, "preserve-comment-indent", false,
-		"Preserve relative indentation of comments")
-	formatCmd.Flags().IntVar(&commentMinSpacing, "comment-min-spacing", 1, "Minimum spaces before inline comments")
+	formatCmd.Flags().BoolVarP(&listDifferent, "list", "l", false,
+		"List files whose formatting differs instead of printing them")
+	formatCmd.Flags().BoolVarP(&printDiff, "diff", "d", false,
+		"Print a unified diff instead of the formatted content")
+	formatCmd.Flags().BoolVar(&checkOnly, "check", false,
+		"Exit with a non-zero status if any file needs formatting, without writing")
+	formatCmd.Flags().StringVar(&sourceMapPath, "source-map", "",
+		"Write a JSON source map linking the output offset of each token to its input offset to this file")
+	registerStdinFilepath(formatCmd.Flags())
+	formatCmd.Flags().StringVar(&watchDir, "watch", "",
+		"Keep running and format the SQL files under this directory in place whenever they change")
+	formatCmd.Flags().DurationVar(&watchDebounce, "watch-debounce", watch.DefaultDebounce,
+		"With --watch, how long a changed file must stay unchanged before it is formatted")
 }
 
 func runFormat(cmd *cobra.Command, args []string) error {
-	config := buildConfig(cmd)
+	if checkOnly && write {
+		return fmt.Errorf("--check cannot be combined with --write")
+	}
+	if backupSuffix != "" && !write && watchDir == "" {
+		return fmt.Errorf("--backup requires --write or --watch")
+	}
+	if sourceMapPath != "" && (color || reportOnly()) {
+		return fmt.Errorf("--source-map cannot be combined with --color, --list, --diff or --check")
+	}
+
+	config, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Load ignore file if available
 	ignoreFile, err := sqlfmt.LoadIgnoreFile()
@@ -89,252 +103,322 @@ func runFormat(cmd *cobra.Command, args []string) error {
 	}
 
 	// If no args or args is "-", read from stdin
-	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
-		return formatStdin(config)
+	readStdin := watchDir == "" && !gitOpts.enabled() && (len(args) == 0 || (len(args) == 1 && args[0] == "-"))
+	if err := checkStdinFilepath(readStdin); err != nil {
+		return err
 	}
 
-	// Process files, filtering out ignored ones
-	for _, filename := range args {
-		if ignoreFile.ShouldIgnore(filename) {
-			continue
-		}
-		if err := formatFile(filename, config); err != nil {
-			return fmt.Errorf("failed to format %s: %w", filename, err)
-		}
+	if watchDir != "" {
+		return runWatch(cmd, args, config, ignoreFile)
 	}
 
-	return nil
-}
+	files, err := gitOpts.selectFiles(args)
+	if err != nil {
+		return err
+	}
+	if sourceMapPath != "" && len(files) > 1 {
+		return fmt.Errorf("--source-map requires a single input")
+	}
 
-func buildConfig(cmd *cobra.Command) *sqlfmt.Config {
-	config := sqlfmt.NewDefaultConfig()
+	// Errors from here on are about the inputs, not the usage
+	cmd.SilenceUsage = true
 
-	// Load config file if available
-	if configFile, err := sqlfmt.LoadConfigFile(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to load config file: %v\n", err)
-	} else {
-		if err := configFile.ApplyToConfig(config); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to apply config file: %v\n", err)
+	// The report modes report inputs that fail and go on with the others, and
+	// exit with exitError so that CI can tell failures from unformatted files
+	changed, failed := false, false
+	inputFailed := func(err error) error {
+		if !reportOnly() {
+			return err
 		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed = true
+		return nil
 	}
 
-	// Command-line flags override config file settings
-	applyCommandLineFlags(cmd, config)
+	if readStdin {
+		if changed, err = formatStdin(cmd, config); err != nil {
+			if err := inputFailed(err); err != nil {
+				return err
+			}
+		}
+	} else {
+		// Only runs that do not print the formatted content can skip files
+		// Cached files are skipped, so they would get no source map
+		var fileCache *cache.Cache
+		if (write || reportOnly()) && sourceMapPath == "" {
+			fileCache = cacheOpts.open()
+			defer saveCache(fileCache)
+		}
 
-	if color {
-		config.WithColorConfig(sqlfmt.NewDefaultColorConfig())
+		// Process files, filtering out ignored ones
+		for _, filename := range files {
+			if ignoreFile.ShouldIgnore(filename) {
+				continue
+			}
+			fileChanged, err := formatFile(cmd, filename, config, fileCache)
+			if err != nil {
+				if err := inputFailed(fmt.Errorf("failed to format %s: %w", filename, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			changed = changed || fileChanged
+		}
 	}
 
-	return config
+	switch {
+	case failed:
+		return &exitStatusError{code: exitError}
+	case checkOnly && changed:
+		return &exitStatusError{code: exitUnformatted}
+	}
+
+	return nil
+}
+
+// reportOnly reports whether output is limited to --list, --diff or --check results.
+func reportOnly() bool {
+	return listDifferent || printDiff || checkOnly
 }
 
-func applyCommandLineFlags(cmd *cobra.Command, config *sqlfmt.Config) {
-	// Handle auto-detection first (only if explicitly requested)
-	if cmd.Flags().Changed("auto-detect") && autoDetect {
-		// Auto-detection will be handled per-file in formatFile
-		// For now, don't set language from --lang flag if auto-detect is used
-		return
+// reportDifference prints the --list and --diff output for an input whose
+// formatting differs and reports whether it does.
+func reportDifference(name, original string, config *sqlfmt.Config) bool {
+	result, _ := sqlfmt.Check(original, config)
+	if result.Valid {
+		return false
 	}
 
-	// Set language (only if explicitly provided via flag)
-	if cmd.Flags().Changed("lang") {
-		applyLanguageFlag(config)
+	if listDifferent {
+		fmt.Println(name)
 	}
+	if printDiff {
+		fmt.Print(diff.Unified("a/"+name, "b/"+name, original, result.Formatted))
+	}
+	return true
+}
 
-	// Set indentation (only if explicitly provided)
-	if cmd.Flags().Changed("indent") {
-		config.WithIndent(indent)
+// buildConfig creates the configuration for standard input and validates the
+// config file options. A config file named with --config must load; problems
+// with discovered config files are only reported as warnings.
+func buildConfig(cmd *cobra.Command) (*sqlfmt.Config, error) {
+	if formatOpts.noConfig && formatOpts.configPath != "" {
+		return nil, fmt.Errorf("--config cannot be combined with --no-config")
+	}
+	if formatOpts.configPath != "" {
+		configFile, err := sqlfmt.ReadConfigFile(formatOpts.configPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := configFile.ApplyToConfig(sqlfmt.NewDefaultConfig()); err != nil {
+			return nil, err
+		}
 	}
 
-	// Handle keyword casing
-	applyKeywordCaseFlags(cmd, config)
+	return resolveConfig(cmd, ""), nil
+}
 
-	// Set lines between queries (only if explicitly provided)
-	if cmd.Flags().Changed("lines-between") {
-		config.WithLinesBetweenQueries(linesBetween)
+// resolveConfig builds the configuration for one input from scratch. Problems
+// with config files are reported as warnings.
+func resolveConfig(cmd *cobra.Command, filename string) *sqlfmt.Config {
+	config, _, err := loadInputConfig(cmd, filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
+	return config
+}
 
-	// Set alignment options (only if explicitly provided)
-	if cmd.Flags().Changed("align-column-names") {
-		config.WithAlignColumnNames(alignColumnNames)
-	}
-	if cmd.Flags().Changed("align-assignments") {
-		config.WithAlignAssignments(alignAssignments)
+// loadInputConfig builds the configuration for one input: defaults, then the
+// resolved config file including the overrides matching filename, then
+// command-line flags. The flags are applied even if the config file fails.
+func loadInputConfig(cmd *cobra.Command, filename string) (*sqlfmt.Config, *sqlfmt.ConfigFile, error) {
+	config := sqlfmt.NewDefaultConfig()
+
+	// EditorConfig provides defaults that sqlfmt config files override
+	editorConfig, editorConfigErr := formatOpts.loadEditorConfig(filename)
+	if editorConfigErr == nil {
+		editorConfig.ApplyToConfig(config)
 	}
-	if cmd.Flags().Changed("align-values") {
-		config.WithAlignValues(alignValues)
+
+	configFile, err := formatOpts.loadConfigFile(filename)
+	switch {
+	case err != nil:
+		configFile = &sqlfmt.ConfigFile{}
+		err = fmt.Errorf("failed to load config file: %w", err)
+	case filename != "":
+		if applyErr := configFile.ApplyToConfigForPath(config, filename); applyErr != nil {
+			err = fmt.Errorf("failed to apply config file for %s: %w", filename, applyErr)
+		}
+	default:
+		if applyErr := configFile.ApplyToConfig(config); applyErr != nil {
+			err = fmt.Errorf("failed to apply config file: %w", applyErr)
+		}
 	}
 
-	// Set max line length (only if explicitly provided)
-	if cmd.Flags().Changed("max-line-length") {
-		config.WithMaxLineLength(maxLineLength)
+	if err == nil && editorConfigErr != nil {
+		err = fmt.Errorf("failed to load .editorconfig: %w", editorConfigErr)
 	}
 
-	// Set comment options (only if explicitly provided)
-	if cmd.Flags().Changed("preserve-comment-indent") {
-		config.WithPreserveCommentIndent(preserveCommentIndent)
+	// Command-line flags override config file settings
+	formatOpts.apply(cmd.Flags(), config)
+
+	// Colors from the config file take precedence over the default palette
+	if color && (config.ColorConfig == nil || config.ColorConfig.Empty()) {
+		config.WithColorConfig(sqlfmt.NewDefaultColorConfig())
 	}
-	if cmd.Flags().Changed("comment-min-spacing") {
-		config.WithCommentMinSpacing(commentMinSpacing)
+
+	return config, configFile, err
+}
+
+// colorOutput reports whether cmd prints colored output. The --list, --diff
+// and --check modes compare plain output even when --color is set.
+func colorOutput(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case "pretty-format", "pretty-print":
+		return true
 	}
+	return color && !reportOnly()
 }
 
-func applyLanguageFlag(config *sqlfmt.Config) {
-	switch strings.ToLower(lang) {
-	case defaultSQLDialect, "standard":
-		config.WithLang(sqlfmt.StandardSQL)
-	case "postgresql", "postgres":
-		config.WithLang(sqlfmt.PostgreSQL)
-	case "mysql", "mariadb":
-		config.WithLang(sqlfmt.MySQL)
-	case "pl/sql", "plsql", "oracle":
-		config.WithLang(sqlfmt.PLSQL)
-	case "db2":
-		config.WithLang(sqlfmt.DB2)
-	case "n1ql":
-		config.WithLang(sqlfmt.N1QL)
-	case "sqlite":
-		config.WithLang(sqlfmt.SQLite)
-	default:
-		fmt.Fprintf(os.Stderr, "Warning: unknown language %s, using standard SQL\n", lang)
-		config.WithLang(sqlfmt.StandardSQL)
+// inputConfig resolves the configuration for a single input. Files get their
+// own configuration from the config files that apply to them, while standard
+// input uses baseConfig. Inline dialect hints and auto-detection then choose
+// the language. Every command resolves inputs this way so they all agree.
+func inputConfig(cmd *cobra.Command, baseConfig *sqlfmt.Config, filename, content string) *sqlfmt.Config {
+	config := fileConfig(cmd, baseConfig, filename)
+	if lang, found := inputLanguage(filename, content); found {
+		config.WithLang(lang)
 	}
+	return config
 }
 
-func applyKeywordCaseFlags(cmd *cobra.Command, config *sqlfmt.Config) {
-	// --uppercase flag takes precedence for backward compatibility
-	if cmd.Flags().Changed("uppercase") && uppercase {
-		config.WithKeywordCase(sqlfmt.KeywordCaseUppercase)
-	} else if cmd.Flags().Changed("keyword-case") {
-		applyKeywordCaseFlag(config)
+// fileConfig resolves the configuration for an input before its content is
+// known: that of the config files for filename, or baseConfig for standard
+// input.
+func fileConfig(cmd *cobra.Command, baseConfig *sqlfmt.Config, filename string) *sqlfmt.Config {
+	var config *sqlfmt.Config
+	if filename != "" {
+		config = resolveConfig(cmd, filename)
+	} else {
+		config = baseConfig.Clone()
 	}
+
+	// Color settings from config files must not leak into plain output
+	if !colorOutput(cmd) {
+		config.WithColorConfig(&sqlfmt.ColorConfig{})
+	}
+	return config
 }
 
-func applyKeywordCaseFlag(config *sqlfmt.Config) {
-	switch strings.ToLower(keywordCase) {
-	case "preserve":
-		config.WithKeywordCase(sqlfmt.KeywordCasePreserve)
-	case "uppercase":
-		config.WithKeywordCase(sqlfmt.KeywordCaseUppercase)
-	case "lowercase":
-		config.WithKeywordCase(sqlfmt.KeywordCaseLowercase)
-	case "dialect":
-		config.WithKeywordCase(sqlfmt.KeywordCaseDialect)
-	default:
-		fmt.Fprintf(os.Stderr, "Warning: unknown keyword-case %s, using preserve\n", keywordCase)
-		config.WithKeywordCase(sqlfmt.KeywordCasePreserve)
+// inputLanguage chooses the language of an input by auto-detection, if
+// enabled, or else by an inline dialect hint. It reports false if neither
+// applies and the configured language stays.
+func inputLanguage(filename, content string) (sqlfmt.Language, bool) {
+	if formatOpts.autoDetect {
+		if detectedLang, detected := sqlfmt.DetectDialect(filename, content); detected {
+			return detectedLang, true
+		}
 	}
+	return sqlfmt.ParseInlineDialectHint(content)
 }
 
-func formatStdin(baseConfig *sqlfmt.Config) error {
+// formatStdin formats standard input and reports whether its formatting differs.
+func formatStdin(cmd *cobra.Command, baseConfig *sqlfmt.Config) (bool, error) {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		return fmt.Errorf("failed to read stdin: %w", err)
-	}
-
-	config := baseConfig
-
-	// For stdin, auto-detection only uses content (no file path available)
-	if autoDetect {
-		detectedLang, detected := sqlfmt.DetectDialect("", string(input))
-		if detected {
-			// Create a new config with detected language
-			config = &sqlfmt.Config{
-				Language:            detectedLang,
-				Indent:              baseConfig.Indent,
-				KeywordCase:         baseConfig.KeywordCase,
-				LinesBetweenQueries: baseConfig.LinesBetweenQueries,
-				Params:              baseConfig.Params,
-				ColorConfig:         baseConfig.ColorConfig,
-				TokenizerConfig:     baseConfig.TokenizerConfig,
-			}
+		return false, fmt.Errorf("failed to read stdin: %w", err)
+	}
+
+	if stdinIgnored() {
+		if !reportOnly() {
+			fmt.Print(string(input))
 		}
+		return false, nil
 	}
 
-	var formatted string
-	if color {
-		formatted = sqlfmt.PrettyFormat(string(input), config)
-	} else {
-		formatted = sqlfmt.Format(string(input), config)
+	// Without --stdin-filepath, auto-detection only uses the content
+	config := inputConfig(cmd, baseConfig, stdinFilepath, string(input))
+
+	if reportOnly() {
+		return reportDifference(stdinDisplayName(stdinName), string(input), config), nil
+	}
+
+	formatted, err := formatDocument(string(input), config)
+	if err != nil {
+		return false, err
 	}
 
 	fmt.Print(formatted)
-	return nil
+	return false, nil
 }
 
-func formatFile(filename string, baseConfig *sqlfmt.Config) error {
-	content, err := os.ReadFile(filename)
+// formatFile formats a single file and reports whether its formatting differs.
+// Only the --list, --diff and --check modes determine the difference; otherwise
+// the result is always false. Files that fileCache knows to be formatted are
+// skipped, and files found formatted are added to it.
+func formatFile(cmd *cobra.Command, filename string, baseConfig *sqlfmt.Config, fileCache *cache.Cache) (bool, error) {
+	content, err := readInput(filename)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return false, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	contentStr := string(content)
 
 	// Skip empty files - they're valid and don't need formatting
 	if strings.TrimSpace(contentStr) == "" {
-		if write {
+		if write && !reportOnly() {
 			fmt.Printf("Skipped %s (empty file)\n", filename)
 		}
-		return nil
+		return false, nil
 	}
 
-	// Start with base config
-	config := baseConfig
+	config := inputConfig(cmd, baseConfig, filename, contentStr)
 
-	// Load per-directory config file for this specific file
-	if dirConfig, err := sqlfmt.LoadConfigFileForPath(filename); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to load config file for %s: %v\n", filename, err)
-	} else {
-		// Apply directory-specific config (this will override global config)
-		if err := dirConfig.ApplyToConfig(config); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to apply config file for %s: %v\n", filename, err)
+	if reportOnly() {
+		key := cacheKey(cacheModeCheck, contentStr, config)
+		if key != "" && fileCache.Has(key) {
+			return false, nil
 		}
-	}
-
-	// Check for inline dialect hints (these override config file settings)
-	if hintedLang, found := sqlfmt.ParseInlineDialectHint(contentStr); found {
-		config = &sqlfmt.Config{
-			Language:            hintedLang,
-			Indent:              config.Indent,
-			KeywordCase:         config.KeywordCase,
-			LinesBetweenQueries: config.LinesBetweenQueries,
-			Params:              config.Params,
-			ColorConfig:         config.ColorConfig,
-			TokenizerConfig:     config.TokenizerConfig,
+		changed := reportDifference(filename, contentStr, config)
+		if !changed && key != "" {
+			fileCache.Add(key)
 		}
+		if changed && write {
+			// Like gofmt -l -w, write silently and only report the file names
+			if _, err := writeInput(filename, contentStr, sqlfmt.FormatDocument(contentStr, config)); err != nil {
+				return false, fmt.Errorf("failed to write file: %w", err)
+			}
+		}
+		return changed, nil
 	}
 
-	// Handle auto-detection if enabled (this overrides everything else)
-	if autoDetect {
-		detectedLang, detected := sqlfmt.DetectDialect(filename, contentStr)
-		if detected {
-			// Create a new config with detected language, preserving other settings
-			config = &sqlfmt.Config{
-				Language:            detectedLang,
-				Indent:              config.Indent,
-				KeywordCase:         config.KeywordCase,
-				LinesBetweenQueries: config.LinesBetweenQueries,
-				Params:              config.Params,
-				ColorConfig:         config.ColorConfig,
-				TokenizerConfig:     config.TokenizerConfig,
-			}
+	var key string
+	if write {
+		key = cacheKey(cacheModeWrite, contentStr, config)
+		if key != "" && fileCache.Has(key) {
+			return false, nil
 		}
 	}
 
-	var formatted string
-	if color {
-		formatted = sqlfmt.PrettyFormat(contentStr, config)
-	} else {
-		formatted = sqlfmt.Format(contentStr, config)
+	formatted, err := formatDocument(contentStr, config)
+	if err != nil {
+		return false, err
 	}
 
 	if write {
-		// Write back to file
-		if err := os.WriteFile(filename, []byte(formatted), 0o644); err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
+		// Write back to file, skipping files that are already formatted
+		written, err := writeInput(filename, contentStr, formatted)
+		if err != nil {
+			return false, fmt.Errorf("failed to write file: %w", err)
+		}
+		if !written {
+			if key != "" {
+				fileCache.Add(key)
+			}
+			return false, nil
 		}
 		fmt.Printf("Formatted %s", filename)
-		if autoDetect && config.Language != baseConfig.Language {
+		if formatOpts.autoDetect && config.Language != baseConfig.Language {
 			fmt.Printf(" (detected as %s)", config.Language)
 		}
 		fmt.Println()
@@ -343,5 +427,26 @@ func formatFile(filename string, baseConfig *sqlfmt.Config) error {
 		fmt.Print(formatted)
 	}
 
-	return nil
+	return false, nil
+}
+
+// formatDocument formats the content of one input for printing or writing.
+// With --source-map, it also writes the source map of the result.
+func formatDocument(content string, config *sqlfmt.Config) (string, error) {
+	switch {
+	case color:
+		return sqlfmt.PrettyFormatDocument(content, config), nil
+	case sourceMapPath == "":
+		return sqlfmt.FormatDocument(content, config), nil
+	}
+
+	formatted, sourceMap := sqlfmt.FormatDocumentWithSourceMap(content, config)
+	data, err := json.MarshalIndent(sourceMap, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(sourceMapPath, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write source map: %w", err)
+	}
+	return formatted, nil
 }
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -14,6 +16,35 @@ import (
 
 const testSQLDialect = "sql"
 
+// chdirTemp changes into a new temporary directory holding files, which maps
+// slash-separated paths to contents, for the rest of the test and returns its
+// path. The directory is marked as a repository root so that config files
+// outside of it are not found, and HOME is isolated too.
+func chdirTemp(t *testing.T, files map[string]string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(tmpDir, ".git"), 0o755))
+
+	oldWd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tmpDir))
+	t.Cleanup(func() { _ = os.Chdir(oldWd) })
+
+	writeTestFiles(t, files)
+	return tmpDir
+}
+
+// writeTestFiles writes files, which maps slash-separated paths relative to
+// the working directory to contents, creating their directories.
+func writeTestFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		name = filepath.FromSlash(name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+}
+
 func TestFormatCommand(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -95,15 +126,15 @@ VALUES
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags before each test
-			lang = testSQLDialect
-			indent = "  "
+			formatOpts.lang = testSQLDialect
+			formatOpts.indent = "  "
 			write = false
 			color = false
-			uppercase = false
-			linesBetween = 2
-			alignColumnNames = false
-			alignAssignments = false
-			alignValues = false
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
+			formatOpts.alignColumnNames = false
+			formatOpts.alignAssignments = false
+			formatOpts.alignValues = false
 
 			// Create a new command for each test to ensure isolation
 			cmd := &cobra.Command{
@@ -111,15 +142,15 @@ VALUES
 				Args: cobra.ArbitraryArgs,
 				RunE: runFormat,
 			}
-			cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 			cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 			cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-			cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-			cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-			cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+			cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+			cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 			// Capture stdout
 			oldStdout := os.Stdout
@@ -172,15 +203,15 @@ func TestFormatFile(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	// Create format command
 	cmd := &cobra.Command{
@@ -188,15 +219,15 @@ func TestFormatFile(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -257,15 +288,15 @@ align_values: true
 	defer func() { _ = os.Chdir(oldWd) }()
 
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	// Create format command
 	cmd := &cobra.Command{
@@ -273,15 +304,15 @@ align_values: true
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -322,6 +353,211 @@ VALUES
 	assert.Equal(t, expected, output)
 }
 
+func TestFormatCommandConfigOverrides(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".sqlfmt.yaml":     "keyword_case: lowercase\noverrides:\n  - files: [\"pg/**/*.sql\"]\n    keyword_case: uppercase\n",
+		"legacy.sql":       "SELECT id FROM users;",
+		"pg/api/users.sql": "select id from users;",
+	})
+
+	formatOpts = formatOptions{}
+	write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+	cmd := &cobra.Command{
+		Use:  "format [files...]",
+		Args: cobra.ArbitraryArgs,
+		RunE: runFormat,
+	}
+	formatOpts.register(cmd.Flags())
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	cmd.SetArgs([]string{"legacy.sql", filepath.Join("pg", "api", "users.sql")})
+	err := cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	assert.Equal(t, "select\n  id\nfrom\n  users;SELECT\n  id\nFROM\n  users;", buf.String())
+}
+
+// TestFormatCommandConfigFromSubdirectory tests that relative file names find
+// the config files of parent directories.
+func TestFormatCommandConfigFromSubdirectory(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".sqlfmt.yaml": "keyword_case: uppercase\n",
+		"sub/x.sql":    "select id from users;",
+	})
+	require.NoError(t, os.Chdir("sub"))
+
+	formatOpts = formatOptions{}
+	write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+	cmd := &cobra.Command{
+		Use:  "format [files...]",
+		Args: cobra.ArbitraryArgs,
+		RunE: runFormat,
+	}
+	formatOpts.register(cmd.Flags())
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	cmd.SetArgs([]string{"x.sql"})
+	err := cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	assert.Equal(t, "SELECT\n  id\nFROM\n  users;", buf.String())
+}
+
+func TestFormatCommandConfigFlags(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".sqlfmt.yaml": "keyword_case: lowercase\n",
+		"upper.yaml":   "keyword_case: uppercase\n",
+		"test.sql":     "Select id From users;",
+	})
+
+	run := func(args ...string) (string, error) {
+		formatOpts = formatOptions{}
+		write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+
+		cmd := &cobra.Command{
+			Use:  "format [files...]",
+			Args: cobra.ArbitraryArgs,
+			RunE: runFormat,
+		}
+		formatOpts.register(cmd.Flags())
+		cmd.SilenceUsage = true
+
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+
+		_ = w.Close()
+		os.Stdout = oldStdout
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		return buf.String(), err
+	}
+
+	output, err := run("test.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "select\n  id\nfrom\n  users;", output)
+
+	output, err = run("--config", "upper.yaml", "test.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT\n  id\nFROM\n  users;", output)
+
+	output, err = run("--no-config", "test.sql")
+	require.NoError(t, err)
+	assert.Equal(t, "Select\n  id\nFrom\n  users;", output)
+
+	_, err = run("--config", "missing.yaml", "test.sql")
+	require.ErrorContains(t, err, "failed to read config file")
+
+	_, err = run("--config", "upper.yaml", "--no-config", "test.sql")
+	require.EqualError(t, err, "--config cannot be combined with --no-config")
+}
+
+func TestFormatCommandEditorConfig(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".editorconfig": "root = true\n\n[*.sql]\nindent_size = 4\nend_of_line = crlf\ninsert_final_newline = true\n",
+		"test.sql":      "SELECT id FROM users;",
+	})
+
+	run := func(args ...string) string {
+		formatOpts = formatOptions{}
+		write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+
+		cmd := &cobra.Command{
+			Use:  "format [files...]",
+			Args: cobra.ArbitraryArgs,
+			RunE: runFormat,
+		}
+		formatOpts.register(cmd.Flags())
+
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+
+		_ = w.Close()
+		os.Stdout = oldStdout
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		return buf.String()
+	}
+
+	assert.Equal(t, "SELECT\r\n    id\r\nFROM\r\n    users;\r\n", run("test.sql"))
+	assert.Equal(t, "SELECT\n  id\nFROM\n  users;", run("--no-config", "test.sql"))
+
+	// sqlfmt config files and flags take precedence over .editorconfig
+	require.NoError(t, os.WriteFile(".sqlfmt.yaml", []byte("indent: \"\\t\"\n"), 0o644))
+	assert.Equal(t, "SELECT\r\n\tid\r\nFROM\r\n\tusers;\r\n", run("test.sql"))
+	assert.Equal(t, "SELECT\r\n   id\r\nFROM\r\n   users;\r\n", run("--indent", "   ", "test.sql"))
+}
+
+func TestFormatCommandConfigColorsOnlyWithColorFlag(t *testing.T) {
+	tmpDir := chdirTemp(t, map[string]string{
+		".sqlfmt.yaml": "color:\n  reserved_words: [red]\n",
+		"test.sql":     "SELECT id FROM users;",
+	})
+	tmpSQL := filepath.Join(tmpDir, "test.sql")
+
+	run := func(args ...string) string {
+		formatOpts = formatOptions{}
+		write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+
+		cmd := &cobra.Command{
+			Use:  "format [files...]",
+			Args: cobra.ArbitraryArgs,
+			RunE: runFormat,
+		}
+		formatOpts.register(cmd.Flags())
+		cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
+
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+
+		_ = w.Close()
+		os.Stdout = oldStdout
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		return buf.String()
+	}
+
+	// The palette from the config file is only used for colored output
+	plain := run(tmpSQL)
+	assert.NotContains(t, plain, "\033[")
+	assert.Contains(t, plain, "SELECT")
+
+	colored := run("--color", tmpSQL)
+	assert.Contains(t, colored, "\033[31m"+"SELECT")
+	assert.NotContains(t, colored, "\033[36m"+"SELECT")
+}
+
 func TestFormatCommandDialects(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -379,30 +615,30 @@ FROM
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags
-			lang = tt.dialect
-			indent = "  "
+			formatOpts.lang = tt.dialect
+			formatOpts.indent = "  "
 			write = false
 			color = false
-			uppercase = false
-			linesBetween = 2
-			alignColumnNames = false
-			alignAssignments = false
-			alignValues = false
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
+			formatOpts.alignColumnNames = false
+			formatOpts.alignAssignments = false
+			formatOpts.alignValues = false
 
 			cmd := &cobra.Command{
 				Use:  "format [files...]",
 				Args: cobra.ArbitraryArgs,
 				RunE: runFormat,
 			}
-			cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 			cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 			cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-			cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-			cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-			cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+			cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+			cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 			// Mark the lang flag as changed to trigger applyLanguageFlag
 			_ = cmd.Flags().Set("lang", tt.dialect)
@@ -499,32 +735,32 @@ from
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags
-			lang = testSQLDialect
-			indent = "  "
+			formatOpts.lang = testSQLDialect
+			formatOpts.indent = "  "
 			write = false
 			color = false
-			uppercase = false
-			keywordCase = tt.keywordCase
-			linesBetween = 2
-			alignColumnNames = false
-			alignAssignments = false
-			alignValues = false
+			formatOpts.uppercase = false
+			formatOpts.keywordCase = tt.keywordCase
+			formatOpts.linesBetween = 2
+			formatOpts.alignColumnNames = false
+			formatOpts.alignAssignments = false
+			formatOpts.alignValues = false
 
 			cmd := &cobra.Command{
 				Use:  "format [files...]",
 				Args: cobra.ArbitraryArgs,
 				RunE: runFormat,
 			}
-			cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 			cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 			cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().StringVar(&keywordCase, "keyword-case", "preserve", "Keyword casing")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-			cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-			cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-			cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().StringVar(&formatOpts.keywordCase, "keyword-case", "preserve", "Keyword casing")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+			cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+			cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 			// Mark the keyword-case flag as changed to trigger applyKeywordCaseFlag
 			_ = cmd.Flags().Set("keyword-case", tt.keywordCase)
@@ -564,6 +800,105 @@ from
 	}
 }
 
+// TestFormatCommandPreservesLineEndings tests that --write keeps the byte order
+// mark, line endings and final newline of a file.
+func TestFormatCommandPreservesLineEndings(t *testing.T) {
+	chdirTemp(t, nil)
+
+	run := func(content string, args ...string) string {
+		require.NoError(t, os.WriteFile("test.sql", []byte(content), 0o644))
+		formatOpts = formatOptions{}
+		write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+
+		cmd := &cobra.Command{
+			Use:  "format [files...]",
+			Args: cobra.ArbitraryArgs,
+			RunE: runFormat,
+		}
+		formatOpts.register(cmd.Flags())
+		cmd.Flags().BoolVarP(&write, "write", "w", false, "Write result to file")
+
+		oldStdout := os.Stdout
+		_, w, _ := os.Pipe()
+		os.Stdout = w
+
+		cmd.SetArgs(append(args, "--write", "test.sql"))
+		require.NoError(t, cmd.Execute())
+
+		_ = w.Close()
+		os.Stdout = oldStdout
+
+		formatted, err := os.ReadFile("test.sql")
+		require.NoError(t, err)
+		return string(formatted)
+	}
+
+	assert.Equal(t, "\ufeffSELECT\r\n  id\r\nFROM\r\n  users;\r\n", run("\ufeffSELECT id\r\nFROM users;\r\n"))
+	assert.Equal(t, "SELECT\n  id\nFROM\n  users;", run("SELECT id FROM users;"))
+	assert.Equal(t, "SELECT\n  id\nFROM\n  users;\n", run("SELECT id\r\nFROM users;", "--line-ending=lf", "--insert-final-newline"))
+
+	require.NoError(t, os.WriteFile(".sqlfmt.yaml", []byte("line_ending: crlf\ninsert_final_newline: true\n"), 0o644))
+	assert.Equal(t, "SELECT\r\n  id\r\nFROM\r\n  users;\r\n", run("SELECT id FROM users;\n\n\n"))
+}
+
+func TestFormatCommandSourceMap(t *testing.T) {
+	chdirTemp(t, nil)
+	defer func() { checkOnly, sourceMapPath = false, "" }()
+
+	run := func(args ...string) error {
+		formatOpts = formatOptions{}
+		write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+		sourceMapPath = ""
+
+		cmd := &cobra.Command{
+			Use:  "format [files...]",
+			Args: cobra.ArbitraryArgs,
+			RunE: runFormat,
+		}
+		formatOpts.register(cmd.Flags())
+		cmd.Flags().BoolVarP(&write, "write", "w", false, "Write result to file")
+		cmd.Flags().BoolVarP(&checkOnly, "check", "", false, "Check formatting")
+		cmd.Flags().StringVar(&sourceMapPath, "source-map", "", "Write a source map")
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+
+		oldStdout := os.Stdout
+		_, w, _ := os.Pipe()
+		os.Stdout = w
+		defer func() {
+			_ = w.Close()
+			os.Stdout = oldStdout
+		}()
+
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	query := "select id from users"
+	require.NoError(t, os.WriteFile("test.sql", []byte(query), 0o644))
+	require.NoError(t, os.WriteFile("other.sql", []byte(query), 0o644))
+
+	require.NoError(t, run("--write", "--source-map=test.map.json", "test.sql"))
+	formatted, err := os.ReadFile("test.sql")
+	require.NoError(t, err)
+	data, err := os.ReadFile("test.map.json")
+	require.NoError(t, err)
+
+	var sourceMap sqlfmt.SourceMap
+	require.NoError(t, json.Unmarshal(data, &sourceMap))
+	require.Len(t, sourceMap, 4)
+	for _, mapping := range sourceMap {
+		assert.Equal(t,
+			query[mapping.Input:mapping.Input+mapping.InputLength],
+			string(formatted[mapping.Output:mapping.Output+mapping.OutputLength]))
+	}
+
+	err = run("--source-map=test.map.json", "test.sql", "other.sql")
+	require.EqualError(t, err, "--source-map requires a single input")
+	err = run("--check", "--source-map=test.map.json", "test.sql")
+	require.ErrorContains(t, err, "cannot be combined")
+}
+
 func TestFormatCommandWriteFlag(t *testing.T) {
 	// Create a temporary SQL file
 	tmpFile, err := os.CreateTemp("", "test*.sql")
@@ -576,15 +911,15 @@ func TestFormatCommandWriteFlag(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = true // Enable write flag
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	// Create format command
 	cmd := &cobra.Command{
@@ -592,15 +927,15 @@ func TestFormatCommandWriteFlag(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", true, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -637,30 +972,30 @@ WHERE
 
 func TestFormatCommandColorFlag(t *testing.T) {
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = true // Enable color flag
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", true, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -720,15 +1055,15 @@ func TestFormatCommandMultipleFiles(t *testing.T) {
 	_ = tmpFile2.Close()
 
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	// Create format command
 	cmd := &cobra.Command{
@@ -736,15 +1071,15 @@ func TestFormatCommandMultipleFiles(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -771,15 +1106,15 @@ func TestFormatCommandMultipleFiles(t *testing.T) {
 
 func TestFormatCommandErrorNonExistentFile(t *testing.T) {
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	// Create format command
 	cmd := &cobra.Command{
@@ -787,15 +1122,15 @@ func TestFormatCommandErrorNonExistentFile(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Run the command with a non-existent file
 	cmd.SetArgs([]string{"/nonexistent/file.sql"})
@@ -815,7 +1150,7 @@ func TestFormatCommandOtherFlags(t *testing.T) {
 		{
 			name: "max-line-length flag",
 			setupCmd: func(cmd *cobra.Command) {
-				maxLineLength = 50
+				formatOpts.maxLineLength = 50
 				_ = cmd.Flags().Set("max-line-length", "50")
 			},
 			input: "SELECT id, name, email, created_at FROM users",
@@ -823,7 +1158,7 @@ func TestFormatCommandOtherFlags(t *testing.T) {
 		{
 			name: "preserve-comment-indent flag",
 			setupCmd: func(cmd *cobra.Command) {
-				preserveCommentIndent = true
+				formatOpts.preserveCommentIndent = true
 				_ = cmd.Flags().Set("preserve-comment-indent", "true")
 			},
 			input: "SELECT * FROM users -- comment",
@@ -831,7 +1166,7 @@ func TestFormatCommandOtherFlags(t *testing.T) {
 		{
 			name: "comment-min-spacing flag",
 			setupCmd: func(cmd *cobra.Command) {
-				commentMinSpacing = 4
+				formatOpts.commentMinSpacing = 4
 				_ = cmd.Flags().Set("comment-min-spacing", "4")
 			},
 			input: "SELECT * FROM users -- comment",
@@ -841,36 +1176,36 @@ func TestFormatCommandOtherFlags(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags
-			lang = testSQLDialect
-			indent = "  "
+			formatOpts.lang = testSQLDialect
+			formatOpts.indent = "  "
 			write = false
 			color = false
-			uppercase = false
-			linesBetween = 2
-			alignColumnNames = false
-			alignAssignments = false
-			alignValues = false
-			maxLineLength = 0
-			preserveCommentIndent = false
-			commentMinSpacing = 1
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
+			formatOpts.alignColumnNames = false
+			formatOpts.alignAssignments = false
+			formatOpts.alignValues = false
+			formatOpts.maxLineLength = 0
+			formatOpts.preserveCommentIndent = false
+			formatOpts.commentMinSpacing = 1
 
 			cmd := &cobra.Command{
 				Use:  "format [files...]",
 				Args: cobra.ArbitraryArgs,
 				RunE: runFormat,
 			}
-			cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 			cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 			cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-			cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-			cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-			cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
-			cmd.Flags().IntVar(&maxLineLength, "max-line-length", 0, "Maximum line length")
-			cmd.Flags().BoolVar(&preserveCommentIndent, "preserve-comment-indent", false, "Preserve comment indent")
-			cmd.Flags().IntVar(&commentMinSpacing, "comment-min-spacing", 1, "Comment min spacing")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+			cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+			cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
+			cmd.Flags().IntVar(&formatOpts.maxLineLength, "max-line-length", 0, "Maximum line length")
+			cmd.Flags().BoolVar(&formatOpts.preserveCommentIndent, "preserve-comment-indent", false, "Preserve comment indent")
+			cmd.Flags().IntVar(&formatOpts.commentMinSpacing, "comment-min-spacing", 1, "Comment min spacing")
 
 			// Apply the test-specific setup
 			tt.setupCmd(cmd)
@@ -922,15 +1257,15 @@ func TestFormatCommandColorWithFile(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = true // Enable color flag
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	// Create format command
 	cmd := &cobra.Command{
@@ -938,15 +1273,15 @@ func TestFormatCommandColorWithFile(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", true, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -972,30 +1307,30 @@ func TestFormatCommandColorWithFile(t *testing.T) {
 
 func TestFormatCommandStandardDialectAlias(t *testing.T) {
 	// Reset global flags
-	lang = "standard"
-	indent = "  "
+	formatOpts.lang = "standard"
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark the lang flag as changed to trigger applyLanguageFlag
 	_ = cmd.Flags().Set("lang", "standard")
@@ -1041,30 +1376,30 @@ FROM
 
 func TestFormatCommandMariaDBAlias(t *testing.T) {
 	// Reset global flags
-	lang = "mariadb"
-	indent = "  "
+	formatOpts.lang = "mariadb"
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark the lang flag as changed to trigger applyLanguageFlag
 	_ = cmd.Flags().Set("lang", "mariadb")
@@ -1110,32 +1445,32 @@ FROM
 
 func TestFormatCommandLowercaseFlag(t *testing.T) {
 	// Reset global flags
-	lang = testSQLDialect
-	indent = "  "
+	formatOpts.lang = testSQLDialect
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	keywordCase = "lowercase"
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.keywordCase = "lowercase"
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().StringVar(&keywordCase, "keyword-case", "preserve", "Keyword casing")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().StringVar(&formatOpts.keywordCase, "keyword-case", "preserve", "Keyword casing")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark flags as changed
 	_ = cmd.Flags().Set("keyword-case", "lowercase")
@@ -1181,30 +1516,30 @@ from
 
 func TestFormatCommandOracleAlias(t *testing.T) {
 	// Reset global flags
-	lang = "oracle"
-	indent = "  "
+	formatOpts.lang = "oracle"
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark the lang flag as changed to trigger applyLanguageFlag
 	_ = cmd.Flags().Set("lang", "oracle")
@@ -1250,30 +1585,30 @@ FROM
 
 func TestFormatCommandPostgresAlias(t *testing.T) {
 	// Reset global flags
-	lang = "postgres"
-	indent = "  "
+	formatOpts.lang = "postgres"
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark the lang flag as changed to trigger applyLanguageFlag
 	_ = cmd.Flags().Set("lang", "postgres")
@@ -1319,30 +1654,30 @@ FROM
 
 func TestFormatCommandPLSQLAlias(t *testing.T) {
 	// Reset global flags
-	lang = "plsql"
-	indent = "  "
+	formatOpts.lang = "plsql"
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark the lang flag as changed to trigger applyLanguageFlag
 	_ = cmd.Flags().Set("lang", "plsql")
@@ -1388,30 +1723,30 @@ FROM
 
 func TestFormatCommandPLSQLWithSlash(t *testing.T) {
 	// Reset global flags
-	lang = "pl/sql"
-	indent = "  "
+	formatOpts.lang = "pl/sql"
+	formatOpts.indent = "  "
 	write = false
 	color = false
-	uppercase = false
-	linesBetween = 2
-	alignColumnNames = false
-	alignAssignments = false
-	alignValues = false
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
+	formatOpts.alignColumnNames = false
+	formatOpts.alignAssignments = false
+	formatOpts.alignValues = false
 
 	cmd := &cobra.Command{
 		Use:  "format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", testSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
 	cmd.Flags().BoolVar(&color, "color", false, "Enable colors")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
-	cmd.Flags().BoolVar(&alignColumnNames, "align-column-names", false, "Align SELECT column names")
-	cmd.Flags().BoolVar(&alignAssignments, "align-assignments", false, "Align UPDATE assignments")
-	cmd.Flags().BoolVar(&alignValues, "align-values", false, "Align INSERT values")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.alignColumnNames, "align-column-names", false, "Align SELECT column names")
+	cmd.Flags().BoolVar(&formatOpts.alignAssignments, "align-assignments", false, "Align UPDATE assignments")
+	cmd.Flags().BoolVar(&formatOpts.alignValues, "align-values", false, "Align INSERT values")
 
 	// Mark the lang flag as changed to trigger applyLanguageFlag
 	_ = cmd.Flags().Set("lang", "pl/sql")
@@ -1454,3 +1789,190 @@ FROM
   users`
 	assert.Equal(t, expected, output)
 }
+
+func TestFormatCommandReportFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	formattedFile := filepath.Join(tmpDir, "formatted.sql")
+	unformattedFile := filepath.Join(tmpDir, "unformatted.sql")
+	trailingFile := filepath.Join(tmpDir, "trailing.sql")
+	missingFile := filepath.Join(tmpDir, "missing.sql")
+	unformattedSQL := "SELECT * FROM users"
+	require.NoError(t, os.WriteFile(formattedFile, []byte("SELECT\n  *\nFROM\n  orders\n"), 0o644))
+	require.NoError(t, os.WriteFile(unformattedFile, []byte(unformattedSQL), 0o644))
+	// Formatting only removes the extra final newlines of trailingFile
+	require.NoError(t, os.WriteFile(trailingFile, []byte("SELECT\n  *\nFROM\n  items\n\n\n"), 0o644))
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantStatus  int
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "list",
+			args:        []string{"-l", formattedFile, unformattedFile},
+			contains:    []string{unformattedFile},
+			notContains: []string{formattedFile, "FROM"},
+		},
+		{
+			name:        "diff",
+			args:        []string{"-d", formattedFile, unformattedFile},
+			contains:    []string{"--- a/" + unformattedFile, "+++ b/" + unformattedFile, "-SELECT * FROM users", "+FROM"},
+			notContains: []string{"orders"},
+		},
+		{
+			name:       "check",
+			args:       []string{"--check", formattedFile, unformattedFile},
+			wantStatus: exitUnformatted,
+		},
+		{
+			name: "check formatted only",
+			args: []string{"--check", formattedFile},
+		},
+		{
+			name:       "check final newlines",
+			args:       []string{"--check", trailingFile},
+			wantStatus: exitUnformatted,
+		},
+		{
+			name:       "check unreadable file",
+			args:       []string{"--check", missingFile, unformattedFile},
+			wantStatus: exitError,
+		},
+		{
+			name:       "list continues after unreadable file",
+			args:       []string{"-l", missingFile, unformattedFile},
+			wantStatus: exitError,
+			contains:   []string{unformattedFile},
+		},
+		{
+			name:     "diff final newlines",
+			args:     []string{"-d", trailingFile},
+			contains: []string{"--- a/" + trailingFile, "   items\n-\n-\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset global flags before each test
+			formatOpts.lang = testSQLDialect
+			formatOpts.indent = "  "
+			write = false
+			color = false
+			listDifferent = false
+			printDiff = false
+			checkOnly = false
+
+			cmd := &cobra.Command{
+				Use:  "format [files...]",
+				Args: cobra.ArbitraryArgs,
+				RunE: runFormat,
+			}
+			cmd.SilenceErrors = true
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", testSQLDialect, "SQL dialect")
+			cmd.Flags().BoolVarP(&write, "write", "w", false, "Write result to file")
+			cmd.Flags().BoolVarP(&listDifferent, "list", "l", false, "List files")
+			cmd.Flags().BoolVarP(&printDiff, "diff", "d", false, "Print diff")
+			cmd.Flags().BoolVar(&checkOnly, "check", false, "Check only")
+
+			// Capture stdout
+			oldStdout := os.Stdout
+			r, w, _ := os.Pipe()
+			os.Stdout = w
+
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+
+			// Restore stdout and capture output
+			_ = w.Close()
+			os.Stdout = oldStdout
+
+			var buf bytes.Buffer
+			_, _ = buf.ReadFrom(r)
+			output := buf.String()
+
+			if tt.wantStatus != exitOK {
+				var statusErr *exitStatusError
+				require.ErrorAs(t, err, &statusErr)
+				assert.Equal(t, tt.wantStatus, statusErr.code)
+			} else {
+				require.NoError(t, err)
+			}
+			for _, s := range tt.contains {
+				assert.Contains(t, output, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, output, s)
+			}
+
+			// None of the report modes write files
+			content, err := os.ReadFile(unformattedFile)
+			require.NoError(t, err)
+			assert.Equal(t, unformattedSQL, string(content))
+		})
+	}
+
+	listDifferent = false
+	printDiff = false
+	checkOnly = false
+}
+
+func TestFormatCommandCheckRejectsWrite(t *testing.T) {
+	write = true
+	checkOnly = true
+	defer func() {
+		write = false
+		checkOnly = false
+	}()
+
+	err := runFormat(&cobra.Command{}, []string{"file.sql"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--check cannot be combined with --write")
+}
+
+func TestFormatCommandBackupRequiresWrite(t *testing.T) {
+	backupSuffix = ".orig"
+	defer func() { backupSuffix = "" }()
+
+	err := runFormat(&cobra.Command{}, []string{"file.sql"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--backup requires --write")
+}
+
+// TestFormatCommandWriteSkipsFormattedFiles tests that --write neither rewrites
+// nor reports files that are already formatted.
+func TestFormatCommandWriteSkipsFormattedFiles(t *testing.T) {
+	chdirTemp(t, nil)
+
+	require.NoError(t, os.WriteFile("formatted.sql", []byte("SELECT\n  id\nFROM\n  users;\n"), 0o644))
+	require.NoError(t, os.WriteFile("messy.sql", []byte("select id from users;\n"), 0o644))
+
+	formatOpts = formatOptions{}
+	write, color, listDifferent, printDiff, checkOnly = true, false, false, false, false
+	backupSuffix = ".bak"
+	defer func() {
+		write = false
+		backupSuffix = ""
+	}()
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runFormat(&cobra.Command{}, []string{"formatted.sql", "messy.sql"})
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	assert.Equal(t, "Formatted messy.sql\n", buf.String())
+
+	_, err = os.Stat("formatted.sql.bak")
+	assert.True(t, os.IsNotExist(err))
+	backup, err := os.ReadFile("messy.sql.bak")
+	require.NoError(t, err)
+	assert.Equal(t, "select id from users;\n", string(backup))
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/git"
+	"github.com/spf13/pflag"
+)
+
+// sqlExtensions are the extensions of the files that git selections include.
+var sqlExtensions = []string{".sql", ".psql", ".pgsql", ".mysql", ".plsql"}
+
+// gitOptions holds the flags that let git select the files to process.
+type gitOptions struct {
+	changed bool
+	since   string
+	staged  bool
+}
+
+// gitOpts is bound to the git flags of format, validate and check.
+var gitOpts gitOptions
+
+// stagedRepo is the repository whose index --staged reads and writes. It is
+// nil unless --staged is set.
+var stagedRepo *git.Repo
+
+func (o *gitOptions) register(flags *pflag.FlagSet) {
+	flags.BoolVar(&o.changed, "changed", false, "Only process SQL files that are modified, staged or untracked in git")
+	flags.StringVar(&o.since, "since", "",
+		"Only process SQL files changed since the merge base with this git ref, implies --changed")
+	flags.BoolVar(&o.staged, "staged", false,
+		"Process the staged content of staged SQL files; with --write, re-stage them")
+}
+
+// enabled reports whether git selects the files instead of the arguments.
+func (o *gitOptions) enabled() bool {
+	return o.changed || o.since != "" || o.staged
+}
+
+// selectFiles returns the files a command processes: the arguments, or the
+// files that git selects among them.
+func (o *gitOptions) selectFiles(args []string) ([]string, error) {
+	stagedRepo = nil
+	if !o.enabled() {
+		return args, nil
+	}
+	return o.files(args)
+}
+
+// files asks git for the SQL files to process. Arguments limit the selection
+// to the files and directories they name. The paths are relative to the
+// working directory, like arguments would be.
+func (o *gitOptions) files(args []string) ([]string, error) {
+	if o.staged && (o.changed || o.since != "") {
+		return nil, fmt.Errorf("--staged cannot be combined with --changed or --since")
+	}
+	for _, arg := range args {
+		if arg == "-" {
+			return nil, fmt.Errorf("standard input cannot be combined with --changed, --since or --staged")
+		}
+	}
+
+	repo, err := git.Open(".")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if o.staged {
+		stagedRepo = repo
+		paths, err = repo.StagedFiles(args...)
+	} else {
+		paths, err = repo.ChangedFiles(o.since, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(wd); err == nil {
+		wd = resolved
+	}
+
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !isSQLFile(path) {
+			continue
+		}
+		if rel, err := filepath.Rel(wd, path); err == nil {
+			path = rel
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func isSQLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, sqlExt := range sqlExtensions {
+		if ext == sqlExt {
+			return true
+		}
+	}
+	return false
+}
+
+// readInput reads the content of a file, or with --staged its staged content.
+func readInput(filename string) ([]byte, error) {
+	if stagedRepo != nil {
+		return stagedRepo.IndexContent(filename)
+	}
+	return os.ReadFile(filename)
+}
+
+// writeInput replaces the content of a file like writeFormatted. With
+// --staged, the formatted content is staged instead, and also written to the
+// working tree if that has no unstaged changes, so that the two stay alike.
+func writeInput(filename, original, formatted string) (bool, error) {
+	if stagedRepo == nil {
+		return writeFormatted(filename, original, formatted)
+	}
+	if formatted == original {
+		return false, nil
+	}
+
+	if err := stagedRepo.Stage(filename, []byte(formatted)); err != nil {
+		return false, err
+	}
+	if current, err := os.ReadFile(filename); err == nil && string(current) == original {
+		if _, err := writeFormatted(filename, original, formatted); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
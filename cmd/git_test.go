@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chdirGitRepo creates a git repository with one commit of files in a
+// temporary directory and changes into it.
+func chdirGitRepo(t *testing.T, files map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	chdirTemp(t, files)
+	runGit(t, "init", "-q")
+	runGit(t, "add", "-A")
+	runGit(t, "commit", "-q", "-m", "initial")
+}
+
+func runGit(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).Output()
+	require.NoError(t, err, "git %v", args)
+	return string(out)
+}
+
+func readTestFile(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return string(content)
+}
+
+// runFormatWithGit runs format with the given git options and --write.
+func runFormatWithGit(t *testing.T, opts gitOptions, args ...string) error {
+	t.Helper()
+	formatOpts = formatOptions{}
+	gitOpts = opts
+	write, color, listDifferent, printDiff, checkOnly = true, false, false, false, false
+	defer func() {
+		gitOpts = gitOptions{}
+		stagedRepo = nil
+		write = false
+	}()
+
+	oldStdout := os.Stdout
+	_, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() {
+		_ = w.Close()
+		os.Stdout = oldStdout
+	}()
+
+	return runFormat(&cobra.Command{}, args)
+}
+
+func TestFormatCommandChanged(t *testing.T) {
+	chdirGitRepo(t, map[string]string{
+		"a.sql":      "select 1",
+		"b.sql":      "select 2",
+		"sub/c.sql":  "select 3",
+		"notes.txt":  "select 4",
+		".gitignore": "build/\n",
+	})
+	writeTestFiles(t, map[string]string{
+		"a.sql":         "select 10",
+		"sub/c.sql":     "select 30",
+		"new.sql":       "select 5",
+		"notes.txt":     "select 40",
+		"build/out.sql": "select 6",
+	})
+
+	require.NoError(t, runFormatWithGit(t, gitOptions{changed: true}, "sub"))
+	assert.Equal(t, "select 10", readTestFile(t, "a.sql"))
+	assert.Equal(t, "select\n  30", readTestFile(t, "sub/c.sql"))
+
+	require.NoError(t, runFormatWithGit(t, gitOptions{changed: true}))
+	assert.Equal(t, "select\n  10", readTestFile(t, "a.sql"))
+	assert.Equal(t, "select 2", readTestFile(t, "b.sql"))
+	assert.Equal(t, "select\n  5", readTestFile(t, "new.sql"))
+	assert.Equal(t, "select 40", readTestFile(t, "notes.txt"))
+	assert.Equal(t, "select 6", readTestFile(t, "build/out.sql"))
+}
+
+func TestFormatCommandSince(t *testing.T) {
+	chdirGitRepo(t, map[string]string{"a.sql": "select 1", "b.sql": "select 2"})
+	runGit(t, "branch", "base")
+	writeTestFiles(t, map[string]string{"b.sql": "select 20"})
+	runGit(t, "commit", "-q", "-am", "change b")
+
+	require.NoError(t, runFormatWithGit(t, gitOptions{since: "base"}))
+	assert.Equal(t, "select 1", readTestFile(t, "a.sql"))
+	assert.Equal(t, "select\n  20", readTestFile(t, "b.sql"))
+
+	require.ErrorContains(t, runFormatWithGit(t, gitOptions{since: "no-such-ref"}), "git merge-base")
+}
+
+func TestFormatCommandStaged(t *testing.T) {
+	chdirGitRepo(t, map[string]string{"a.sql": "select 1", "b.sql": "select 2"})
+	writeTestFiles(t, map[string]string{"a.sql": "select 10", "b.sql": "select 20"})
+	runGit(t, "add", "a.sql", "b.sql")
+	// b.sql has unstaged changes on top of the staged ones
+	writeTestFiles(t, map[string]string{"b.sql": "select 200"})
+
+	require.NoError(t, runFormatWithGit(t, gitOptions{staged: true}))
+
+	assert.Equal(t, "select\n  10", runGit(t, "show", ":a.sql"))
+	assert.Equal(t, "select\n  10", readTestFile(t, "a.sql"))
+	assert.Equal(t, "select\n  20", runGit(t, "show", ":b.sql"))
+	assert.Equal(t, "select 200", readTestFile(t, "b.sql"))
+
+	err := runFormatWithGit(t, gitOptions{staged: true, changed: true})
+	require.ErrorContains(t, err, "--staged cannot be combined")
+	require.ErrorContains(t, runFormatWithGit(t, gitOptions{changed: true}, "-"), "standard input")
+}
+
+func TestValidateCommandStaged(t *testing.T) {
+	chdirGitRepo(t, map[string]string{"a.sql": "select 1"})
+	writeTestFiles(t, map[string]string{"a.sql": "select\n  1"})
+	runGit(t, "add", "a.sql")
+	writeTestFiles(t, map[string]string{"a.sql": "select 1"})
+
+	formatOpts = formatOptions{}
+	gitOpts = gitOptions{staged: true}
+	defer func() {
+		gitOpts = gitOptions{}
+		stagedRepo = nil
+	}()
+
+	files, err := gitOpts.selectFiles(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.sql"}, files)
+
+	// The staged content is checked, not the working tree
+	result := validateFileWithResult(&cobra.Command{}, "a.sql", nil, nil)
+	assert.True(t, result.Valid, result.Error)
+}
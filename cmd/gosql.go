@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/diff"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/gosql"
+	"github.com/spf13/cobra"
+)
+
+var goCmd = &cobra.Command{
+	Use:   "go [files or directories...]",
+	Short: "Format SQL embedded in Go source files",
+	Long: `Format the SQL in raw string literals of Go source files and rewrite the
+files with gofmt. Directories are searched for .go files recursively, skipping
+vendor, testdata and hidden directories. Without arguments or with "-",
+standard input is formatted.
+
+A literal is SQL when it is an argument of a database/sql, sqlx or pgx query
+method such as QueryContext, the value of a constant or variable named *Query
+or *SQL and starts like a SQL statement, or when it is marked with a
+/* sql */ comment or a //sqlfmt:sql directive. A directive can name the
+dialect instead, e.g. //sqlfmt:postgresql, and //sqlfmt:ignore leaves a
+literal alone.
+
+Examples:
+  sqlfmt go store.go              # Print store.go with formatted SQL
+  sqlfmt go -w ./internal         # Format the Go files of a tree in place
+  sqlfmt go -l .                  # List Go files whose SQL is not formatted
+  sqlfmt go --check --lang=postgresql ./...`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runGo,
+}
+
+func init() {
+	rootCmd.AddCommand(goCmd)
+
+	formatOpts.register(goCmd.Flags())
+	goCmd.Flags().BoolVarP(&write, "write", "w", false, "Write result to file instead of stdout")
+	goCmd.Flags().StringVar(&backupSuffix, "backup", "",
+		"With --write, keep the original of each changed file under its name plus this suffix")
+	goCmd.Flags().BoolVarP(&listDifferent, "list", "l", false,
+		"List files whose formatting differs instead of printing them")
+	goCmd.Flags().BoolVarP(&printDiff, "diff", "d", false,
+		"Print a unified diff instead of the formatted content")
+	goCmd.Flags().BoolVar(&checkOnly, "check", false,
+		"Exit with a non-zero status if any file needs formatting, without writing")
+}
+
+func runGo(cmd *cobra.Command, args []string) error {
+	if checkOnly && write {
+		return fmt.Errorf("--check cannot be combined with --write")
+	}
+	if backupSuffix != "" && !write {
+		return fmt.Errorf("--backup requires --write")
+	}
+
+	baseConfig, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Errors from here on are about the inputs, not the usage
+	cmd.SilenceUsage = true
+
+	// As with format, the report modes go on past inputs that fail
+	changed, failed := false, false
+	inputFailed := func(err error) error {
+		if !reportOnly() {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		failed = true
+		return nil
+	}
+
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		if changed, err = formatGoSource(cmd, stdinName, "", input, baseConfig); err != nil {
+			if err := inputFailed(err); err != nil {
+				return err
+			}
+		}
+	} else {
+		files, err := goFiles(args)
+		if err != nil {
+			return err
+		}
+		for _, filename := range files {
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				if err := inputFailed(fmt.Errorf("failed to read %s: %w", filename, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			fileChanged, err := formatGoSource(cmd, filename, filename, content, baseConfig)
+			if err != nil {
+				if err := inputFailed(err); err != nil {
+					return err
+				}
+				continue
+			}
+			changed = changed || fileChanged
+		}
+	}
+
+	switch {
+	case failed:
+		return &exitStatusError{code: exitError}
+	case checkOnly && changed:
+		return &exitStatusError{code: exitUnformatted}
+	}
+	return nil
+}
+
+// goFiles expands the arguments to the Go files to process. Files are taken
+// as they are; directories are searched recursively, skipping the
+// directories that the go tool skips and paths excluded by .sqlfmtignore.
+// The "./..." pattern of the go tool names a directory tree too.
+func goFiles(args []string) ([]string, error) {
+	ignoreFile, err := sqlfmt.LoadIgnoreFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load ignore file: %v\n", err)
+	}
+
+	var files []string
+	for _, arg := range args {
+		root := strings.TrimSuffix(arg, "...")
+		if root == "" {
+			root = "."
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				name := entry.Name()
+				if path != root && (name == "vendor" || name == "testdata" ||
+					strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || ignoreFile.ShouldIgnore(path)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".go") && entry.Type().IsRegular() && !ignoreFile.ShouldIgnore(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// formatGoSource formats the SQL in the Go source content of the input name
+// and reports whether it changed in the --list, --diff and --check modes.
+// The config files for filename apply; standard input has no filename.
+func formatGoSource(cmd *cobra.Command, name, filename string, content []byte, baseConfig *sqlfmt.Config) (bool, error) {
+	formatted, err := gosql.Format(name, content, gosql.Options{
+		Config: fileConfig(cmd, baseConfig, filename),
+		Language: func(query string) (sqlfmt.Language, bool) {
+			return inputLanguage("", query)
+		},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to format %s: %w", name, err)
+	}
+
+	if reportOnly() {
+		if string(formatted) == string(content) {
+			return false, nil
+		}
+		if listDifferent {
+			fmt.Println(name)
+		}
+		if printDiff {
+			fmt.Print(diff.Unified("a/"+name, "b/"+name, string(content), string(formatted)))
+		}
+		return true, nil
+	}
+
+	if filename == "" || !write {
+		fmt.Print(string(formatted))
+		return false, nil
+	}
+
+	written, err := writeFormatted(filename, string(content), string(formatted))
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if written {
+		fmt.Printf("Formatted %s\n", filename)
+	}
+	return false, nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGoCommand tests that the go command finds the Go files of a tree,
+// applies the config files of their directories and rewrites them.
+func TestGoCommand(t *testing.T) {
+	src := "package store\n\nconst userQuery = `select id from users`\n"
+	files := map[string]string{"store/.sqlfmt.yaml": "keyword_case: uppercase\n"}
+	for _, file := range []string{"store/users.go", "store/notes.txt", "vendor/v.go", "testdata/t.go", "main.go"} {
+		files[file] = src
+	}
+	chdirTemp(t, files)
+
+	formatOpts = formatOptions{}
+	write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+	defer func() {
+		write = false
+		listDifferent = false
+	}()
+
+	run := func(args ...string) (string, error) {
+		oldStdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		err := runGo(&cobra.Command{}, args)
+		_ = w.Close()
+		os.Stdout = oldStdout
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		return buf.String(), err
+	}
+
+	listDifferent = true
+	output, err := run("./...")
+	require.NoError(t, err)
+	assert.Equal(t, "main.go\n"+filepath.Join("store", "users.go")+"\n", output)
+
+	listDifferent, write = false, true
+	output, err = run("store")
+	require.NoError(t, err)
+	assert.Equal(t, "Formatted "+filepath.Join("store", "users.go")+"\n", output)
+
+	content, err := os.ReadFile(filepath.Join("store", "users.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package store\n\nconst userQuery = `\n\tSELECT\n\t  id\n\tFROM\n\t  users\n`\n", string(content))
+
+	// Formatted files are left alone
+	output, err = run(filepath.Join("store", "users.go"))
+	require.NoError(t, err)
+	assert.Empty(t, output)
+}
+
+func TestGoCommandParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.go")
+	require.NoError(t, os.WriteFile(path, []byte("package p\nfunc {"), 0o644))
+
+	formatOpts = formatOptions{}
+	write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+	err := runGo(&cobra.Command{}, []string{path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to format "+path)
+}
+
+// TestGoCommandReportsFailures tests that the report modes go on past files
+// that fail and exit with exitError.
+func TestGoCommandReportsFailures(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"a_broken.go": "package p\nfunc {",
+		"b_query.go":  "package p\n\nconst userQuery = `select id from users`\n",
+	})
+
+	formatOpts = formatOptions{}
+	write, color, listDifferent, printDiff, checkOnly = false, false, true, false, false
+	defer func() { listDifferent = false }()
+
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	outR, outW, _ := os.Pipe()
+	errR, errW, _ := os.Pipe()
+	os.Stdout, os.Stderr = outW, errW
+	err := runGo(&cobra.Command{}, []string{"."})
+	_ = outW.Close()
+	_ = errW.Close()
+	os.Stdout, os.Stderr = oldStdout, oldStderr
+
+	var stdout, stderr bytes.Buffer
+	_, _ = stdout.ReadFrom(outR)
+	_, _ = stderr.ReadFrom(errR)
+
+	var exitErr *exitStatusError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, exitError, exitErr.code)
+	assert.Equal(t, "b_query.go\n", stdout.String())
+	assert.Contains(t, stderr.String(), "Error: failed to format a_broken.go")
+}
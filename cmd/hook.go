@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies pre-commit hooks written by hook install, which may
+// be replaced without --force.
+const hookMarker = "# Installed by \"sqlfmt hook install\""
+
+var (
+	hookFix   bool
+	hookForce bool
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage the git pre-commit hook",
+	Long: `Manage a git pre-commit hook that checks staged SQL files.
+
+Projects using the pre-commit framework can use the hooks defined in
+.pre-commit-hooks.yaml instead.`,
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install a git pre-commit hook that checks staged SQL files",
+	Long: `Install a git pre-commit hook that runs "sqlfmt check --staged", which
+rejects commits whose staged SQL files need formatting. With --fix, the hook
+runs "sqlfmt format --write --staged" instead, which formats the staged SQL
+files and stages the result.
+
+The hook checks the staged content, follows .sqlfmtignore and resolves the
+config files for each file like the other commands. sqlfmt (or go-sqlfmt, as
+"go install" names it) must be in the PATH when committing.
+
+Examples:
+  sqlfmt hook install          # Reject commits with unformatted SQL
+  sqlfmt hook install --fix    # Format staged SQL files when committing`,
+	Args: cobra.NoArgs,
+	RunE: runHookInstall,
+}
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(hookInstallCmd)
+
+	hookInstallCmd.Flags().BoolVar(&hookFix, "fix", false, "Format and re-stage staged SQL files instead of rejecting the commit")
+	hookInstallCmd.Flags().BoolVarP(&hookForce, "force", "f", false, "Replace an existing pre-commit hook")
+}
+
+func runHookInstall(cmd *cobra.Command, args []string) error {
+	repo, err := git.Open(".")
+	if err != nil {
+		return err
+	}
+	dir, err := repo.HooksDir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "pre-commit")
+
+	if existing, err := os.ReadFile(path); err == nil && !hookForce && !strings.Contains(string(existing), hookMarker) {
+		return fmt.Errorf("%s already exists (use --force to replace it)", path)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(preCommitHook(hookFix)), 0o755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0o755); err != nil {
+		return fmt.Errorf("failed to make hook executable: %w", err)
+	}
+
+	fmt.Printf("Installed %s\n", path)
+	return nil
+}
+
+// preCommitHook returns the pre-commit hook script. It checks the staged SQL
+// files, or formats and re-stages them if fix is set. "go install" names the
+// binary go-sqlfmt, so the hook looks for that name too.
+func preCommitHook(fix bool) string {
+	run := `"$sqlfmt" check --staged || {
+	echo 'sqlfmt: run "sqlfmt format --write --staged" to format the staged SQL files' >&2
+	exit 1
+}`
+	if fix {
+		run = `"$sqlfmt" format --write --staged`
+	}
+
+	return `#!/bin/sh
+` + hookMarker + `
+
+sqlfmt=$(command -v sqlfmt || command -v go-sqlfmt) || {
+	echo "sqlfmt: not found in PATH, install it with: go install github.com/MeKo-Christian/go-sqlfmt@latest" >&2
+	exit 1
+}
+
+` + run + "\n"
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runHookInstallTest runs hook install with the given flags and discards its output.
+func runHookInstallTest(t *testing.T, fix, force bool) error {
+	t.Helper()
+	hookFix, hookForce = fix, force
+	defer func() { hookFix, hookForce = false, false }()
+
+	oldStdout := os.Stdout
+	_, w, _ := os.Pipe()
+	os.Stdout = w
+	defer func() {
+		_ = w.Close()
+		os.Stdout = oldStdout
+	}()
+
+	return runHookInstall(hookInstallCmd, nil)
+}
+
+func TestHookInstall(t *testing.T) {
+	chdirGitRepo(t, map[string]string{"a.sql": "select 1"})
+	path := filepath.Join(".git", "hooks", "pre-commit")
+
+	require.NoError(t, runHookInstallTest(t, false, false))
+	hook := readTestFile(t, path)
+	assert.Contains(t, hook, hookMarker)
+	assert.Contains(t, hook, `"$sqlfmt" check --staged`)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	// A hook installed by sqlfmt is replaced without --force
+	require.NoError(t, runHookInstallTest(t, true, false))
+	assert.Contains(t, readTestFile(t, path), `"$sqlfmt" format --write --staged`)
+
+	// Other hooks are only replaced with --force
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nmake lint\n"), 0o644))
+	err = runHookInstallTest(t, false, false)
+	require.ErrorContains(t, err, "already exists")
+	assert.Equal(t, "#!/bin/sh\nmake lint\n", readTestFile(t, path))
+
+	require.NoError(t, runHookInstallTest(t, false, true))
+	assert.Contains(t, readTestFile(t, path), hookMarker)
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+}
+
+func TestHookInstallOutsideRepository(t *testing.T) {
+	tmpDir := chdirTemp(t, nil)
+	require.NoError(t, os.Remove(".git"))
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(tmpDir))
+
+	require.ErrorContains(t, runHookInstallTest(t, false, false), "git rev-parse")
+}
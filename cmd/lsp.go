@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/lsp"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a Language Server Protocol server over standard input and output",
+	Long: `Run a Language Server Protocol server over standard input and output, for
+editors such as Neovim, Helix and VS Code.
+
+The server formats whole documents, selections and statements ended by a
+typed ";", and reports the lines that need formatting and problems such as
+unterminated strings as diagnostics. Each document is formatted with the
+config files that apply to its path and chooses the dialect exactly like
+the format command does, so editors and the command line agree. The
+formatting options sent by the editor are ignored.
+
+Examples:
+  sqlfmt lsp                     # Start the server
+  sqlfmt lsp --lang=postgresql   # Format every document as PostgreSQL
+  sqlfmt lsp --auto-detect       # Detect the dialect of each document`,
+	Args: cobra.NoArgs,
+	RunE: runLSP,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+
+	formatOpts.register(lspCmd.Flags())
+}
+
+func runLSP(cmd *cobra.Command, args []string) error {
+	baseConfig, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	server := lsp.NewServer(os.Stdin, os.Stdout, func(path, content string) *sqlfmt.Config {
+		return inputConfig(cmd, baseConfig, path, content)
+	})
+	cmd.SilenceUsage = true
+	return server.Run()
+}
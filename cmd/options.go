@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/pflag"
+)
+
+// formatOptions holds the formatting flags shared by every command that formats
+// or checks SQL. Registering the same set everywhere guarantees that format,
+// validate, check and the pretty commands agree on what "formatted" means.
+type formatOptions struct {
+	lang                              string
+	indent                            string
+	uppercase                         bool
+	keywordCase                       string
+	linesBetween                      int
+	autoDetect                        bool
+	alignColumnNames                  bool
+	alignAssignments                  bool
+	alignValues                       bool
+	maxLineLength                     int
+	preserveCommentIndent             bool
+	commentMinSpacing                 int
+	joinIndentStyle                   string
+	preserveEmptyLinesBetweenComments bool
+	lineEnding                        string
+	insertFinalNewline                bool
+	params                            map[string]string
+	positionalParams                  []string
+	configPath                        string
+	noConfig                          bool
+}
+
+// formatOpts is bound to the formatting flags of all commands. Only one
+// command runs per process, so sharing the values is safe.
+var formatOpts formatOptions
+
+// register adds the formatting flags to a command's flag set.
+func (o *formatOptions) register(flags *pflag.FlagSet) {
+	flags.StringVar(&o.lang, "lang", defaultSQLDialect,
+		"SQL dialect (sql, postgresql, mysql, pl/sql, db2, n1ql, sqlite)")
+	flags.StringVar(&o.indent, "indent", sqlfmt.DefaultIndent, "Indentation string")
+	flags.BoolVar(&o.uppercase, "uppercase", false, "Deprecated: convert keywords to uppercase")
+	flags.StringVar(&o.keywordCase, "keyword-case", string(sqlfmt.DefaultKeywordCase),
+		"Keyword casing (preserve, uppercase, lowercase, dialect)")
+	flags.IntVar(&o.linesBetween, "lines-between", sqlfmt.DefaultLinesBetweenQueries, "Lines between queries")
+	flags.BoolVar(&o.autoDetect, "auto-detect", false,
+		"Automatically detect SQL dialect from file extension and content")
+	flags.BoolVar(&o.alignColumnNames, "align-column-names", false, "Align SELECT column names vertically")
+	flags.BoolVar(&o.alignAssignments, "align-assignments", false, "Align UPDATE assignment operators vertically")
+	flags.BoolVar(&o.alignValues, "align-values", false, "Align INSERT VALUES vertically")
+	flags.IntVar(&o.maxLineLength, "max-line-length", sqlfmt.DefaultMaxLineLength,
+		"Maximum line length (0 = unlimited)")
+	flags.BoolVar(&o.preserveCommentIndent, "preserve-comment-indent", false,
+		"Preserve relative indentation of comments")
+	flags.IntVar(&o.commentMinSpacing, "comment-min-spacing", 1, "Minimum spaces before inline comments")
+	flags.StringVar(&o.joinIndentStyle, "join-indent-style", string(sqlfmt.JoinIndentDefault),
+		"JOIN indentation style (default, root-level)")
+	flags.BoolVar(&o.preserveEmptyLinesBetweenComments, "preserve-empty-lines-between-comments", false,
+		"Keep empty lines between consecutive comments")
+	flags.StringVar(&o.lineEnding, "line-ending", string(sqlfmt.DefaultLineEnding),
+		"Line ending of the output (auto, lf, crlf)")
+	flags.BoolVar(&o.insertFinalNewline, "insert-final-newline", false,
+		"Always end the output with exactly one newline")
+	flags.StringToStringVar(&o.params, "param", nil, "Named placeholder replacement (name=value, repeatable)")
+	flags.StringArrayVar(&o.positionalParams, "positional-param", nil,
+		"Indexed placeholder replacement, in order (repeatable)")
+	flags.StringVar(&o.configPath, "config", "", "Use this config file instead of searching for one")
+	flags.BoolVar(&o.noConfig, "no-config", false, "Ignore all config files")
+}
+
+// loadConfigFile resolves the config file for an input: none with --no-config,
+// the file given with --config, or the cascade found from the input's
+// directory. An empty filename searches from the working directory.
+func (o *formatOptions) loadConfigFile(filename string) (*sqlfmt.ConfigFile, error) {
+	switch {
+	case o.noConfig:
+		return &sqlfmt.ConfigFile{}, nil
+	case o.configPath != "":
+		return sqlfmt.ReadConfigFile(o.configPath)
+	case filename != "":
+		return sqlfmt.LoadConfigFileForPath(filename)
+	default:
+		return sqlfmt.LoadConfigFile()
+	}
+}
+
+// loadEditorConfig reads the EditorConfig properties for filename. Standard
+// input has no file name to match, and --no-config ignores .editorconfig too.
+func (o *formatOptions) loadEditorConfig(filename string) (*sqlfmt.EditorConfig, error) {
+	if filename == "" || o.noConfig {
+		return &sqlfmt.EditorConfig{}, nil
+	}
+	return sqlfmt.LoadEditorConfig(filename)
+}
+
+// apply copies every flag that was set explicitly onto config, so that flags
+// override configuration files but unset flags keep their settings.
+func (o *formatOptions) apply(flags *pflag.FlagSet, config *sqlfmt.Config) {
+	// With auto-detection the language is resolved per file
+	if flags.Changed("lang") && !o.autoDetect {
+		config.WithLang(o.language())
+	}
+	if flags.Changed("indent") {
+		config.WithIndent(o.indent)
+	}
+
+	// --uppercase takes precedence for backward compatibility
+	if flags.Changed("uppercase") && o.uppercase {
+		config.WithKeywordCase(sqlfmt.KeywordCaseUppercase)
+	} else if flags.Changed("keyword-case") {
+		config.WithKeywordCase(o.parsedKeywordCase())
+	}
+
+	if flags.Changed("lines-between") {
+		config.WithLinesBetweenQueries(o.linesBetween)
+	}
+	if flags.Changed("align-column-names") {
+		config.WithAlignColumnNames(o.alignColumnNames)
+	}
+	if flags.Changed("align-assignments") {
+		config.WithAlignAssignments(o.alignAssignments)
+	}
+	if flags.Changed("align-values") {
+		config.WithAlignValues(o.alignValues)
+	}
+	if flags.Changed("max-line-length") {
+		config.WithMaxLineLength(o.maxLineLength)
+	}
+	if flags.Changed("preserve-comment-indent") {
+		config.WithPreserveCommentIndent(o.preserveCommentIndent)
+	}
+	if flags.Changed("comment-min-spacing") {
+		config.WithCommentMinSpacing(o.commentMinSpacing)
+	}
+	if flags.Changed("join-indent-style") {
+		config.WithJoinIndentStyle(o.parsedJoinIndentStyle())
+	}
+	if flags.Changed("preserve-empty-lines-between-comments") {
+		config.WithPreserveEmptyLinesBetweenComments(o.preserveEmptyLinesBetweenComments)
+	}
+	if flags.Changed("line-ending") {
+		config.WithLineEnding(o.parsedLineEnding())
+	}
+	if flags.Changed("insert-final-newline") {
+		config.WithInsertFinalNewline(o.insertFinalNewline)
+	}
+	if flags.Changed("param") {
+		config.WithParams(sqlfmt.NewMapParams(o.params))
+	}
+	if flags.Changed("positional-param") {
+		config.WithParams(sqlfmt.NewListParams(o.positionalParams))
+	}
+}
+
+func (o *formatOptions) language() sqlfmt.Language {
+	lang, err := sqlfmt.ParseLanguage(o.lang)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unknown language %s, using standard SQL\n", o.lang)
+	}
+	return lang
+}
+
+func (o *formatOptions) parsedKeywordCase() sqlfmt.KeywordCase {
+	keywordCase, err := sqlfmt.ParseKeywordCase(o.keywordCase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unknown keyword-case %s, using preserve\n", o.keywordCase)
+	}
+	return keywordCase
+}
+
+func (o *formatOptions) parsedJoinIndentStyle() sqlfmt.JoinIndentStyle {
+	style, err := sqlfmt.ParseJoinIndentStyle(o.joinIndentStyle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unknown join-indent-style %s, using default\n", o.joinIndentStyle)
+	}
+	return style
+}
+
+func (o *formatOptions) parsedLineEnding() sqlfmt.LineEnding {
+	lineEnding, err := sqlfmt.ParseLineEnding(o.lineEnding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: unknown line-ending %s, using auto\n", o.lineEnding)
+	}
+	return lineEnding
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormattingFlagsRegisteredOnEveryCommand(t *testing.T) {
+	reference := pflag.NewFlagSet("reference", pflag.ContinueOnError)
+	(&formatOptions{}).register(reference)
+
+	commands := []*cobra.Command{formatCmd, validateCmd, checkCmd, prettyFormatCmd, prettyPrintCmd, lspCmd, serveCmd, daemonCmd, replCmd, goCmd}
+	for _, c := range commands {
+		t.Run(c.Name(), func(t *testing.T) {
+			reference.VisitAll(func(want *pflag.Flag) {
+				got := c.Flags().Lookup(want.Name)
+				require.NotNil(t, got, "missing --%s", want.Name)
+				assert.Equal(t, want.DefValue, got.DefValue, "--%s default", want.Name)
+				assert.Equal(t, want.Value.Type(), got.Value.Type(), "--%s type", want.Name)
+			})
+		})
+	}
+}
+
+func TestFormatOptionsApply(t *testing.T) {
+	opts := &formatOptions{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	opts.register(flags)
+
+	require.NoError(t, flags.Parse([]string{
+		"--lang=postgres",
+		"--indent=\t",
+		"--keyword-case=lowercase",
+		"--lines-between=0",
+		"--align-column-names",
+		"--align-assignments",
+		"--align-values",
+		"--max-line-length=100",
+		"--preserve-comment-indent",
+		"--comment-min-spacing=3",
+		"--join-indent-style=root-level",
+		"--preserve-empty-lines-between-comments",
+		"--line-ending=crlf",
+		"--insert-final-newline",
+		"--param=id=42",
+	}))
+
+	config := sqlfmt.NewDefaultConfig()
+	opts.apply(flags, config)
+
+	assert.Equal(t, sqlfmt.PostgreSQL, config.Language)
+	assert.Equal(t, "\t", config.Indent)
+	assert.Equal(t, sqlfmt.KeywordCaseLowercase, config.KeywordCase)
+	assert.Equal(t, 0, config.LinesBetweenQueries)
+	assert.True(t, config.AlignColumnNames)
+	assert.True(t, config.AlignAssignments)
+	assert.True(t, config.AlignValues)
+	assert.Equal(t, 100, config.MaxLineLength)
+	assert.True(t, config.PreserveCommentIndent)
+	assert.Equal(t, 3, config.CommentMinSpacing)
+	assert.Equal(t, sqlfmt.JoinIndentRootLevel, config.JoinIndentStyle)
+	assert.True(t, config.PreserveEmptyLinesBetweenComments)
+	assert.Equal(t, sqlfmt.LineEndingCRLF, config.LineEnding)
+	assert.True(t, config.InsertFinalNewline)
+	assert.Equal(t, map[string]string{"id": "42"}, config.Params.MapParams)
+}
+
+func TestFormatOptionsApplyKeepsUnsetValues(t *testing.T) {
+	opts := &formatOptions{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	opts.register(flags)
+	require.NoError(t, flags.Parse([]string{"--auto-detect", "--lang=mysql", "--align-values"}))
+
+	config := sqlfmt.NewDefaultConfig().WithIndent("    ").WithLang(sqlfmt.SQLite)
+	opts.apply(flags, config)
+
+	// The language is left to per-file detection, other flags still apply
+	assert.Equal(t, sqlfmt.SQLite, config.Language)
+	assert.Equal(t, "    ", config.Indent)
+	assert.True(t, config.AlignValues)
+}
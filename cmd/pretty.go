@@ -43,52 +43,39 @@ func init() {
 	rootCmd.AddCommand(prettyPrintCmd)
 
 	// Add flags for pretty-format (same as format but color is always enabled)
-	prettyFormatCmd.Flags().StringVar(&lang, "lang", "sql", "Dialect")
-	prettyFormatCmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	formatOpts.register(prettyFormatCmd.Flags())
+	registerStdinFilepath(prettyFormatCmd.Flags())
 	prettyFormatCmd.Flags().BoolVarP(&write, "write", "w", false, "Write result to file instead of stdout")
-	prettyFormatCmd.Flags().BoolVar(
-		&uppercase,
-		"uppercase",
-		false,
-		"Uppercase keywords (deprecated)",
-	)
-	prettyFormatCmd.Flags().StringVar(
-		&keywordCase,
-		"keyword-case",
-		"preserve",
-		"Keyword case",
-	)
-	prettyFormatCmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	prettyFormatCmd.Flags().StringVar(&backupSuffix, "backup", "",
+		"With --write, keep the original of each changed file under its name plus this suffix")
 
 	// Add flags for pretty-print (same as pretty-format but no write option)
-	prettyPrintCmd.Flags().StringVar(&lang, "lang", "sql", "Dialect")
-	prettyPrintCmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	prettyPrintCmd.Flags().BoolVar(
-		&uppercase,
-		"uppercase",
-		false,
-		"Uppercase keywords (deprecated)",
-	)
-	prettyPrintCmd.Flags().StringVar(
-		&keywordCase,
-		"keyword-case",
-		"preserve",
-		"Keyword case",
-	)
-	prettyPrintCmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	formatOpts.register(prettyPrintCmd.Flags())
+	registerStdinFilepath(prettyPrintCmd.Flags())
 }
 
 func runPrettyFormat(cmd *cobra.Command, args []string) error {
-	config := buildConfig(cmd)
+	if backupSuffix != "" && !write {
+		return fmt.Errorf("--backup requires --write")
+	}
+
+	config, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
 
 	// If no args or args is "-", read from stdin
-	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
-		return prettyFormatStdin(config)
+	readStdin := len(args) == 0 || (len(args) == 1 && args[0] == "-")
+	if err := checkStdinFilepath(readStdin); err != nil {
+		return err
+	}
+	if readStdin {
+		return prettyFormatStdin(cmd, config)
 	}
 
 	// Process files
 	for _, filename := range args {
-		if err := prettyFormatFile(filename, config); err != nil {
+		if err := prettyFormatFile(cmd, filename, config); err != nil {
 			return fmt.Errorf("failed to pretty format %s: %w", filename, err)
 		}
 	}
@@ -97,16 +84,23 @@ func runPrettyFormat(cmd *cobra.Command, args []string) error {
 }
 
 func runPrettyPrint(cmd *cobra.Command, args []string) error {
-	config := buildConfig(cmd)
+	config, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
 
 	// If no args or args is "-", read from stdin
-	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
-		return prettyPrintStdin(config)
+	readStdin := len(args) == 0 || (len(args) == 1 && args[0] == "-")
+	if err := checkStdinFilepath(readStdin); err != nil {
+		return err
+	}
+	if readStdin {
+		return prettyPrintStdin(cmd, config)
 	}
 
 	// Process files
 	for _, filename := range args {
-		if err := prettyPrintFile(filename, config); err != nil {
+		if err := prettyPrintFile(cmd, filename, config); err != nil {
 			return fmt.Errorf("failed to pretty print %s: %w", filename, err)
 		}
 	}
@@ -114,31 +108,39 @@ func runPrettyPrint(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func prettyFormatStdin(config *sqlfmt.Config) error {
+func prettyFormatStdin(cmd *cobra.Command, baseConfig *sqlfmt.Config) error {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return fmt.Errorf("failed to read stdin: %w", err)
 	}
 
-	formatted := sqlfmt.PrettyFormat(string(input), config)
+	if stdinIgnored() {
+		fmt.Print(string(input))
+		return nil
+	}
+
+	formatted := sqlfmt.PrettyFormatDocument(string(input), inputConfig(cmd, baseConfig, stdinFilepath, string(input)))
 	fmt.Print(formatted)
 	return nil
 }
 
-func prettyFormatFile(filename string, config *sqlfmt.Config) error {
+func prettyFormatFile(cmd *cobra.Command, filename string, baseConfig *sqlfmt.Config) error {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	formatted := sqlfmt.PrettyFormat(string(content), config)
+	formatted := sqlfmt.PrettyFormatDocument(string(content), inputConfig(cmd, baseConfig, filename, string(content)))
 
 	if write {
-		// Write back to file
-		if err := os.WriteFile(filename, []byte(formatted), 0o644); err != nil {
+		// Write back to file, skipping files that are already formatted
+		written, err := writeFormatted(filename, string(content), formatted)
+		if err != nil {
 			return fmt.Errorf("failed to write file: %w", err)
 		}
-		fmt.Printf("Pretty formatted %s\n", filename)
+		if written {
+			fmt.Printf("Pretty formatted %s\n", filename)
+		}
 	} else {
 		// Output to stdout
 		fmt.Print(formatted)
@@ -147,22 +149,27 @@ func prettyFormatFile(filename string, config *sqlfmt.Config) error {
 	return nil
 }
 
-func prettyPrintStdin(config *sqlfmt.Config) error {
+func prettyPrintStdin(cmd *cobra.Command, baseConfig *sqlfmt.Config) error {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return fmt.Errorf("failed to read stdin: %w", err)
 	}
 
-	sqlfmt.PrettyPrint(string(input), config)
+	if stdinIgnored() {
+		fmt.Println(string(input))
+		return nil
+	}
+
+	sqlfmt.PrettyPrint(string(input), inputConfig(cmd, baseConfig, stdinFilepath, string(input)))
 	return nil
 }
 
-func prettyPrintFile(filename string, config *sqlfmt.Config) error {
+func prettyPrintFile(cmd *cobra.Command, filename string, baseConfig *sqlfmt.Config) error {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	sqlfmt.PrettyPrint(string(content), config)
+	sqlfmt.PrettyPrint(string(content), inputConfig(cmd, baseConfig, filename, string(content)))
 	return nil
 }
@@ -15,22 +15,22 @@ const prettyTestSQLDialect = "sql"
 
 func TestPrettyFormatStdin(t *testing.T) {
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
 	write = false
-	uppercase = false
-	linesBetween = 2
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -80,22 +80,22 @@ func TestPrettyFormatFile(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
 	write = false
-	uppercase = false
-	linesBetween = 2
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -131,22 +131,22 @@ func TestPrettyFormatFileWithWrite(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
 	write = true // Enable write flag
-	uppercase = false
-	linesBetween = 2
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", true, "Write result to file")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -177,22 +177,22 @@ func TestPrettyFormatFileWithWrite(t *testing.T) {
 
 func TestPrettyFormatFileError(t *testing.T) {
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
 	write = false
-	uppercase = false
-	linesBetween = 2
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Run the command with a non-existent file
 	cmd.SetArgs([]string{"/nonexistent/file.sql"})
@@ -205,20 +205,20 @@ func TestPrettyFormatFileError(t *testing.T) {
 
 func TestPrettyPrintStdin(t *testing.T) {
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-print [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyPrint,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -268,20 +268,20 @@ func TestPrettyPrintFile(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-print [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyPrint,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -307,20 +307,20 @@ func TestPrettyPrintFile(t *testing.T) {
 
 func TestPrettyPrintFileError(t *testing.T) {
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-print [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyPrint,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Run the command with a non-existent file
 	cmd.SetArgs([]string{"/nonexistent/file.sql"})
@@ -353,22 +353,22 @@ func TestPrettyFormatMultipleFiles(t *testing.T) {
 	_ = tmpFile2.Close()
 
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
 	write = false
-	uppercase = false
-	linesBetween = 2
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-format [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyFormat,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 	cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -416,20 +416,20 @@ func TestPrettyPrintMultipleFiles(t *testing.T) {
 	_ = tmpFile2.Close()
 
 	// Reset global flags
-	lang = prettyTestSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = prettyTestSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	cmd := &cobra.Command{
 		Use:  "pretty-print [files...]",
 		Args: cobra.ArbitraryArgs,
 		RunE: runPrettyPrint,
 	}
-	cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -476,22 +476,22 @@ func TestPrettyFormatWithDialects(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags
-			lang = tt.dialect
-			indent = "  "
+			formatOpts.lang = tt.dialect
+			formatOpts.indent = "  "
 			write = false
-			uppercase = false
-			linesBetween = 2
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
 
 			cmd := &cobra.Command{
 				Use:  "pretty-format [files...]",
 				Args: cobra.ArbitraryArgs,
 				RunE: runPrettyFormat,
 			}
-			cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
 			cmd.Flags().BoolVar(&write, "write", false, "Write result to file")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 			// Mark the lang flag as changed
 			_ = cmd.Flags().Set("lang", tt.dialect)
@@ -553,20 +553,20 @@ func TestPrettyPrintWithDialects(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags
-			lang = tt.dialect
-			indent = "  "
-			uppercase = false
-			linesBetween = 2
+			formatOpts.lang = tt.dialect
+			formatOpts.indent = "  "
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
 
 			cmd := &cobra.Command{
 				Use:  "pretty-print [files...]",
 				Args: cobra.ArbitraryArgs,
 				RunE: runPrettyPrint,
 			}
-			cmd.Flags().StringVar(&lang, "lang", prettyTestSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", prettyTestSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 			// Mark the lang flag as changed
 			_ = cmd.Flags().Set("lang", tt.dialect)
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var replCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Try formatting options interactively",
+	Long: `Read SQL interactively and print each query, once it is terminated by ";",
+formatted with colors. Commands change the settings or inspect the last query:
+
+  :set                 Show the settings as a config file would contain them
+  :set KEY VALUE       Change a setting, using the keys of .sqlfmt.yaml
+  :tokens              Show the tokens of the last query with their types
+  :detect              Detect the dialect of the last query
+  :help                Show the commands
+  :quit                Leave the REPL, like end of input
+
+Examples:
+  sqlfmt repl
+  sqlfmt repl --lang=postgresql
+  sqlfmt> :set keyword_case upper
+  sqlfmt> :set indent "    "`,
+	Args: cobra.NoArgs,
+	RunE: runREPL,
+}
+
+func init() {
+	rootCmd.AddCommand(replCmd)
+
+	formatOpts.register(replCmd.Flags())
+}
+
+func runREPL(cmd *cobra.Command, args []string) error {
+	config, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	// Prompts would only clutter the output of piped input
+	prompt := false
+	if info, err := os.Stdin.Stat(); err == nil {
+		prompt = info.Mode()&os.ModeCharDevice != 0
+	}
+	return newREPL(config, os.Stdin, os.Stdout, prompt).run()
+}
+
+// Prompts of the REPL, for a new query and for its continuation lines.
+const (
+	replPrompt         = "sqlfmt> "
+	replContinuePrompt = "     -> "
+)
+
+// replHelp lists the commands of the REPL.
+const replHelp = `Enter SQL terminated by ";" to format it. Commands:
+  :set                 Show the settings
+  :set KEY VALUE       Change a setting, e.g. ":set keyword_case upper"
+  :tokens              Show the tokens of the last query
+  :detect              Detect the dialect of the last query
+  :help                Show this help
+  :quit                Leave the REPL
+`
+
+// repl reads queries and commands line by line and prints the results.
+type repl struct {
+	config  *sqlfmt.Config
+	in      *bufio.Scanner
+	out     io.Writer
+	prompt  bool
+	pending strings.Builder // lines of the query being entered
+	last    string          // the last complete query
+}
+
+func newREPL(config *sqlfmt.Config, in io.Reader, out io.Writer, prompt bool) *repl {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, maxREPLLine)
+	config = config.Clone()
+	// Output is always colored, so :set shows the colors that apply
+	if config.ColorConfig == nil || config.ColorConfig.Empty() {
+		config.ColorConfig = sqlfmt.NewDefaultColorConfig()
+	}
+	return &repl{config: config, in: scanner, out: out, prompt: prompt}
+}
+
+// maxREPLLine is the length of the longest line the REPL reads, which allows
+// pasting long single-line queries.
+const maxREPLLine = 16 << 20
+
+// run reads until the end of input or :quit. A query that is not terminated
+// by the end of input is formatted too.
+func (r *repl) run() error {
+	for {
+		r.printPrompt()
+		if !r.in.Scan() {
+			break
+		}
+		line := r.in.Text()
+
+		if r.pending.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			if !r.command(strings.TrimSpace(line)) {
+				return nil
+			}
+			continue
+		}
+
+		r.pending.WriteString(line)
+		r.pending.WriteString("\n")
+		if r.complete() {
+			r.formatPending()
+		}
+	}
+
+	if r.prompt {
+		fmt.Fprintln(r.out)
+	}
+	r.formatPending()
+	return r.in.Err()
+}
+
+func (r *repl) printPrompt() {
+	if !r.prompt {
+		return
+	}
+	if r.pending.Len() == 0 {
+		fmt.Fprint(r.out, replPrompt)
+	} else {
+		fmt.Fprint(r.out, replContinuePrompt)
+	}
+}
+
+// complete reports whether the pending input ends with a semicolon outside
+// of strings and comments.
+func (r *repl) complete() bool {
+	tokens := sqlfmt.Tokenize(r.pending.String(), r.config)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].Type {
+		case types.TokenTypeWhitespace, types.TokenTypeLineComment, types.TokenTypeBlockComment:
+			continue
+		}
+		return tokens[i].Value == ";"
+	}
+	return false
+}
+
+// formatPending prints the pending query formatted and makes it the last query.
+func (r *repl) formatPending() {
+	query := r.pending.String()
+	r.pending.Reset()
+	if strings.TrimSpace(query) == "" {
+		return
+	}
+
+	r.last = query
+	fmt.Fprintln(r.out, sqlfmt.PrettyFormat(query, r.config))
+}
+
+// command runs a REPL command and reports whether to continue.
+func (r *repl) command(line string) bool {
+	name, args, _ := strings.Cut(line, " ")
+	switch name {
+	case ":quit", ":q", ":exit":
+		return false
+	case ":help", ":h":
+		fmt.Fprint(r.out, replHelp)
+	case ":set":
+		if err := r.set(strings.TrimSpace(args)); err != nil {
+			fmt.Fprintf(r.out, "Error: %v\n", err)
+		}
+	case ":tokens":
+		r.printTokens()
+	case ":detect":
+		r.detect()
+	default:
+		fmt.Fprintf(r.out, "Unknown command %s, type :help for the commands\n", name)
+	}
+	return true
+}
+
+// set changes one setting, or shows them all without arguments. Values are
+// read as YAML, like in a config file, so that numbers, booleans, lists and
+// quoted strings keep their types.
+func (r *repl) set(args string) error {
+	if args == "" {
+		var doc yaml.Node
+		if err := doc.Encode(sqlfmt.NewConfigSettings(r.config)); err != nil {
+			return err
+		}
+		for i := 1; i < len(doc.Content); i += 2 {
+			useFlowLists(doc.Content[i])
+		}
+		encoder := yaml.NewEncoder(r.out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	key, value, found := strings.Cut(args, " ")
+	value = strings.TrimSpace(value)
+	if !found || value == "" {
+		return fmt.Errorf("usage: :set KEY VALUE")
+	}
+	key = replSettingKey(key)
+
+	var parsed any
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil || parsed == nil {
+		parsed = value
+	}
+	if key == "keyword_case" {
+		parsed = replKeywordCase(parsed)
+	}
+
+	data, err := json.Marshal(map[string]any{key: parsed})
+	if err != nil {
+		return err
+	}
+	settings, err := sqlfmt.ParseConfigFile("repl.json", data)
+	if err != nil {
+		return withoutPosition(err)
+	}
+	if settings.Extends != "" || len(settings.Overrides) > 0 {
+		return fmt.Errorf("%s cannot be set in the REPL", key)
+	}
+
+	// Only apply complete changes, so that a bad value keeps the settings
+	config := r.config.Clone()
+	if err := settings.ApplyToConfig(config); err != nil {
+		return withoutPosition(err)
+	}
+	r.config = config
+	return nil
+}
+
+// withoutPosition drops the file and line from config errors, which would
+// only name the document that set builds.
+func withoutPosition(err error) error {
+	var configErr *sqlfmt.ConfigError
+	if errors.As(err, &configErr) {
+		configErr.File, configErr.Line = "", 0
+	}
+	return err
+}
+
+// replSettingKey maps the short names and flag names that come naturally in
+// the REPL to config file keys.
+func replSettingKey(key string) string {
+	switch key {
+	case "lang", "dialect":
+		return "language"
+	case "lines-between", "lines_between":
+		return "lines_between_queries"
+	default:
+		return strings.ReplaceAll(key, "-", "_")
+	}
+}
+
+// replKeywordCase accepts "upper" and "lower" for the keyword cases.
+func replKeywordCase(value any) any {
+	switch value {
+	case "upper":
+		return string(sqlfmt.KeywordCaseUppercase)
+	case "lower":
+		return string(sqlfmt.KeywordCaseLowercase)
+	default:
+		return value
+	}
+}
+
+// printTokens prints the tokens of the last query other than whitespace, with
+// their offsets and types.
+func (r *repl) printTokens() {
+	if r.last == "" {
+		fmt.Fprintln(r.out, "No query yet")
+		return
+	}
+	for _, token := range sqlfmt.Tokenize(r.last, r.config) {
+		if token.Type == types.TokenTypeWhitespace {
+			continue
+		}
+		fmt.Fprintf(r.out, "%5d  %-28s %q\n", token.Offset, token.Type, token.Value)
+	}
+}
+
+// detect prints the dialect that the content of the last query suggests.
+func (r *repl) detect() {
+	if r.last == "" {
+		fmt.Fprintln(r.out, "No query yet")
+		return
+	}
+	if lang, ok := sqlfmt.DetectDialect("", r.last); ok {
+		fmt.Fprintf(r.out, "Detected %s, use :set lang %s to format with it\n", lang, lang)
+		return
+	}
+	fmt.Fprintf(r.out, "No dialect detected, formatting with %s\n", r.config.Language)
+}
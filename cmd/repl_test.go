@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runREPLInput runs the REPL on input and returns its output.
+func runREPLInput(t *testing.T, config *sqlfmt.Config, input string) string {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, newREPL(config, strings.NewReader(input), &out, false).run())
+	return out.String()
+}
+
+func TestREPLFormatsTerminatedQueries(t *testing.T) {
+	input := "select a,\n  b from t;\nselect ';' from u -- not done;\n;\nselect 1"
+	want := sqlfmt.PrettyFormat("select a,\n  b from t;\n") + "\n" +
+		sqlfmt.PrettyFormat("select ';' from u -- not done;\n;\n") + "\n" +
+		sqlfmt.PrettyFormat("select 1\n") + "\n"
+	assert.Equal(t, want, runREPLInput(t, sqlfmt.NewDefaultConfig(), input))
+}
+
+func TestREPLSet(t *testing.T) {
+	config := sqlfmt.NewDefaultConfig()
+	input := ":set lang postgresql\n:set keyword_case upper\n:set indent \"    \"\n:set align-values true\n" +
+		"select a::int from t;\n"
+
+	want := sqlfmt.PrettyFormat("select a::int from t;\n", sqlfmt.NewDefaultConfig().WithLang(sqlfmt.PostgreSQL).
+		WithKeywordCase(sqlfmt.KeywordCaseUppercase).WithIndent("    ")) + "\n"
+	assert.Equal(t, want, runREPLInput(t, config, input))
+	assert.Equal(t, sqlfmt.StandardSQL, config.Language, "the config of the caller is not changed")
+}
+
+func TestREPLSetErrors(t *testing.T) {
+	out := runREPLInput(t, sqlfmt.NewDefaultConfig(),
+		":set bogus 1\n:set keyword_case shouty\n:set indent\n:set indent 4\n:set extends base.yaml\n:nope\n")
+	assert.Equal(t, "Error: bogus: unknown key\n"+
+		"Error: keyword_case: unknown keyword_case in config: shouty\n"+
+		"Error: usage: :set KEY VALUE\n"+
+		"Error: indent: expected a string\n"+
+		"Error: extends cannot be set in the REPL\n"+
+		"Unknown command :nope, type :help for the commands\n", out)
+}
+
+func TestREPLShowSettings(t *testing.T) {
+	out := runREPLInput(t, sqlfmt.NewDefaultConfig(), ":set keyword_case lower\n:set\n")
+	assert.Contains(t, out, "language: sql\n")
+	assert.Contains(t, out, "keyword_case: lowercase\n")
+	assert.Contains(t, out, "  reserved_words: [cyan, bold]\n")
+}
+
+func TestREPLTokensAndDetect(t *testing.T) {
+	out := runREPLInput(t, sqlfmt.NewDefaultConfig(), ":tokens\n:detect\nselect 'a' from t;\n:tokens\n:detect\n:quit\nselect 2;\n")
+	lines := strings.Split(out, "\n")
+
+	assert.Equal(t, "No query yet", lines[0])
+	assert.Equal(t, "No query yet", lines[1])
+	assert.NotContains(t, out, sqlfmt.PrettyFormat("select 2;"), "input after :quit is not read")
+	assert.Contains(t, out, `    0  reserved-top-level           "select"`+"\n")
+	assert.Contains(t, out, `    7  string                       "'a'"`+"\n")
+	assert.Contains(t, out, `   17  operator                     ";"`+"\n")
+	assert.Contains(t, out, "No dialect detected, formatting with sql\n")
+
+	out = runREPLInput(t, sqlfmt.NewDefaultConfig(), "select data->>'name' from t where id = $1;\n:detect\n")
+	assert.Contains(t, out, "Detected postgresql, use :set lang postgresql to format with it\n")
+}
@@ -1,10 +1,33 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+
 	"github.com/MeKo-Christian/go-sqlfmt/internal/version"
 	"github.com/spf13/cobra"
 )
 
+// Exit codes returned by Execute.
+const (
+	exitOK          = 0
+	exitFailure     = 1 // a command returned an error
+	exitUnformatted = 1 // a check found files that need formatting
+	exitError       = 2 // a check could not read or process an input
+)
+
+// exitStatusError asks Execute to terminate the process with a specific exit code.
+// Commands return it instead of calling os.Exit so that deferred cleanup runs
+// and the commands stay embeddable.
+type exitStatusError struct {
+	code int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "sqlfmt",
 	Short: "A SQL formatter for multiple dialects",
@@ -13,11 +36,24 @@ multiple SQL dialects including Standard SQL, PostgreSQL, N1QL, DB2, and PL/SQL.
 
 It provides both programmatic access as a Go library and command-line formatting
 capabilities with customizable indentation, colors, and dialect-specific formatting.`,
-	Version: "v" + version.Version,
+	Version:       "v" + version.Version,
+	SilenceErrors: true,
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+// Execute runs the root command and returns the process exit code.
+func Execute() int {
+	err := rootCmd.Execute()
+	if err == nil {
+		return exitOK
+	}
+
+	var statusErr *exitStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	return exitFailure
 }
 
 func init() {
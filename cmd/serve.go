@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/server"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr           string
+	serveMaxRequestSize int64
+	serveTimeout        time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve an HTTP API for formatting SQL",
+	Long: `Serve an HTTP API for formatting SQL, for tools that would otherwise run
+sqlfmt once per query.
+
+Endpoints:
+  GET  /health    Report that the server is up, with its version
+  GET  /dialects  List the supported dialects
+  POST /format    Format {"query", "language", "options"}; returns the
+                  formatted query, its tokens and diagnostics for the query
+  POST /detect    Detect the dialect of {"query", "filename"}
+
+The options of a format request are config file settings such as
+"keyword_case". They refine the configuration from the config files of the
+working directory and the command-line flags; "language" overrides all.
+
+Examples:
+  sqlfmt serve                           # Listen on localhost:8080
+  sqlfmt serve --addr=:9000 --lang=mysql # Listen on all interfaces, MySQL by default
+  curl -d '{"query": "select 1"}' localhost:8080/format`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	formatOpts.register(serveCmd.Flags())
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Address to listen on")
+	serveCmd.Flags().Int64Var(&serveMaxRequestSize, "max-request-size", server.DefaultMaxRequestSize,
+		"Maximum size of a request body in bytes")
+	serveCmd.Flags().DurationVar(&serveTimeout, "timeout", server.DefaultTimeout, "Maximum time to handle a request")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	baseConfig, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var dialects []server.Dialect
+	for _, dialect := range getSupportedDialects() {
+		dialects = append(dialects, server.Dialect{
+			Name:        dialect.name,
+			Description: dialect.description,
+			Aliases:     dialect.aliases,
+		})
+	}
+
+	listener, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", listener.Addr())
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.Serve(ctx, listener, server.Options{
+		Config: func(query string) *sqlfmt.Config {
+			return inputConfig(cmd, baseConfig, "", query)
+		},
+		Dialects:       dialects,
+		MaxRequestSize: serveMaxRequestSize,
+		Timeout:        serveTimeout,
+	})
+}
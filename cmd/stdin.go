@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/pflag"
+)
+
+// stdinFilepath is the path that standard input is treated as for config
+// files, .sqlfmtignore and dialect detection. Empty treats standard input as
+// coming from no file.
+var stdinFilepath string
+
+// registerStdinFilepath adds --stdin-filepath to the commands that read
+// standard input.
+func registerStdinFilepath(flags *pflag.FlagSet) {
+	flags.StringVar(&stdinFilepath, "stdin-filepath", "",
+		"Treat standard input as the file at this path for config files, .sqlfmtignore and --auto-detect")
+}
+
+// checkStdinFilepath rejects --stdin-filepath unless standard input is read.
+func checkStdinFilepath(readsStdin bool) error {
+	if stdinFilepath != "" && !readsStdin {
+		return fmt.Errorf("--stdin-filepath requires reading standard input")
+	}
+	return nil
+}
+
+// stdinIgnored reports whether .sqlfmtignore excludes the path given with
+// --stdin-filepath. Like ignored files, ignored input is passed through
+// unchanged, so that editors can format every buffer alike.
+func stdinIgnored() bool {
+	if stdinFilepath == "" {
+		return false
+	}
+	ignoreFile, err := sqlfmt.LoadIgnoreFile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load ignore file: %v\n", err)
+	}
+	return ignoreFile != nil && ignoreFile.ShouldIgnore(stdinFilepath)
+}
+
+// stdinDisplayName is the name reported for standard input: the path given
+// with --stdin-filepath, or else name.
+func stdinDisplayName(name string) string {
+	if stdinFilepath != "" {
+		return stdinFilepath
+	}
+	return name
+}
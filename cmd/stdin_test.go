@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runWithStdin runs the RunE of a command with fresh formatting flags on
+// input and returns what it printed.
+func runWithStdin(t *testing.T, run func(*cobra.Command, []string) error, input string, args ...string) (string, error) {
+	t.Helper()
+	formatOpts = formatOptions{}
+	cmd := &cobra.Command{Use: "format", RunE: run}
+	formatOpts.register(cmd.Flags())
+	registerStdinFilepath(cmd.Flags())
+	cmd.SetArgs(args)
+
+	oldStdout, oldStdin := os.Stdout, os.Stdin
+	r, w, _ := os.Pipe()
+	stdinReader, stdinWriter, _ := os.Pipe()
+	os.Stdout, os.Stdin = w, stdinReader
+	go func() {
+		defer func() { _ = stdinWriter.Close() }()
+		_, _ = stdinWriter.WriteString(input)
+	}()
+
+	err := cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout, os.Stdin = oldStdout, oldStdin
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	return buf.String(), err
+}
+
+// TestStdinFilepath tests that standard input named with --stdin-filepath is
+// formatted like the file at that path would be.
+func TestStdinFilepath(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		"pg/.sqlfmt.yaml": "keyword_case: uppercase\n",
+		".sqlfmtignore":   "generated/\n",
+	})
+	require.NoError(t, os.Mkdir("generated", 0o755))
+
+	write, color, listDifferent, printDiff, checkOnly = false, false, false, false, false
+	defer func() {
+		stdinFilepath = ""
+		listDifferent = false
+	}()
+
+	tests := []struct {
+		name     string
+		args     []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "config of the directory",
+			args:     []string{"--stdin-filepath=pg/new.sql"},
+			input:    "select a from t;\n",
+			expected: "SELECT\n  a\nFROM\n  t;\n",
+		},
+		{
+			name:     "without the flag",
+			input:    "select a from t;\n",
+			expected: "select\n  a\nfrom\n  t;\n",
+		},
+		{
+			name:     "extension-based detection",
+			args:     []string{"--auto-detect", "--stdin-filepath=q.mysql.sql"},
+			input:    "select `a` from t where b = #c\n1;\n",
+			expected: "select\n  `a`\nfrom\n  t\nwhere\n  b = #c\n  1;\n",
+		},
+		{
+			name:     "ignored path passes through",
+			args:     []string{"--stdin-filepath=generated/q.sql"},
+			input:    "select  a from t;\n",
+			expected: "select  a from t;\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdinFilepath = ""
+			output, err := runWithStdin(t, runFormat, tt.input, tt.args...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, output)
+		})
+	}
+
+	t.Run("reported name", func(t *testing.T) {
+		stdinFilepath = ""
+		listDifferent = true
+		defer func() { listDifferent = false }()
+		output, err := runWithStdin(t, runFormat, "select  a from t;\n", "--stdin-filepath=pg/new.sql")
+		require.NoError(t, err)
+		assert.Equal(t, "pg/new.sql\n", output)
+	})
+
+	t.Run("requires standard input", func(t *testing.T) {
+		stdinFilepath = ""
+		_, err := runWithStdin(t, runFormat, "", "--stdin-filepath=pg/new.sql", "other.sql")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--stdin-filepath requires reading standard input")
+	})
+}
+
+func TestValidateStdinFilepath(t *testing.T) {
+	chdirTemp(t, map[string]string{".sqlfmtignore": "legacy.sql\n"})
+
+	outputFormat, showDiff = "text", false
+	defer func() { stdinFilepath = "" }()
+
+	output, err := runWithStdin(t, runValidate, "select  a from t;\n", "--stdin-filepath=q.sql")
+	var statusErr *exitStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, exitUnformatted, statusErr.code)
+	assert.Contains(t, output, "q.sql: needs formatting")
+
+	stdinFilepath = ""
+	output, err = runWithStdin(t, runValidate, "select  a from t;\n", "--stdin-filepath=legacy.sql")
+	require.NoError(t, err)
+	assert.NotContains(t, output, "needs formatting")
+}
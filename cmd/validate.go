@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
 
+	"github.com/MeKo-Christian/go-sqlfmt/internal/cache"
+	"github.com/MeKo-Christian/go-sqlfmt/internal/diff"
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
 	"github.com/spf13/cobra"
 )
@@ -17,21 +18,10 @@ var (
 )
 
 // ValidationResult represents the result of validating a single file.
-type ValidationResult struct {
-	File  string `json:"file"`
-	Valid bool   `json:"valid"`
-	Diff  string `json:"diff,omitempty"`
-	Error string `json:"error,omitempty"`
-}
+type ValidationResult = sqlfmt.CheckResult
 
 // ValidationSummary represents the overall validation summary.
-type ValidationSummary struct {
-	TotalFiles   int                `json:"total_files"`
-	ValidFiles   int                `json:"valid_files"`
-	InvalidFiles int                `json:"invalid_files"`
-	ErrorFiles   int                `json:"error_files"`
-	Results      []ValidationResult `json:"results"`
-}
+type ValidationSummary = sqlfmt.CheckSummary
 
 var validateCmd = &cobra.Command{
 	Use:   "validate [files...]",
@@ -51,6 +41,8 @@ Examples:
   sqlfmt validate --lang=postgresql *.sql    # Validate all SQL files
   sqlfmt validate --output=json *.sql        # JSON output mode
   sqlfmt validate --diff file.sql            # Show what would change
+  sqlfmt validate --cache *.sql              # Skip files validated in an earlier run
+  sqlfmt validate --since=origin/main        # Validate SQL files changed on a branch
   cat file.sql | sqlfmt validate -            # Validate stdin`,
 	Args: cobra.ArbitraryArgs,
 	RunE: runValidate,
@@ -59,22 +51,11 @@ Examples:
 func init() {
 	rootCmd.AddCommand(validateCmd)
 
-	// Reuse format flags but exclude --write and --color as they don't make sense for validation
-	validateCmd.Flags().StringVar(&lang, "lang", "sql", "Dialect")
-	validateCmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	validateCmd.Flags().BoolVar(
-		&uppercase,
-		"uppercase",
-		false,
-		"Uppercase keywords (deprecated)",
-	)
-	validateCmd.Flags().StringVar(
-		&keywordCase,
-		"keyword-case",
-		"preserve",
-		"Keyword case",
-	)
-	validateCmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	// Share the format flags but exclude --write and --color as they don't make sense for validation
+	formatOpts.register(validateCmd.Flags())
+	cacheOpts.register(validateCmd.Flags())
+	gitOpts.register(validateCmd.Flags())
+	registerStdinFilepath(validateCmd.Flags())
 	validateCmd.Flags().StringVar(
 		&outputFormat,
 		"output",
@@ -94,33 +75,43 @@ func shouldValidateStdin(args []string) bool {
 }
 
 func runValidate(cmd *cobra.Command, args []string) error {
-	config := buildConfig(cmd)
+	config, err := buildConfig(cmd)
+	if err != nil {
+		return err
+	}
 	summary := &ValidationSummary{
 		Results: make([]ValidationResult, 0),
 	}
 
+	files, err := gitOpts.selectFiles(args)
+	if err != nil {
+		return err
+	}
+
 	// If no args or args is "-", validate stdin
-	if shouldValidateStdin(args) {
-		result := validateStdinWithResult(config)
-		summary.Results = append(summary.Results, result)
+	readStdin := !gitOpts.enabled() && shouldValidateStdin(args)
+	if err := checkStdinFilepath(readStdin); err != nil {
+		return err
+	}
+	if readStdin {
+		// Ignored input is not validated, like ignored files
+		if !stdinIgnored() {
+			summary.Add(validateStdinWithResult(cmd, config))
+		}
 	} else {
-		for _, filename := range args {
-			result := validateFileWithResult(filename, config)
-			summary.Results = append(summary.Results, result)
+		ignoreFile, err := sqlfmt.LoadIgnoreFile()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load ignore file: %v\n", err)
 		}
-	}
 
-	// Calculate summary statistics
-	for _, result := range summary.Results {
-		summary.TotalFiles++
-		switch {
-		case result.Error != "":
-			summary.ErrorFiles++
-		case result.Valid:
-			summary.ValidFiles++
-		default:
-			summary.InvalidFiles++
+		fileCache := cacheOpts.open()
+		for _, filename := range files {
+			if ignoreFile.ShouldIgnore(filename) {
+				continue
+			}
+			summary.Add(validateFileWithResult(cmd, filename, config, fileCache))
 		}
+		saveCache(fileCache)
 	}
 
 	// Output results based on format
@@ -130,41 +121,50 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		outputText(summary)
 	}
 
-	// Exit with appropriate code
-	if summary.InvalidFiles > 0 || summary.ErrorFiles > 0 {
-		os.Exit(1)
+	// Report the outcome through the exit code, mapped in Execute
+	if code := validationExitCode(summary); code != exitOK {
+		cmd.SilenceUsage = true
+		return &exitStatusError{code: code}
 	}
 
 	return nil
 }
 
-func validateStdinWithResult(config *sqlfmt.Config) ValidationResult {
+// validationExitCode maps a validation summary to the documented exit codes.
+func validationExitCode(summary *ValidationSummary) int {
+	switch {
+	case summary.ErrorFiles > 0:
+		return exitError
+	case summary.InvalidFiles > 0:
+		return exitUnformatted
+	default:
+		return exitOK
+	}
+}
+
+func validateStdinWithResult(cmd *cobra.Command, baseConfig *sqlfmt.Config) ValidationResult {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return ValidationResult{
-			File:  "stdin",
+			File:  stdinDisplayName("stdin"),
 			Valid: false,
 			Error: fmt.Sprintf("failed to read stdin: %v", err),
 		}
 	}
 
-	original := string(input)
-	formatted := sqlfmt.Format(original, config)
-
-	result := ValidationResult{
-		File:  "stdin",
-		Valid: strings.TrimSpace(original) == strings.TrimSpace(formatted),
-	}
-
-	if !result.Valid && showDiff {
-		result.Diff = generateDiff(original, formatted)
-	}
-
-	return result
+	config := inputConfig(cmd, baseConfig, stdinFilepath, string(input))
+	result, _ := sqlfmt.Check(string(input), config)
+	result.File = stdinDisplayName("stdin")
+	return withDiff(result)
 }
 
-func validateFileWithResult(filename string, config *sqlfmt.Config) ValidationResult {
-	content, err := os.ReadFile(filename)
+// validateFileWithResult checks a file. Files that fileCache knows to be
+// formatted are reported valid without formatting them, and valid files are
+// added to it.
+func validateFileWithResult(
+	cmd *cobra.Command, filename string, baseConfig *sqlfmt.Config, fileCache *cache.Cache,
+) ValidationResult {
+	content, err := readInput(filename)
 	if err != nil {
 		return ValidationResult{
 			File:  filename,
@@ -173,56 +173,30 @@ func validateFileWithResult(filename string, config *sqlfmt.Config) ValidationRe
 		}
 	}
 
-	original := string(content)
-	formatted := sqlfmt.Format(original, config)
+	config := inputConfig(cmd, baseConfig, filename, string(content))
+	key := cacheKey(cacheModeCheck, string(content), config)
+	if key != "" && fileCache.Has(key) {
+		return ValidationResult{File: filename, Valid: true, Original: string(content), Formatted: string(content)}
+	}
 
-	result := ValidationResult{
-		File:  filename,
-		Valid: strings.TrimSpace(original) == strings.TrimSpace(formatted),
+	result, _ := sqlfmt.Check(string(content), config)
+	result.File = filename
+	if result.Valid && key != "" {
+		fileCache.Add(key)
 	}
+	return withDiff(result)
+}
 
+// withDiff attaches a diff to results that need formatting when --diff is set.
+func withDiff(result ValidationResult) ValidationResult {
 	if !result.Valid && showDiff {
-		result.Diff = generateDiff(original, formatted)
+		result.Diff = generateDiff(result.Original, result.Formatted)
 	}
-
 	return result
 }
 
 func generateDiff(original, formatted string) string {
-	// Simple diff showing before/after
-	var diff strings.Builder
-	diff.WriteString("--- Before\n")
-	diff.WriteString("+++ After\n")
-
-	// Split into lines for better diff
-	origLines := strings.Split(original, "\n")
-	formLines := strings.Split(formatted, "\n")
-
-	maxLines := len(origLines)
-	if len(formLines) > maxLines {
-		maxLines = len(formLines)
-	}
-
-	for i := range maxLines {
-		var origLine, formLine string
-		if i < len(origLines) {
-			origLine = origLines[i]
-		}
-		if i < len(formLines) {
-			formLine = formLines[i]
-		}
-
-		if origLine != formLine {
-			if origLine != "" {
-				diff.WriteString(fmt.Sprintf("- %s\n", origLine))
-			}
-			if formLine != "" {
-				diff.WriteString(fmt.Sprintf("+ %s\n", formLine))
-			}
-		}
-	}
-
-	return diff.String()
+	return diff.Unified("Before", "After", original, formatted)
 }
 
 func outputJSON(summary *ValidationSummary) {
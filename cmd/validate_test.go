@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -49,10 +50,10 @@ func TestValidateCommand(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset global flags before each test
-			lang = validationSQLDialect
-			indent = "  "
-			uppercase = false
-			linesBetween = 2
+			formatOpts.lang = validationSQLDialect
+			formatOpts.indent = "  "
+			formatOpts.uppercase = false
+			formatOpts.linesBetween = 2
 
 			// Create a new command for each test to ensure isolation
 			cmd := &cobra.Command{
@@ -60,10 +61,10 @@ func TestValidateCommand(t *testing.T) {
 				Args: cobra.ArbitraryArgs,
 				RunE: runValidateTest,
 			}
-			cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-			cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-			cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-			cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+			cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+			cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+			cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+			cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 			// Capture stdout
 			oldStdout := os.Stdout
@@ -119,10 +120,10 @@ func TestValidateFile(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 
 	// Create validate command
 	cmd := &cobra.Command{
@@ -130,10 +131,10 @@ func TestValidateFile(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 
 	// Capture stdout
 	oldStdout := os.Stdout
@@ -156,45 +157,15 @@ func TestValidateFile(t *testing.T) {
 	assert.Contains(t, output, "needs formatting")
 }
 
-// runValidateTest is like runValidate but doesn't call os.Exit.
+// runValidateTest is like runValidate but treats the exit status as success,
+// so tests can inspect the output of failing validations.
 func runValidateTest(cmd *cobra.Command, args []string) error {
-	config := buildConfig(cmd)
-	summary := &ValidationSummary{
-		Results: make([]ValidationResult, 0),
+	err := runValidate(cmd, args)
+	var statusErr *exitStatusError
+	if errors.As(err, &statusErr) {
+		return nil
 	}
-
-	// If no args or args is "-", validate stdin
-	if shouldValidateStdin(args) {
-		result := validateStdinWithResult(config)
-		summary.Results = append(summary.Results, result)
-	} else {
-		for _, filename := range args {
-			result := validateFileWithResult(filename, config)
-			summary.Results = append(summary.Results, result)
-		}
-	}
-
-	// Calculate summary statistics
-	for _, result := range summary.Results {
-		summary.TotalFiles++
-		switch {
-		case result.Error != "":
-			summary.ErrorFiles++
-		case result.Valid:
-			summary.ValidFiles++
-		default:
-			summary.InvalidFiles++
-		}
-	}
-
-	// Output results based on format
-	if outputFormat == outputFormatJSON {
-		outputJSON(summary)
-	} else {
-		outputText(summary)
-	}
-
-	return nil
+	return err
 }
 
 func TestValidateJSONOutput(t *testing.T) {
@@ -209,10 +180,10 @@ func TestValidateJSONOutput(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = outputFormatJSON
 	showDiff = false
 
@@ -222,10 +193,10 @@ func TestValidateJSONOutput(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", outputFormatJSON, "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
@@ -270,10 +241,10 @@ func TestValidateDiffFlag(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = outputFormatText
 	showDiff = true
 
@@ -283,10 +254,10 @@ func TestValidateDiffFlag(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", outputFormatText, "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", true, "Show diff")
 
@@ -338,10 +309,10 @@ func TestValidateMultipleFiles(t *testing.T) {
 	_ = tmpFile2.Close()
 
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = outputFormatText
 	showDiff = false
 
@@ -351,10 +322,10 @@ func TestValidateMultipleFiles(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", outputFormatText, "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
@@ -387,10 +358,10 @@ func TestValidateMultipleFiles(t *testing.T) {
 
 func TestValidateFileWithError(t *testing.T) {
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = outputFormatText
 	showDiff = false
 
@@ -400,10 +371,10 @@ func TestValidateFileWithError(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", outputFormatText, "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
@@ -432,10 +403,10 @@ func TestValidateFileWithError(t *testing.T) {
 
 func TestValidateJSONOutputWithError(t *testing.T) {
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = outputFormatJSON
 	showDiff = false
 
@@ -445,10 +416,10 @@ func TestValidateJSONOutputWithError(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", outputFormatJSON, "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
@@ -491,10 +462,10 @@ func TestValidateMultipleFilesWithErrors(t *testing.T) {
 	_ = tmpFile.Close()
 
 	// Reset global flags
-	lang = validationSQLDialect
-	indent = "  "
-	uppercase = false
-	linesBetween = 2
+	formatOpts.lang = validationSQLDialect
+	formatOpts.indent = "  "
+	formatOpts.uppercase = false
+	formatOpts.linesBetween = 2
 	outputFormat = outputFormatText
 	showDiff = false
 
@@ -504,10 +475,10 @@ func TestValidateMultipleFilesWithErrors(t *testing.T) {
 		Args: cobra.ArbitraryArgs,
 		RunE: runValidateTest,
 	}
-	cmd.Flags().StringVar(&lang, "lang", validationSQLDialect, "SQL dialect")
-	cmd.Flags().StringVar(&indent, "indent", "  ", "Indentation string")
-	cmd.Flags().BoolVar(&uppercase, "uppercase", false, "Convert to uppercase")
-	cmd.Flags().IntVar(&linesBetween, "lines-between", 2, "Lines between queries")
+	cmd.Flags().StringVar(&formatOpts.lang, "lang", validationSQLDialect, "SQL dialect")
+	cmd.Flags().StringVar(&formatOpts.indent, "indent", "  ", "Indentation string")
+	cmd.Flags().BoolVar(&formatOpts.uppercase, "uppercase", false, "Convert to uppercase")
+	cmd.Flags().IntVar(&formatOpts.linesBetween, "lines-between", 2, "Lines between queries")
 	cmd.Flags().StringVar(&outputFormat, "output", outputFormatText, "Output format")
 	cmd.Flags().BoolVar(&showDiff, "diff", false, "Show diff")
 
@@ -537,3 +508,82 @@ func TestValidateMultipleFilesWithErrors(t *testing.T) {
 	assert.Contains(t, output, "properly formatted")
 	assert.Contains(t, output, "ERROR")
 }
+
+func TestValidateExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary ValidationSummary
+		want    int
+	}{
+		{name: "all valid", summary: ValidationSummary{ValidFiles: 2}, want: exitOK},
+		{name: "needs formatting", summary: ValidationSummary{ValidFiles: 1, InvalidFiles: 1}, want: exitUnformatted},
+		{name: "errors win", summary: ValidationSummary{InvalidFiles: 1, ErrorFiles: 1}, want: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validationExitCode(&tt.summary))
+		})
+	}
+}
+
+func TestRunValidateReturnsExitStatus(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test*.sql")
+	require.NoError(t, err)
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	_, err = tmpFile.WriteString(testSQL)
+	require.NoError(t, err)
+	_ = tmpFile.Close()
+
+	formatOpts.lang = validationSQLDialect
+	outputFormat = outputFormatText
+	showDiff = false
+
+	cmd := &cobra.Command{
+		Use:  "validate [files...]",
+		Args: cobra.ArbitraryArgs,
+		RunE: runValidate,
+	}
+	cmd.SilenceErrors = true
+
+	oldStdout := os.Stdout
+	_, w, _ := os.Pipe()
+	os.Stdout = w
+
+	cmd.SetArgs([]string{tmpFile.Name()})
+	err = cmd.Execute()
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+
+	var statusErr *exitStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, exitUnformatted, statusErr.code)
+}
+
+func TestValidateRespectsIgnoreFile(t *testing.T) {
+	chdirTemp(t, nil)
+	require.NoError(t, os.WriteFile(".sqlfmtignore", []byte("legacy/\n"), 0o644))
+	require.NoError(t, os.Mkdir("legacy", 0o755))
+	require.NoError(t, os.WriteFile("legacy/old.sql", []byte("select * from users"), 0o644))
+	require.NoError(t, os.WriteFile("new.sql", []byte("SELECT\n  *\nFROM\n  users"), 0o644))
+
+	formatOpts = formatOptions{}
+	outputFormat = outputFormatText
+	showDiff = false
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := runValidate(&cobra.Command{}, []string{"legacy/old.sql", "new.sql"})
+
+	_ = w.Close()
+	os.Stdout = oldStdout
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	assert.Equal(t, "new.sql: properly formatted\n", buf.String())
+}
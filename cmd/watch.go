@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/watch"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// watchDir is the directory whose SQL files --watch formats as they change.
+	watchDir string
+	// watchDebounce is how long a file must stay unchanged before it is formatted.
+	watchDebounce time.Duration
+)
+
+// watcher formats the SQL files of a directory in place as they change.
+type watcher struct {
+	cmd        *cobra.Command
+	baseConfig *sqlfmt.Config
+	ignoreFile *sqlfmt.IgnoreFile
+	// written holds the content hash of each file the watcher wrote, so that
+	// its own writes do not count as changes
+	written map[string][sha256.Size]byte
+}
+
+// runWatch formats the SQL files below watchDir whenever they change, until
+// the process is interrupted.
+func runWatch(cmd *cobra.Command, args []string, baseConfig *sqlfmt.Config, ignoreFile *sqlfmt.IgnoreFile) error {
+	switch {
+	case len(args) > 0 || gitOpts.enabled():
+		return fmt.Errorf("--watch cannot be combined with files or git selections")
+	case reportOnly() || color || sourceMapPath != "":
+		return fmt.Errorf("--watch cannot be combined with --list, --diff, --check, --color or --source-map")
+	}
+	if info, err := os.Stat(watchDir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("--watch requires a directory: %s", watchDir)
+	}
+
+	w := &watcher{
+		cmd:        cmd,
+		baseConfig: baseConfig,
+		ignoreFile: ignoreFile,
+		written:    map[string][sha256.Size]byte{},
+	}
+	poller, err := watch.New(watchDir, watch.Options{Debounce: watchDebounce, Skip: w.skip})
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+	fmt.Fprintf(os.Stderr, "Watching %s for changes, press Ctrl+C to stop\n", watchDir)
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return poller.Run(ctx, w.format)
+}
+
+// skip excludes hidden directories such as .git, files ignored by
+// .sqlfmtignore and files without a SQL extension.
+func (w *watcher) skip(path string, entry fs.DirEntry) bool {
+	if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") {
+		return true
+	}
+	if !entry.IsDir() && !isSQLFile(path) {
+		return true
+	}
+	return w.ignoreFile.ShouldIgnore(path)
+}
+
+// format formats a changed file in place. Failures are reported as warnings,
+// so that one broken file does not stop the watch.
+func (w *watcher) format(filename string) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read %s: %v\n", filename, err)
+		}
+		return
+	}
+
+	hash := sha256.Sum256(content)
+	if written, ok := w.written[filename]; ok && written == hash {
+		return
+	}
+	delete(w.written, filename)
+
+	contentStr := string(content)
+	if strings.TrimSpace(contentStr) == "" {
+		return
+	}
+
+	// The config is resolved for every change, so that edits to the config
+	// files of a directory apply to the next save
+	config := inputConfig(w.cmd, w.baseConfig, filename, contentStr)
+	formatted := sqlfmt.FormatDocument(contentStr, config)
+	changed, err := writeFormatted(filename, contentStr, formatted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write %s: %v\n", filename, err)
+		return
+	}
+	if changed {
+		w.written[filename] = sha256.Sum256([]byte(formatted))
+		fmt.Printf("Formatted %s\n", filepath.Clean(filename))
+	}
+}
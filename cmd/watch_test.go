@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWatcherFormat tests that the watcher formats changed files in place
+// with the config of their directory and ignores its own writes.
+func TestWatcherFormat(t *testing.T) {
+	chdirTemp(t, map[string]string{"upper/.sqlfmt.yaml": "keyword_case: uppercase\n"})
+
+	formatOpts = formatOptions{}
+	cmd := &cobra.Command{}
+	formatOpts.register(cmd.Flags())
+	baseConfig, err := buildConfig(cmd)
+	require.NoError(t, err)
+	ignoreFile, err := sqlfmt.LoadIgnoreFile()
+	require.NoError(t, err)
+	w := &watcher{cmd: cmd, baseConfig: baseConfig, ignoreFile: ignoreFile, written: map[string][sha256.Size]byte{}}
+
+	lower := "lower.sql"
+	upper := filepath.Join("upper", "q.sql")
+	require.NoError(t, os.WriteFile(lower, []byte("select id from users;\n"), 0o644))
+	require.NoError(t, os.WriteFile(upper, []byte("select id from users;\n"), 0o644))
+
+	oldStdout := os.Stdout
+	r, pw, _ := os.Pipe()
+	os.Stdout = pw
+
+	w.format(lower)
+	w.format(upper)
+	// The second change of upper is the write of the watcher
+	w.format(upper)
+
+	_ = pw.Close()
+	os.Stdout = oldStdout
+
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+	assert.Equal(t, "Formatted lower.sql\nFormatted upper/q.sql\n", filepath.ToSlash(buf.String()))
+
+	content, err := os.ReadFile(lower)
+	require.NoError(t, err)
+	assert.Equal(t, "select\n  id\nfrom\n  users;\n", string(content))
+	content, err = os.ReadFile(upper)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT\n  id\nFROM\n  users;\n", string(content))
+	assert.Contains(t, w.written, upper)
+}
+
+func TestWatcherSkip(t *testing.T) {
+	chdirTemp(t, map[string]string{
+		".sqlfmtignore":    "generated/\n",
+		"generated/a.sql":  "",
+		"queries/a.sql":    "",
+		"queries/notes.md": "",
+	})
+
+	ignoreFile, err := sqlfmt.LoadIgnoreFile()
+	require.NoError(t, err)
+	w := &watcher{ignoreFile: ignoreFile}
+
+	skipped := func(path string) bool {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		return w.skip(path, fs.FileInfoToDirEntry(info))
+	}
+	assert.True(t, skipped(".git"))
+	assert.True(t, skipped("generated"))
+	assert.True(t, skipped(filepath.Join("queries", "notes.md")))
+	assert.False(t, skipped("queries"))
+	assert.False(t, skipped(filepath.Join("queries", "a.sql")))
+}
+
+func TestFormatCommandWatchRejectsOtherModes(t *testing.T) {
+	watchDir = t.TempDir()
+	defer func() { watchDir = "" }()
+
+	err := runFormat(&cobra.Command{}, []string{"file.sql"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--watch cannot be combined with files")
+
+	checkOnly = true
+	defer func() { checkOnly = false }()
+	err = runFormat(&cobra.Command{}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--watch cannot be combined with --list")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// backupSuffix is appended to the name of the copy that --backup keeps of each
+// file before --write changes it. Empty disables backups.
+var backupSuffix string
+
+// writeFormatted replaces the content of filename with formatted and reports
+// whether it did. Unchanged files are not touched, so that their modification
+// times stay stable for build systems.
+func writeFormatted(filename, original, formatted string) (bool, error) {
+	if formatted == original {
+		return false, nil
+	}
+
+	// Replace the target of a symbolic link rather than the link itself
+	target, err := filepath.EvalSymlinks(filename)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return false, err
+	}
+
+	if backupSuffix != "" {
+		if err := writeFileAtomic(filename+backupSuffix, []byte(original), info); err != nil {
+			return false, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := writeFileAtomic(target, []byte(formatted), info); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of filename
+// and renames it over filename, so that a crash never leaves a truncated file
+// behind. The new file gets the mode and, where permitted, the owner of info.
+func writeFileAtomic(filename string, data []byte, info os.FileInfo) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	// Changing the owner clears the setuid and setgid bits, so it goes first
+	chown(tmp.Name(), info)
+	if err = os.Chmod(tmp.Name(), info.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// chown is a no-op on systems without Unix file ownership.
+func chown(string, os.FileInfo) {}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFormatted(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "query.sql")
+	require.NoError(t, os.WriteFile(filename, []byte("select 1"), 0o600))
+
+	written, err := writeFormatted(filename, "select 1", "SELECT\n  1\n")
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT\n  1\n", string(content))
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestWriteFormattedSkipsUnchangedFiles(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "query.sql")
+	require.NoError(t, os.WriteFile(filename, []byte("SELECT\n  1\n"), 0o644))
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(filename, modTime, modTime))
+
+	written, err := writeFormatted(filename, "SELECT\n  1\n", "SELECT\n  1\n")
+	require.NoError(t, err)
+	assert.False(t, written)
+
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(modTime))
+}
+
+func TestWriteFormattedBackup(t *testing.T) {
+	backupSuffix = ".orig"
+	defer func() { backupSuffix = "" }()
+
+	filename := filepath.Join(t.TempDir(), "query.sql")
+	require.NoError(t, os.WriteFile(filename, []byte("select 1"), 0o640))
+
+	_, err := writeFormatted(filename, "select 1", "SELECT\n  1")
+	require.NoError(t, err)
+
+	backup, err := os.ReadFile(filename + ".orig")
+	require.NoError(t, err)
+	assert.Equal(t, "select 1", string(backup))
+	info, err := os.Stat(filename + ".orig")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+}
+
+func TestWriteFormattedSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.sql")
+	link := filepath.Join(dir, "link.sql")
+	require.NoError(t, os.WriteFile(target, []byte("select 1"), 0o644))
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	_, err := writeFormatted(link, "select 1", "SELECT\n  1")
+	require.NoError(t, err)
+
+	// The link is kept and the target gets the new content
+	info, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT\n  1", string(content))
+}
+
+// TestWriteFormattedKeepsSpecialModeBits tests that the setgid and sticky bits
+// survive the atomic rewrite.
+func TestWriteFormattedKeepsSpecialModeBits(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "query.sql")
+	require.NoError(t, os.WriteFile(filename, []byte("select 1"), 0o640))
+	mode := os.FileMode(0o640) | os.ModeSetgid | os.ModeSticky
+	require.NoError(t, os.Chmod(filename, mode))
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	if info.Mode() != mode {
+		t.Skip("the file system does not keep special mode bits")
+	}
+
+	_, err = writeFormatted(filename, "select 1", "SELECT\n  1")
+	require.NoError(t, err)
+
+	info, err = os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, mode, info.Mode())
+}
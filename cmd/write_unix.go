@@ -0,0 +1,16 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown gives the file name the owner and group of info. Only privileged users
+// may give files away, so failures keep the current owner.
+func chown(name string, info os.FileInfo) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		_ = os.Lchown(name, int(stat.Uid), int(stat.Gid))
+	}
+}
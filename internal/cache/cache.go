@@ -0,0 +1,161 @@
+// Package cache remembers which inputs sqlfmt has found to be formatted, so
+// that repeated runs over large trees can skip them without formatting.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/version"
+)
+
+// maxAge is how long an entry is kept after it was last used.
+const maxAge = 30 * 24 * time.Hour
+
+// touchInterval limits how often the last use of an entry is updated, so that
+// runs that only hit the cache do not rewrite it.
+const touchInterval = 24 * time.Hour
+
+// now is replaced in tests.
+var now = time.Now
+
+// Cache is a set of keys of formatted inputs, stored as a JSON file. A nil
+// Cache is valid and remembers nothing. It is safe for concurrent use.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]int64 // key to the Unix time of its last use
+	dirty   bool
+}
+
+// file is the on-disk form of a Cache.
+type file struct {
+	Version string           `json:"version"`
+	Entries map[string]int64 `json:"entries"`
+}
+
+// DefaultPath returns the cache file in the user cache directory, which is
+// $XDG_CACHE_HOME/sqlfmt on Linux.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sqlfmt", "cache.json"), nil
+}
+
+// Open reads the cache file at path. A missing file, a file that cannot be
+// parsed and a file written by another sqlfmt version give an empty cache.
+func Open(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: map[string]int64{}}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var f file
+	if json.Unmarshal(content, &f) == nil && f.Version == version.Version && f.Entries != nil {
+		c.entries = f.Entries
+	}
+	return c, nil
+}
+
+// Key derives a cache key from the sqlfmt version and parts, which should
+// identify everything the formatting result depends on: typically the kind of
+// check, the resolved configuration and the input content.
+func Key(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range append([]string{version.Version}, parts...) {
+		// Length prefixes keep ("ab", "c") and ("a", "bc") apart
+		hash.Write([]byte(strconv.Itoa(len(part)) + ":" + part))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Has reports whether key was added before.
+func (c *Cache) Has(key string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	used, ok := c.entries[key]
+	if ok && now().Sub(time.Unix(used, 0)) > touchInterval {
+		c.entries[key] = now().Unix()
+		c.dirty = true
+	}
+	return ok
+}
+
+// Add records key.
+func (c *Cache) Add(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = now().Unix()
+	c.dirty = true
+}
+
+// Save writes the cache file if entries changed, dropping entries that have
+// not been used for a while. The file is replaced atomically, so concurrent
+// runs lose at most each other's additions.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	for key, used := range c.entries {
+		if now().Sub(time.Unix(used, 0)) > maxAge {
+			delete(c.entries, key)
+		}
+	}
+
+	content, err := json.Marshal(file{Version: version.Version, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sqlfmt", "cache.json")
+
+	c, err := Open(path)
+	require.NoError(t, err)
+	key := Key("write", "config", "SELECT\n  1\n")
+	assert.False(t, c.Has(key))
+
+	c.Add(key)
+	assert.True(t, c.Has(key))
+	require.NoError(t, c.Save())
+
+	c, err = Open(path)
+	require.NoError(t, err)
+	assert.True(t, c.Has(key))
+	assert.False(t, c.Has(Key("check", "config", "SELECT\n  1\n")))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("a", "b"), Key("a", "b"))
+	assert.NotEqual(t, Key("ab", "c"), Key("a", "bc"))
+	assert.NotEqual(t, Key("a"), Key("a", ""))
+}
+
+func TestOpenInvalidCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	for _, content := range []string{"not json", `{"version":"0.0.1","entries":{"k":1}}`} {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		c, err := Open(path)
+		require.NoError(t, err)
+		assert.False(t, c.Has("k"))
+	}
+}
+
+func TestSavePrunesOldEntries(t *testing.T) {
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+
+	path := filepath.Join(t.TempDir(), "cache.json")
+	c, err := Open(path)
+	require.NoError(t, err)
+	c.Add("old")
+	c.Add("used")
+
+	// Using an entry keeps it
+	now = func() time.Time { return start.Add(maxAge - time.Hour) }
+	assert.True(t, c.Has("used"))
+
+	now = func() time.Time { return start.Add(maxAge + time.Hour) }
+	c.Add("new")
+	require.NoError(t, c.Save())
+
+	c, err = Open(path)
+	require.NoError(t, err)
+	assert.False(t, c.Has("old"))
+	assert.True(t, c.Has("used"))
+	assert.True(t, c.Has("new"))
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Add("k")
+	assert.False(t, c.Has("k"))
+	assert.NoError(t, c.Save())
+}
@@ -0,0 +1,270 @@
+// Package daemon implements a lightweight alternative to the language
+// server for editor plugins: newline-delimited JSON requests and responses
+// over a pair of streams, usually standard input and output.
+//
+// Each request is a JSON object on one line, such as
+//
+//	{"id": 1, "method": "format", "params": {"query": "select 1", "path": "q.sql"}}
+//
+// and gets one response line with the same id and either a result or an
+// error:
+//
+//	{"id": 1, "result": {"formatted": "select\n  1", "language": "sql"}}
+//	{"id": 2, "error": {"code": -32601, "message": "unknown method \"lint\""}}
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+)
+
+// Error codes, the same as in JSON-RPC.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// maxRequestSize bounds the size of a single request line.
+const maxRequestSize = 64 << 20
+
+// configTTL is how long a resolved configuration is reused, so a burst of
+// requests for a file resolves its config files once while edits to them
+// still apply soon after.
+const configTTL = 5 * time.Second
+
+// ConfigFunc resolves the configuration for a file path, or for input that
+// is not a file if path is empty. It must return a new Config on every call.
+type ConfigFunc func(path string) *sqlfmt.Config
+
+// LanguageFunc chooses the language of content, e.g. from an inline dialect
+// hint. It reports false to keep the configured language.
+type LanguageFunc func(path, content string) (sqlfmt.Language, bool)
+
+// Server answers requests read from one stream on another, one at a time.
+type Server struct {
+	in       *bufio.Scanner
+	out      *json.Encoder
+	config   ConfigFunc
+	language LanguageFunc
+
+	configs map[string]cachedConfig
+	now     func() time.Time
+}
+
+type cachedConfig struct {
+	config   *sqlfmt.Config
+	resolved time.Time
+}
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result any             `json:"result,omitempty"`
+	Error  *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// formatParams are the parameters of the format method. The query is
+// formatted as the content of the file at path, which selects the config
+// files; language overrides the configured language. With cursor, a byte
+// offset in query, the result includes where the cursor belongs.
+type formatParams struct {
+	Query    string `json:"query"`
+	Path     string `json:"path,omitempty"`
+	Language string `json:"language,omitempty"`
+	Cursor   *int   `json:"cursor,omitempty"`
+}
+
+type formatResult struct {
+	Formatted string          `json:"formatted"`
+	Language  sqlfmt.Language `json:"language"`
+	Cursor    *int            `json:"cursor,omitempty"`
+}
+
+// detectParams are the parameters of the detect method.
+type detectParams struct {
+	Query string `json:"query"`
+	Path  string `json:"path,omitempty"`
+}
+
+type detectResult struct {
+	Language sqlfmt.Language `json:"language,omitempty"`
+	Detected bool            `json:"detected"`
+}
+
+// NewServer returns a server that reads requests from in and writes
+// responses to out. It resolves configurations with config and chooses
+// languages with language, which may be nil.
+func NewServer(in io.Reader, out io.Writer, config ConfigFunc, language LanguageFunc) *Server {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, maxRequestSize)
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+
+	if language == nil {
+		language = func(string, string) (sqlfmt.Language, bool) { return "", false }
+	}
+	return &Server{
+		in:       scanner,
+		out:      encoder,
+		config:   config,
+		language: language,
+		configs:  map[string]cachedConfig{},
+		now:      time.Now,
+	}
+}
+
+// Run answers requests until the input ends. It returns an error if a
+// request is too large or the streams fail.
+func (s *Server) Run() error {
+	for s.in.Scan() {
+		line := s.in.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		var resp response
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp.Error = &responseError{Code: codeParseError, Message: err.Error()}
+		} else {
+			resp.ID = req.ID
+			var result any
+			err := protect(req.Method, func() error {
+				var err error
+				result, err = s.handle(req)
+				return err
+			})
+			var rpcErr *responseError
+			switch {
+			case errors.As(err, &rpcErr):
+				resp.Error = rpcErr
+			case err != nil:
+				resp.Error = &responseError{Code: codeInternalError, Message: err.Error()}
+			default:
+				resp.Result = result
+			}
+		}
+		if len(resp.ID) == 0 {
+			resp.ID = json.RawMessage("null")
+		}
+
+		if err := s.out.Encode(resp); err != nil {
+			return err
+		}
+	}
+	if errors.Is(s.in.Err(), bufio.ErrTooLong) {
+		return fmt.Errorf("request exceeds %d bytes", maxRequestSize)
+	}
+	return s.in.Err()
+}
+
+// protect runs fn and turns a panic into an error, so that a request that
+// crashes the formatter does not end the session.
+func protect(method string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := make([]byte, 4096)
+			stack = stack[:runtime.Stack(stack, false)]
+			err = fmt.Errorf("%s failed: %v\n%s", method, r, stack)
+		}
+	}()
+	return fn()
+}
+
+func (s *Server) handle(req request) (any, error) {
+	switch req.Method {
+	case "format":
+		var params formatParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.format(params)
+	case "detect":
+		var params detectParams
+		if err := decodeParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		lang, detected := sqlfmt.DetectDialect(params.Path, params.Query)
+		if !detected {
+			lang = ""
+		}
+		return detectResult{Language: lang, Detected: detected}, nil
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+func (s *Server) format(params formatParams) (any, error) {
+	config := s.resolve(params.Path)
+	if params.Language != "" {
+		lang, err := sqlfmt.ParseLanguage(params.Language)
+		if err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		config.WithLang(lang)
+	} else if lang, found := s.language(params.Path, params.Query); found {
+		config.WithLang(lang)
+	}
+
+	result := formatResult{Language: config.Language}
+	if params.Cursor == nil {
+		result.Formatted = sqlfmt.FormatDocument(params.Query, config)
+		return result, nil
+	}
+
+	formatted, sourceMap := sqlfmt.FormatDocumentWithSourceMap(params.Query, config)
+	cursor := sourceMap.OutputOffset(*params.Cursor)
+	result.Formatted, result.Cursor = formatted, &cursor
+	return result, nil
+}
+
+// resolve returns the configuration for path, from the cache while it is
+// recent. Expired entries are dropped whenever one is added, so the cache
+// only holds the paths of the last configTTL.
+func (s *Server) resolve(path string) *sqlfmt.Config {
+	now := s.now()
+	cached, ok := s.configs[path]
+	if !ok || now.Sub(cached.resolved) >= configTTL {
+		for p, c := range s.configs {
+			if now.Sub(c.resolved) >= configTTL {
+				delete(s.configs, p)
+			}
+		}
+		cached = cachedConfig{config: s.config(path), resolved: now}
+		s.configs[path] = cached
+	}
+	return cached.config.Clone()
+}
+
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return &responseError{Code: codeInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
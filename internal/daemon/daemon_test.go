@@ -0,0 +1,166 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run sends the request lines to a server and returns its response lines.
+func run(t *testing.T, server func(in, out *bytes.Buffer) *Server, requests ...string) []map[string]any {
+	t.Helper()
+
+	in := bytes.NewBufferString(strings.Join(requests, "\n") + "\n")
+	out := &bytes.Buffer{}
+	require.NoError(t, server(in, out).Run())
+
+	var responses []map[string]any
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var resp map[string]any
+		require.NoError(t, decoder.Decode(&resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func defaultServer(in, out *bytes.Buffer) *Server {
+	return NewServer(in, out, func(string) *sqlfmt.Config { return sqlfmt.NewDefaultConfig() }, nil)
+}
+
+func TestFormat(t *testing.T) {
+	responses := run(t, defaultServer,
+		`{"id": 1, "method": "format", "params": {"query": "select a from t;\n"}}`,
+		`{"id": "two", "method": "format", "params": {"query": "select a <> 1", "language": "postgresql"}}`,
+		`{"id": 3, "method": "format", "params": {"query": "select abc from t", "cursor": 9}}`,
+	)
+	require.Len(t, responses, 3)
+
+	assert.Equal(t, map[string]any{
+		"id":     float64(1),
+		"result": map[string]any{"formatted": "select\n  a\nfrom\n  t;\n", "language": "sql"},
+	}, responses[0])
+	assert.Equal(t, map[string]any{
+		"id":     "two",
+		"result": map[string]any{"formatted": "select\n  a <> 1", "language": "postgresql"},
+	}, responses[1])
+	assert.Equal(t, map[string]any{
+		"id":     float64(3),
+		"result": map[string]any{"formatted": "select\n  abc\nfrom\n  t", "language": "sql", "cursor": float64(11)},
+	}, responses[2])
+}
+
+func TestConfigPerPath(t *testing.T) {
+	var resolved []string
+	server := func(in, out *bytes.Buffer) *Server {
+		return NewServer(in, out, func(path string) *sqlfmt.Config {
+			resolved = append(resolved, path)
+			config := sqlfmt.NewDefaultConfig()
+			if strings.HasSuffix(path, "upper.sql") {
+				config.WithKeywordCase(sqlfmt.KeywordCaseUppercase)
+			}
+			return config
+		}, func(path, content string) (sqlfmt.Language, bool) {
+			return sqlfmt.ParseInlineDialectHint(content)
+		})
+	}
+
+	responses := run(t, server,
+		`{"id": 1, "method": "format", "params": {"query": "select 1", "path": "upper.sql"}}`,
+		`{"id": 2, "method": "format", "params": {"query": "select 2", "path": "upper.sql"}}`,
+		`{"id": 3, "method": "format", "params": {"query": "-- sqlfmt: dialect=mysql\nselect 3", "path": "lower.sql"}}`,
+	)
+	require.Len(t, responses, 3)
+	assert.Equal(t, "SELECT\n  1", responses[0]["result"].(map[string]any)["formatted"])
+	assert.Equal(t, "SELECT\n  2", responses[1]["result"].(map[string]any)["formatted"])
+	assert.Equal(t, "mysql", responses[2]["result"].(map[string]any)["language"])
+
+	// The second request for upper.sql reuses the resolved config
+	assert.Equal(t, []string{"upper.sql", "lower.sql"}, resolved)
+}
+
+func TestConfigExpires(t *testing.T) {
+	calls := 0
+	s := NewServer(nil, nil, func(string) *sqlfmt.Config {
+		calls++
+		return sqlfmt.NewDefaultConfig()
+	}, nil)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	config := s.resolve("a.sql")
+	config.WithIndent("\t")
+	assert.Equal(t, "  ", s.resolve("a.sql").Indent, "callers get a copy")
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(configTTL)
+	s.resolve("a.sql")
+	assert.Equal(t, 2, calls)
+
+	// Expired entries of other paths are dropped
+	s.resolve("b.sql")
+	now = now.Add(configTTL)
+	s.resolve("c.sql")
+	assert.Len(t, s.configs, 1)
+	assert.Contains(t, s.configs, "c.sql")
+}
+
+func TestDetect(t *testing.T) {
+	responses := run(t, defaultServer,
+		`{"id": 1, "method": "detect", "params": {"query": "SELECT data->>'x' FROM t"}}`,
+		`{"id": 2, "method": "detect", "params": {"query": "SELECT 1"}}`,
+	)
+	require.Len(t, responses, 2)
+	assert.Equal(t, map[string]any{"language": "postgresql", "detected": true}, responses[0]["result"])
+	assert.Equal(t, map[string]any{"detected": false}, responses[1]["result"])
+}
+
+func TestErrors(t *testing.T) {
+	responses := run(t, func(in, out *bytes.Buffer) *Server {
+		return NewServer(in, out, func(path string) *sqlfmt.Config {
+			if path == "panic.sql" {
+				panic("boom")
+			}
+			return sqlfmt.NewDefaultConfig()
+		}, nil)
+	},
+		`not json`,
+		``,
+		`{"id": 1, "method": "lint", "params": {}}`,
+		`{"id": 2, "method": "format"}`,
+		`{"id": 3, "method": "format", "params": {"query": 1}}`,
+		`{"id": 4, "method": "format", "params": {"query": "select 1", "language": "cobol"}}`,
+		`{"id": 5, "method": "format", "params": {"query": "select 1", "path": "panic.sql"}}`,
+		`{"id": 6, "method": "format", "params": {"query": "select 1"}}`,
+	)
+	require.Len(t, responses, 7)
+
+	codes := make([]any, 0, len(responses))
+	for _, resp := range responses[:6] {
+		require.Contains(t, resp, "error")
+		codes = append(codes, resp["error"].(map[string]any)["code"])
+	}
+	assert.Equal(t, []any{
+		float64(codeParseError), float64(codeMethodNotFound), float64(codeInvalidParams),
+		float64(codeInvalidParams), float64(codeInvalidParams), float64(codeInternalError),
+	}, codes)
+	assert.Nil(t, responses[0]["id"])
+	assert.Contains(t, responses[5]["error"].(map[string]any)["message"], "format failed: boom")
+
+	// The session continues after errors
+	assert.Equal(t, float64(6), responses[6]["id"])
+	assert.Contains(t, responses[6], "result")
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	in := strings.NewReader(`{"id": 1, "method": "format", "params": {"query": "` +
+		strings.Repeat("x", maxRequestSize) + `"}}` + "\n")
+	err := NewServer(in, &bytes.Buffer{}, nil, nil).Run()
+	require.EqualError(t, err, "request exceeds 67108864 bytes")
+}
@@ -0,0 +1,203 @@
+// Package diff computes line-based differences between texts.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change.
+	diffContextLines = 3
+	// maxDiffCells bounds the size of the LCS table. Larger inputs fall back to
+	// reporting the differing middle section as a single replacement.
+	maxDiffCells = 4_000_000
+)
+
+// Op is a single line-level edit operation.
+type Op struct {
+	Kind byte // ' ', '-' or '+'
+	Line string
+}
+
+// Unified returns a unified diff between a and b, or an empty string if
+// they are equal.
+func Unified(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := Lines(SplitLines(a), SplitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	writeHunks(&out, ops)
+	return out.String()
+}
+
+// SplitLines splits s into lines, keeping a final line without newline.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines computes the edit script turning a into b.
+func Lines(a, b []string) []Op {
+	// Common prefix and suffix are cheap to strip and keep the LCS table small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, Op{' ', line})
+	}
+	ops = append(ops, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, Op{' ', line})
+	}
+	return ops
+}
+
+// diffMiddle diffs the differing middle sections using a longest common subsequence table.
+func diffMiddle(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+
+	if (len(a)+1)*(len(b)+1) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, Op{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, Op{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, Op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, Op{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, Op{'+', b[j]})
+	}
+	return ops
+}
+
+// writeHunks groups the edit script into hunks with surrounding context.
+func writeHunks(out *strings.Builder, ops []Op) {
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].Kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			return
+		}
+
+		// Extend the hunk while changes are separated by little enough context
+		end := start
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				break
+			}
+			end = next
+		}
+
+		hunkStart := max(0, start-diffContextLines)
+		hunkEnd := min(len(ops), end+diffContextLines)
+		writeHunk(out, ops, hunkStart, hunkEnd)
+		start = hunkEnd
+	}
+}
+
+func writeHunk(out *strings.Builder, ops []Op, start, end int) {
+	// Line numbers before the hunk
+	fromLine, toLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.Kind != '+' {
+			fromLine++
+		}
+		if op.Kind != '-' {
+			toLine++
+		}
+	}
+
+	fromCount, toCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.Kind != '+' {
+			fromCount++
+		}
+		if op.Kind != '-' {
+			toCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, op := range ops[start:end] {
+		out.WriteByte(op.Kind)
+		out.WriteString(op.Line)
+		if !strings.HasSuffix(op.Line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before the hunk
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
@@ -0,0 +1,69 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name:     "equal inputs",
+			a:        "SELECT\n  1\n",
+			b:        "SELECT\n  1\n",
+			expected: "",
+		},
+		{
+			name: "single line replaced",
+			a:    "SELECT * FROM users\n",
+			b:    "SELECT\n  *\nFROM\n  users\n",
+			expected: "--- a/q.sql\n+++ b/q.sql\n" +
+				"@@ -1 +1,4 @@\n" +
+				"-SELECT * FROM users\n" +
+				"+SELECT\n" +
+				"+  *\n" +
+				"+FROM\n" +
+				"+  users\n",
+		},
+		{
+			name: "context around change",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			b:    "a\nb\nc\nd\nE\nf\ng\nh\n",
+			expected: "--- a/q.sql\n+++ b/q.sql\n" +
+				"@@ -2,7 +2,7 @@\n" +
+				" b\n c\n d\n-e\n+E\n f\n g\n h\n",
+		},
+		{
+			name: "missing final newline",
+			a:    "SELECT 1",
+			b:    "SELECT\n  1\n",
+			expected: "--- a/q.sql\n+++ b/q.sql\n" +
+				"@@ -1 +1,2 @@\n" +
+				"-SELECT 1\n\\ No newline at end of file\n" +
+				"+SELECT\n" +
+				"+  1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Unified("a/q.sql", "b/q.sql", tt.a, tt.b))
+		})
+	}
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	b := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n"
+
+	expected := "--- a\n+++ b\n" +
+		"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+		"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n"
+	assert.Equal(t, expected, Unified("a", "b", a, b))
+}
@@ -0,0 +1,238 @@
+// Package git asks the local git binary which files of a repository changed
+// and reads and writes the contents of its index.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Repo is the working tree of a git repository.
+type Repo struct {
+	// Root is the absolute top-level directory of the working tree.
+	Root string
+}
+
+// Open finds the repository that contains dir.
+func Open(dir string) (*Repo, error) {
+	out, err := run(dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(strings.TrimSpace(string(out)))
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{Root: root}, nil
+}
+
+// ChangedFiles lists the files that differ from HEAD in the index or the
+// working tree, or from the merge base of since and HEAD if since is set, as
+// well as untracked files that are not ignored. Deleted files are left out.
+// Pathspecs, relative to the working directory, limit the files considered.
+func (r *Repo) ChangedFiles(since string, pathspecs ...string) ([]string, error) {
+	specs, err := r.pathspecs(pathspecs)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists [][]string
+	if since != "" {
+		out, err := r.run(nil, "merge-base", since, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSpace(string(out))
+		// Compares the working tree, which includes staged changes
+		list, err := r.names(append([]string{"diff", "--name-only", "-z", "--diff-filter=ACMR", base, "--"}, specs...))
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	} else {
+		for _, args := range [][]string{
+			{"diff", "--name-only", "-z", "--diff-filter=ACMR", "--"},
+			{"diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR", "--"},
+		} {
+			list, err := r.names(append(args, specs...))
+			if err != nil {
+				return nil, err
+			}
+			lists = append(lists, list)
+		}
+	}
+
+	untracked, err := r.names(append([]string{"ls-files", "--others", "--exclude-standard", "-z", "--"}, specs...))
+	if err != nil {
+		return nil, err
+	}
+	lists = append(lists, untracked)
+
+	// A file staged for deletion can still be listed as unstaged
+	var files []string
+	for _, file := range union(lists...) {
+		if _, err := os.Lstat(file); err == nil {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// StagedFiles lists the files whose staged content differs from HEAD,
+// leaving out deleted files.
+func (r *Repo) StagedFiles(pathspecs ...string) ([]string, error) {
+	specs, err := r.pathspecs(pathspecs)
+	if err != nil {
+		return nil, err
+	}
+	list, err := r.names(append([]string{"diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR", "--"}, specs...))
+	if err != nil {
+		return nil, err
+	}
+	return union(list), nil
+}
+
+// IndexContent returns the staged content of the file at path.
+func (r *Repo) IndexContent(path string) ([]byte, error) {
+	name, err := r.relative(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.run(nil, "cat-file", "blob", ":"+name)
+}
+
+// Stage replaces the staged content of the file at path with content, keeping
+// its file mode. The working tree is not changed.
+func (r *Repo) Stage(path string, content []byte) error {
+	name, err := r.relative(path)
+	if err != nil {
+		return err
+	}
+
+	out, err := r.run(nil, "ls-files", "--stage", "-z", "--", name)
+	if err != nil {
+		return err
+	}
+	mode, _, ok := strings.Cut(string(out), " ")
+	if !ok {
+		return fmt.Errorf("%s is not staged", name)
+	}
+
+	out, err = r.run(content, "hash-object", "-w", "--stdin", "--no-filters")
+	if err != nil {
+		return err
+	}
+	_, err = r.run(nil, "update-index", "--cacheinfo", mode+","+strings.TrimSpace(string(out))+","+name)
+	return err
+}
+
+// HooksDir returns the directory git runs hooks from, which respects
+// core.hooksPath and linked worktrees.
+func (r *Repo) HooksDir() (string, error) {
+	out, err := r.run(nil, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.Root, dir)
+	}
+	return dir, nil
+}
+
+// pathspecs makes pathspecs relative to the working directory relative to the
+// root of the repository, where git runs.
+func (r *Repo) pathspecs(pathspecs []string) ([]string, error) {
+	specs := make([]string, 0, len(pathspecs))
+	for _, spec := range pathspecs {
+		name, err := r.relative(spec)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, name)
+	}
+	return specs, nil
+}
+
+// relative converts path to a slash-separated path relative to the root.
+func (r *Repo) relative(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// The root has symbolic links resolved, so path needs them resolved too
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	} else if resolved, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(resolved, filepath.Base(abs))
+	}
+
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside repository at %s", path, r.Root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// names runs a git command that prints NUL-separated paths relative to the
+// root and returns them as absolute paths.
+func (r *Repo) names(args []string) ([]string, error) {
+	out, err := r.run(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			names = append(names, filepath.Join(r.Root, filepath.FromSlash(name)))
+		}
+	}
+	return names, nil
+}
+
+func (r *Repo) run(stdin []byte, args ...string) ([]byte, error) {
+	return run(r.Root, stdin, args...)
+}
+
+// run runs git in dir. Errors include what git wrote to standard error.
+func run(dir string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if message := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// union merges lists into one sorted list without duplicates.
+func union(lists ...[]string) []string {
+	seen := map[string]bool{}
+	var merged []string
+	for _, list := range lists {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				merged = append(merged, name)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
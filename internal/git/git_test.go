@@ -0,0 +1,139 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initRepo creates a repository with one commit of files in a temporary
+// directory.
+func initRepo(t *testing.T, files map[string]string) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	dir := t.TempDir()
+	_, err := run(dir, nil, "init", "-q")
+	require.NoError(t, err)
+	repo, err := Open(dir)
+	require.NoError(t, err)
+
+	writeFiles(t, repo.Root, files)
+	gitRun(t, repo, "add", "-A")
+	gitRun(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	return repo
+}
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func gitRun(t *testing.T, repo *Repo, args ...string) {
+	t.Helper()
+	_, err := repo.run(nil, args...)
+	require.NoError(t, err)
+}
+
+func TestChangedFiles(t *testing.T) {
+	repo := initRepo(t, map[string]string{
+		"a.sql":         "select 1",
+		"b.sql":         "select 2",
+		"c.sql":         "select 3",
+		"sub/d.sql":     "select 4",
+		".gitignore":    "ignored.sql\n",
+		"unchanged.sql": "select 7",
+	})
+	writeFiles(t, repo.Root, map[string]string{
+		"a.sql":       "select 10",
+		"b.sql":       "select 20",
+		"new.sql":     "select 5",
+		"ignored.sql": "select 6",
+		"sub/d.sql":   "select 40",
+	})
+	gitRun(t, repo, "add", "b.sql")
+	gitRun(t, repo, "rm", "-q", "c.sql")
+
+	files, err := repo.ChangedFiles("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(repo.Root, "a.sql"),
+		filepath.Join(repo.Root, "b.sql"),
+		filepath.Join(repo.Root, "new.sql"),
+		filepath.Join(repo.Root, "sub", "d.sql"),
+	}, files)
+
+	files, err = repo.ChangedFiles("", filepath.Join(repo.Root, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(repo.Root, "sub", "d.sql")}, files)
+
+	staged, err := repo.StagedFiles()
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(repo.Root, "b.sql")}, staged)
+}
+
+func TestChangedFilesSince(t *testing.T) {
+	repo := initRepo(t, map[string]string{"a.sql": "select 1", "b.sql": "select 2"})
+	gitRun(t, repo, "branch", "base")
+	writeFiles(t, repo.Root, map[string]string{"b.sql": "select 20"})
+	gitRun(t, repo, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "change b")
+	writeFiles(t, repo.Root, map[string]string{"c.sql": "select 3"})
+
+	files, err := repo.ChangedFiles("base")
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(repo.Root, "b.sql"), filepath.Join(repo.Root, "c.sql")}, files)
+
+	_, err = repo.ChangedFiles("no-such-ref")
+	require.ErrorContains(t, err, "git merge-base:")
+}
+
+func TestStage(t *testing.T) {
+	repo := initRepo(t, map[string]string{"a.sql": "select 1"})
+	path := filepath.Join(repo.Root, "a.sql")
+	writeFiles(t, repo.Root, map[string]string{"a.sql": "select 2"})
+	gitRun(t, repo, "add", "a.sql")
+	writeFiles(t, repo.Root, map[string]string{"a.sql": "select 3"})
+
+	content, err := repo.IndexContent(path)
+	require.NoError(t, err)
+	assert.Equal(t, "select 2", string(content))
+
+	require.NoError(t, repo.Stage(path, []byte("SELECT\n  2\n")))
+	content, err = repo.IndexContent(path)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT\n  2\n", string(content))
+
+	// The working tree keeps its unstaged changes
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "select 3", string(content))
+
+	require.Error(t, repo.Stage(filepath.Join(repo.Root, "missing.sql"), nil))
+	_, err = repo.relative(filepath.Dir(repo.Root))
+	require.ErrorContains(t, err, "outside repository")
+}
+
+func TestHooksDir(t *testing.T) {
+	repo := initRepo(t, map[string]string{"a.sql": "select 1"})
+
+	dir, err := repo.HooksDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo.Root, ".git", "hooks"), dir)
+
+	gitRun(t, repo, "config", "core.hooksPath", "tools/hooks")
+	dir, err = repo.HooksDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repo.Root, "tools", "hooks"), dir)
+}
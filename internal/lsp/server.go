@@ -0,0 +1,427 @@
+// Package lsp implements a Language Server Protocol server that formats SQL
+// documents and reports the parts of them that need formatting.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"runtime"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/diff"
+	"github.com/MeKo-Christian/go-sqlfmt/internal/version"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+)
+
+// diagnosticSource names the server in diagnostics.
+const diagnosticSource = "sqlfmt"
+
+// ConfigFunc resolves the configuration of a document. path is the file
+// path of the document, or empty for documents that are not files, and
+// content its current text. It must return a new Config on every call.
+type ConfigFunc func(path, content string) *sqlfmt.Config
+
+// Server is a Language Server Protocol server communicating over a pair of
+// streams, usually standard input and output. It handles one message at a
+// time.
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	config    ConfigFunc
+	documents map[string]*document
+
+	initialized bool
+	shutdown    bool
+}
+
+// document is an open text document.
+type document struct {
+	uri     string
+	path    string
+	version int
+	text    string
+}
+
+// NewServer returns a server that reads messages from in and writes messages
+// to out, resolving document configurations with config.
+func NewServer(in io.Reader, out io.Writer, config ConfigFunc) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		config:    config,
+		documents: map[string]*document{},
+	}
+}
+
+// Run serves messages until the client sends the exit notification or
+// closes the input. It returns an error if that happens before a shutdown
+// request, or if the streams fail.
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			if !s.shutdown {
+				return errors.New("connection closed before shutdown")
+			}
+			return nil
+		}
+		var rpcErr *responseError
+		if errors.As(err, &rpcErr) {
+			if err := s.reply(json.RawMessage("null"), nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches a request or notification. Only failures to write are
+// returned; errors of requests are sent to the client.
+func (s *Server) handle(msg *message) error {
+	if msg.ID == nil {
+		err := protect(msg.Method, func() error { return s.notify(msg) })
+		var panicErr *panicError
+		if errors.As(err, &panicErr) {
+			return s.send(&message{
+				Method: "window/logMessage",
+				Params: mustMarshal(logMessageParams{Type: messageTypeError, Message: err.Error()}),
+			})
+		}
+		return err
+	}
+
+	var result any
+	err := protect(msg.Method, func() error {
+		var err error
+		result, err = s.call(msg)
+		return err
+	})
+	var rpcErr *responseError
+	if err != nil && !errors.As(err, &rpcErr) {
+		rpcErr = &responseError{Code: codeInternalError, Message: err.Error()}
+	}
+	return s.reply(msg.ID, result, rpcErr)
+}
+
+// call runs a request and returns its result.
+func (s *Server) call(msg *message) (any, error) {
+	switch {
+	case msg.Method == "initialize":
+		s.initialized = true
+		return s.initializeResult(), nil
+	case !s.initialized:
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server not initialized"}
+	case s.shutdown:
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/formatting":
+		var params formattingParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.formatting(params)
+	case "textDocument/rangeFormatting":
+		var params rangeFormattingParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.rangeFormatting(params)
+	case "textDocument/onTypeFormatting":
+		var params onTypeFormattingParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.onTypeFormatting(params)
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// notify handles a notification. Unknown notifications are ignored, as the
+// protocol requires.
+func (s *Server) notify(msg *message) error {
+	if !s.initialized || s.shutdown {
+		return nil
+	}
+
+	switch msg.Method {
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if decodeParams(msg, &params) != nil {
+			return nil
+		}
+		doc := &document{
+			uri:     params.TextDocument.URI,
+			path:    uriToPath(params.TextDocument.URI),
+			version: params.TextDocument.Version,
+			text:    params.TextDocument.Text,
+		}
+		s.documents[doc.uri] = doc
+		return s.publishDiagnostics(doc)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if decodeParams(msg, &params) != nil {
+			return nil
+		}
+		doc, ok := s.documents[params.TextDocument.URI]
+		if !ok {
+			return nil
+		}
+		for _, change := range params.ContentChanges {
+			if change.Range == nil {
+				doc.text = change.Text
+				continue
+			}
+			start, end := offsetAt(doc.text, change.Range.Start), offsetAt(doc.text, change.Range.End)
+			doc.text = doc.text[:start] + change.Text + doc.text[max(start, end):]
+		}
+		doc.version = params.TextDocument.Version
+		return s.publishDiagnostics(doc)
+	case "textDocument/didClose":
+		var params didCloseParams
+		if decodeParams(msg, &params) != nil {
+			return nil
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return s.send(&message{
+			Method: "textDocument/publishDiagnostics",
+			Params: mustMarshal(publishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}}),
+		})
+	}
+	return nil
+}
+
+func (s *Server) initializeResult() initializeResult {
+	return initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync: textDocumentSyncOptions{
+				OpenClose: true,
+				Change:    textDocumentSyncIncremental,
+			},
+			DocumentFormattingProvider:       true,
+			DocumentRangeFormattingProvider:  true,
+			DocumentOnTypeFormattingProvider: documentOnTypeFormattingOptions{FirstTriggerCharacter: ";"},
+		},
+		ServerInfo: serverInfo{Name: "sqlfmt", Version: version.Version},
+	}
+}
+
+// formatting formats a whole document. The formatting options of the client
+// are ignored; config files decide how a document is formatted.
+func (s *Server) formatting(params formattingParams) ([]TextEdit, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return replaceEdits(doc.text, sqlfmt.FormatDocument(doc.text, s.config(doc.path, doc.text))), nil
+}
+
+// rangeFormatting formats the statements or the parenthesized block that
+// the range is in.
+func (s *Server) rangeFormatting(params rangeFormattingParams) ([]TextEdit, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	start, end := offsetAt(doc.text, params.Range.Start), offsetAt(doc.text, params.Range.End)
+	return s.formatRange(doc, start, end), nil
+}
+
+// onTypeFormatting formats the statement that a typed semicolon ends.
+func (s *Server) onTypeFormatting(params onTypeFormattingParams) ([]TextEdit, error) {
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := offsetAt(doc.text, params.Position)
+	if params.Ch != ";" || offset == 0 || doc.text[offset-1] != ';' {
+		return []TextEdit{}, nil
+	}
+
+	// Semicolons typed in strings or comments do not end statements
+	for _, tok := range sqlfmt.Tokenize(doc.text, s.config(doc.path, doc.text)) {
+		if tok.Offset == offset-1 && tok.Value == ";" {
+			return s.formatRange(doc, offset, offset), nil
+		}
+	}
+	return []TextEdit{}, nil
+}
+
+// formatRange formats the region of a document that sqlfmt.FormatRange
+// expands the byte range [start, end) to.
+func (s *Server) formatRange(doc *document, start, end int) []TextEdit {
+	newText, editStart, editEnd := sqlfmt.FormatRange(doc.text, start, end, s.config(doc.path, doc.text))
+	return replaceEdits(doc.text, doc.text[:editStart]+newText+doc.text[editEnd:])
+}
+
+// publishDiagnostics reports the tokenizer problems of a document and the
+// lines that formatting would change.
+func (s *Server) publishDiagnostics(doc *document) error {
+	config := s.config(doc.path, doc.text)
+	diagnostics := []Diagnostic{}
+
+	for _, d := range sqlfmt.Diagnose(doc.text, config) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    rangeOf(doc.text, d.Start, d.End),
+			Severity: severityError,
+			Source:   diagnosticSource,
+			Message:  d.Message,
+		})
+	}
+
+	formatted := sqlfmt.FormatDocument(doc.text, config)
+	for _, r := range changedLines(doc.text, formatted) {
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    rangeOf(doc.text, r.start, r.end),
+			Severity: severityInformation,
+			Source:   diagnosticSource,
+			Message:  "needs formatting",
+		})
+	}
+
+	version := doc.version
+	return s.send(&message{
+		Method: "textDocument/publishDiagnostics",
+		Params: mustMarshal(publishDiagnosticsParams{URI: doc.uri, Version: &version, Diagnostics: diagnostics}),
+	})
+}
+
+// changedLines returns the byte ranges of the lines of text that differ in
+// formatted. Lines that formatting only inserts yield an empty range at the
+// start of the following line.
+func changedLines(text, formatted string) []span {
+	if text == formatted {
+		return nil
+	}
+
+	var (
+		spans   []span
+		current = span{start: -1}
+		offset  int
+	)
+	for _, op := range diff.Lines(diff.SplitLines(text), diff.SplitLines(formatted)) {
+		switch op.Kind {
+		case ' ':
+			if current.start >= 0 {
+				spans = append(spans, current)
+				current = span{start: -1}
+			}
+			offset += len(op.Line)
+		case '-':
+			if current.start < 0 {
+				current.start = offset
+			}
+			offset += len(op.Line)
+			current.end = offset
+		case '+':
+			if current.start < 0 {
+				current = span{start: offset, end: offset}
+			}
+		}
+	}
+	if current.start >= 0 {
+		spans = append(spans, current)
+	}
+	return spans
+}
+
+// panicError is a panic while handling a message. A formatter bug must not
+// take down the server of an editor.
+type panicError struct {
+	method string
+	value  any
+	stack  []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%s failed: %v\n%s", e.method, e.value, e.stack)
+}
+
+// protect runs fn and turns a panic into a *panicError.
+func protect(method string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := make([]byte, 4096)
+			stack = stack[:runtime.Stack(stack, false)]
+			err = &panicError{method: method, value: r, stack: stack}
+		}
+	}()
+	return fn()
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "unknown document " + uri}
+	}
+	return doc, nil
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *responseError) error {
+	if rpcErr != nil {
+		return s.send(&message{ID: id, Error: rpcErr})
+	}
+	return s.send(&message{ID: id, Result: mustMarshal(result)})
+}
+
+func (s *Server) send(msg *message) error {
+	if err := writeMessage(s.out, msg); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	return nil
+}
+
+func decodeParams(msg *message, params any) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// mustMarshal encodes values of the protocol types, which cannot fail.
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// uriToPath returns the file path of a file URI, or an empty path for other
+// URIs such as those of unsaved buffers.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+
+	path := u.Path
+	// file:///C:/dir has the path /C:/dir
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,308 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClient talks to a server running in the background.
+type testClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+// startServer runs a server with config resolving every document and
+// returns a client connected to it.
+func startServer(t *testing.T, config ConfigFunc) *testClient {
+	t.Helper()
+	clientToServer, serverIn := io.Pipe()
+	serverOut, serverToClient := io.Pipe()
+
+	c := &testClient{t: t, in: serverIn, out: bufio.NewReader(serverOut), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(clientToServer, serverToClient, config).Run()
+		_ = serverToClient.Close()
+		c.done <- err
+	}()
+	t.Cleanup(func() { _ = serverIn.Close() })
+	return c
+}
+
+// startInitializedServer starts a server formatting every document with the
+// default config and initializes it.
+func startInitializedServer(t *testing.T) *testClient {
+	t.Helper()
+	c := startServer(t, func(path, content string) *sqlfmt.Config { return sqlfmt.NewDefaultConfig() })
+	c.call("initialize", map[string]any{"capabilities": map[string]any{}}, nil)
+	c.notify("initialized", map[string]any{})
+	return c
+}
+
+func (c *testClient) send(msg *message) {
+	c.t.Helper()
+	require.NoError(c.t, writeMessage(c.in, msg))
+}
+
+func (c *testClient) notify(method string, params any) {
+	c.t.Helper()
+	c.send(&message{Method: method, Params: mustMarshal(params)})
+}
+
+// call sends a request, decodes its result into result and returns the
+// error of the response. Notifications sent before the response are skipped.
+func (c *testClient) call(method string, params any, result any) *responseError {
+	c.t.Helper()
+	c.nextID++
+	id := mustMarshal(c.nextID)
+	c.send(&message{ID: id, Method: method, Params: mustMarshal(params)})
+
+	for {
+		msg := c.receive()
+		if string(msg.ID) != string(id) {
+			continue
+		}
+		if msg.Error == nil && result != nil {
+			require.NoError(c.t, json.Unmarshal(msg.Result, result))
+		}
+		return msg.Error
+	}
+}
+
+func (c *testClient) receive() *message {
+	c.t.Helper()
+	msg, err := readMessage(c.out)
+	require.NoError(c.t, err)
+	return msg
+}
+
+// diagnostics waits for the next diagnostics published for uri.
+func (c *testClient) diagnostics(uri string) []Diagnostic {
+	c.t.Helper()
+	for {
+		msg := c.receive()
+		if msg.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params publishDiagnosticsParams
+		require.NoError(c.t, json.Unmarshal(msg.Params, &params))
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func (c *testClient) open(uri, text string) []Diagnostic {
+	c.t.Helper()
+	c.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "sql", "version": 1, "text": text},
+	})
+	return c.diagnostics(uri)
+}
+
+func (c *testClient) exit() error {
+	c.t.Helper()
+	require.Nil(c.t, c.call("shutdown", nil, nil))
+	c.notify("exit", nil)
+	return <-c.done
+}
+
+// applyEdits applies non-overlapping edits to text.
+func applyEdits(text string, edits []TextEdit) string {
+	for i := len(edits) - 1; i >= 0; i-- {
+		start, end := offsetAt(text, edits[i].Range.Start), offsetAt(text, edits[i].Range.End)
+		text = text[:start] + edits[i].NewText + text[end:]
+	}
+	return text
+}
+
+func TestServerLifecycle(t *testing.T) {
+	c := startServer(t, func(path, content string) *sqlfmt.Config { return sqlfmt.NewDefaultConfig() })
+
+	err := c.call("textDocument/formatting", formattingParams{}, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, codeServerNotInitialized, err.Code)
+
+	var result initializeResult
+	require.Nil(t, c.call("initialize", map[string]any{}, &result))
+	assert.Equal(t, "sqlfmt", result.ServerInfo.Name)
+	assert.True(t, result.Capabilities.DocumentFormattingProvider)
+	assert.Equal(t, ";", result.Capabilities.DocumentOnTypeFormattingProvider.FirstTriggerCharacter)
+
+	err = c.call("textDocument/hover", map[string]any{}, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, codeMethodNotFound, err.Code)
+
+	err = c.call("textDocument/formatting", formattingParams{TextDocument: textDocumentIdentifier{URI: "file:///x.sql"}}, nil)
+	require.NotNil(t, err)
+	assert.Equal(t, codeInvalidParams, err.Code)
+
+	require.NoError(t, c.exit())
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := startInitializedServer(t)
+	c.notify("exit", nil)
+	assert.EqualError(t, <-c.done, "exit before shutdown")
+}
+
+func TestServerFormatting(t *testing.T) {
+	c := startInitializedServer(t)
+	uri := "file:///project/query.sql"
+	text := "-- report\nselect a, b from t where x = 1;\n"
+	c.open(uri, text)
+
+	var edits []TextEdit
+	require.Nil(t, c.call("textDocument/formatting", formattingParams{TextDocument: textDocumentIdentifier{URI: uri}}, &edits))
+	assert.Equal(t, sqlfmt.FormatDocument(text), applyEdits(text, edits))
+	// The unchanged comment is not part of the edit
+	require.Len(t, edits, 1)
+	assert.Equal(t, 1, edits[0].Range.Start.Line)
+
+	// Formatted documents need no edits
+	formatted := applyEdits(text, edits)
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{"text": formatted}},
+	})
+	assert.Empty(t, c.diagnostics(uri))
+	require.Nil(t, c.call("textDocument/formatting", formattingParams{TextDocument: textDocumentIdentifier{URI: uri}}, &edits))
+	assert.Empty(t, edits)
+
+	require.NoError(t, c.exit())
+}
+
+func TestServerRangeFormatting(t *testing.T) {
+	c := startInitializedServer(t)
+	uri := "untitled:Untitled-1"
+	text := "select 1;\n\nselect a from t where x = 1;\nselect  2;\n"
+	c.open(uri, text)
+
+	// A selection inside the second statement formats that statement only
+	var edits []TextEdit
+	params := rangeFormattingParams{
+		TextDocument: textDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 2, Character: 9}, End: Position{Line: 2, Character: 12}},
+	}
+	require.Nil(t, c.call("textDocument/rangeFormatting", params, &edits))
+	expected := "select 1;\n\nselect\n  a\nfrom\n  t\nwhere\n  x = 1;\nselect  2;\n"
+	assert.Equal(t, expected, applyEdits(text, edits))
+
+	require.NoError(t, c.exit())
+}
+
+func TestServerOnTypeFormatting(t *testing.T) {
+	c := startInitializedServer(t)
+	uri := "file:///query.sql"
+	text := "select  1;\nselect a from t;\nselect ';'"
+	c.open(uri, text)
+
+	onType := func(line, character int) []TextEdit {
+		var edits []TextEdit
+		params := onTypeFormattingParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: character},
+			Ch:           ";",
+		}
+		require.Nil(t, c.call("textDocument/onTypeFormatting", params, &edits))
+		return edits
+	}
+
+	assert.Equal(t, "select  1;\nselect\n  a\nfrom\n  t;\nselect ';'", applyEdits(text, onType(1, 16)))
+	// Semicolons in strings do not end statements
+	assert.Empty(t, onType(2, 9))
+
+	require.NoError(t, c.exit())
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	c := startInitializedServer(t)
+	uri := "file:///query.sql"
+	diagnostics := c.open(uri, "SELECT\n  1;\n\nselect  (a from t;\n")
+
+	require.Len(t, diagnostics, 2)
+	assert.Equal(t, Diagnostic{
+		Range:    Range{Start: Position{Line: 3, Character: 8}, End: Position{Line: 3, Character: 9}},
+		Severity: severityError,
+		Source:   "sqlfmt",
+		Message:  "unclosed parenthesis",
+	}, diagnostics[0])
+	assert.Equal(t, Diagnostic{
+		Range:    Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 4, Character: 0}},
+		Severity: severityInformation,
+		Source:   "sqlfmt",
+		Message:  "needs formatting",
+	}, diagnostics[1])
+
+	// Incremental changes are applied to the document
+	c.notify("textDocument/didChange", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{
+			"range": Range{Start: Position{Line: 2, Character: 0}, End: Position{Line: 4, Character: 0}},
+			"text":  "",
+		}},
+	})
+	assert.Empty(t, c.diagnostics(uri))
+
+	c.notify("textDocument/didClose", map[string]any{"textDocument": map[string]any{"uri": uri}})
+	assert.Empty(t, c.diagnostics(uri))
+
+	require.NoError(t, c.exit())
+}
+
+func TestServerResolvesConfigPerDocument(t *testing.T) {
+	var paths []string
+	c := startServer(t, func(path, content string) *sqlfmt.Config {
+		paths = append(paths, path)
+		return sqlfmt.NewDefaultConfig().WithKeywordCase(sqlfmt.KeywordCaseUppercase)
+	})
+	c.call("initialize", map[string]any{}, nil)
+
+	uri := "file:///project/dir%20name/query.sql"
+	c.open(uri, "select 1")
+	var edits []TextEdit
+	require.Nil(t, c.call("textDocument/formatting", formattingParams{TextDocument: textDocumentIdentifier{URI: uri}}, &edits))
+	assert.Equal(t, "SELECT\n  1", applyEdits("select 1", edits))
+
+	require.NoError(t, c.exit())
+	assert.Contains(t, paths, filepath.FromSlash("/project/dir name/query.sql"))
+}
+
+func TestPositions(t *testing.T) {
+	text := "a\r\nb😀c\nd"
+	tests := []struct {
+		offset int
+		pos    Position
+	}{
+		{0, Position{0, 0}},
+		{1, Position{0, 1}},
+		{3, Position{1, 0}},
+		{4, Position{1, 1}},
+		{8, Position{1, 3}},
+		{10, Position{2, 0}},
+		{11, Position{2, 1}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.pos, positionAt(text, tt.offset), "offset %d", tt.offset)
+		assert.Equal(t, tt.offset, offsetAt(text, tt.pos), "position %v", tt.pos)
+	}
+
+	// Positions past the end of a line or the text are clamped
+	assert.Equal(t, 1, offsetAt(text, Position{0, 10}))
+	assert.Equal(t, len(text), offsetAt(text, Position{5, 0}))
+}
+
+func TestUriToPath(t *testing.T) {
+	assert.Equal(t, filepath.FromSlash("/a/b c.sql"), uriToPath("file:///a/b%20c.sql"))
+	assert.Equal(t, filepath.FromSlash("C:/a.sql"), uriToPath("file:///C:/a.sql"))
+	assert.Equal(t, "", uriToPath("untitled:Untitled-1"))
+}
@@ -0,0 +1,123 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// offsetAt converts a position to a byte offset in text. Positions past the
+// end of a line or of the text are clamped, as the protocol requires.
+func offsetAt(text string, pos Position) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		next := lineBreakEnd(text, offset)
+		if next < 0 {
+			return len(text)
+		}
+		offset = next
+	}
+
+	for units := 0; offset < len(text) && units < pos.Character; {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if r == '\n' || r == '\r' {
+			break
+		}
+		units += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+// positionAt converts a byte offset in text to a position.
+func positionAt(text string, offset int) Position {
+	offset = min(max(offset, 0), len(text))
+
+	var pos Position
+	lineStart := 0
+	for {
+		next := lineBreakEnd(text, lineStart)
+		if next < 0 || next > offset {
+			break
+		}
+		pos.Line++
+		lineStart = next
+	}
+	for _, r := range text[lineStart:offset] {
+		pos.Character += utf16Len(r)
+	}
+	return pos
+}
+
+// lineBreakEnd returns the offset after the first line break at or after
+// offset, or -1 if there is none. "\n", "\r\n" and "\r" all end lines.
+func lineBreakEnd(text string, offset int) int {
+	i := strings.IndexAny(text[offset:], "\r\n")
+	if i < 0 {
+		return -1
+	}
+	i += offset
+	if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+		return i + 2
+	}
+	return i + 1
+}
+
+// utf16Len returns the number of UTF-16 code units encoding r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// rangeOf converts byte offsets in text to a range.
+func rangeOf(text string, start, end int) Range {
+	return Range{Start: positionAt(text, start), End: positionAt(text, end)}
+}
+
+// replaceEdits returns the edits turning text into newText. Only the part
+// between their common prefix and suffix is replaced, which keeps the cursor
+// and marks of editors in place outside of it.
+func replaceEdits(text, newText string) []TextEdit {
+	edits := []TextEdit{}
+	if text == newText {
+		return edits
+	}
+
+	prefix := 0
+	for prefix < len(text) && prefix < len(newText) && text[prefix] == newText[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(text)-prefix && suffix < len(newText)-prefix &&
+		text[len(text)-1-suffix] == newText[len(newText)-1-suffix] {
+		suffix++
+	}
+
+	// Do not split UTF-8 sequences or CRLF line breaks
+	for prefix > 0 && (splitsText(text, prefix) || splitsText(newText, prefix)) {
+		prefix--
+	}
+	for suffix > 0 && (splitsText(text, len(text)-suffix) || splitsText(newText, len(newText)-suffix)) {
+		suffix--
+	}
+
+	return append(edits, TextEdit{
+		Range:   rangeOf(text, prefix, len(text)-suffix),
+		NewText: newText[prefix : len(newText)-suffix],
+	})
+}
+
+// splitsText reports whether offset i is inside a UTF-8 sequence or a CRLF
+// line break of s.
+func splitsText(s string, i int) bool {
+	if i <= 0 || i >= len(s) {
+		return false
+	}
+	return !utf8.RuneStart(s[i]) || (s[i-1] == '\r' && s[i] == '\n')
+}
+
+// span is a range of byte offsets.
+type span struct {
+	start, end int
+}
@@ -0,0 +1,298 @@
+// Package server implements an HTTP API that formats SQL, for tools that
+// would otherwise start sqlfmt once per request.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/internal/version"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+)
+
+// Defaults for the limits of Options.
+const (
+	DefaultMaxRequestSize = 1 << 20
+	DefaultTimeout        = 10 * time.Second
+)
+
+// shutdownTimeout bounds how long Serve waits for running requests when it
+// stops.
+const shutdownTimeout = 5 * time.Second
+
+// ConfigFunc resolves the configuration of a query before the language and
+// options of the request apply. It must return a new Config on every call.
+type ConfigFunc func(query string) *sqlfmt.Config
+
+// Dialect describes a supported SQL dialect.
+type Dialect struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases"`
+}
+
+// Options configure the API.
+type Options struct {
+	// Config resolves the base configuration of each query.
+	Config ConfigFunc
+	// Dialects are listed by GET /dialects.
+	Dialects []Dialect
+	// MaxRequestSize limits the size of request bodies in bytes.
+	// Zero means DefaultMaxRequestSize.
+	MaxRequestSize int64
+	// Timeout limits the time to handle a request. Zero means DefaultTimeout.
+	Timeout time.Duration
+}
+
+type handler struct {
+	Options
+}
+
+// formatRequest is the body of POST /format. Options holds config file
+// settings such as keyword_case, in JSON.
+type formatRequest struct {
+	Query    string          `json:"query"`
+	Language string          `json:"language,omitempty"`
+	Options  json.RawMessage `json:"options,omitempty"`
+}
+
+// formatResponse is the result of POST /format. Tokens are those of the
+// formatted query; diagnostics refer to the query of the request.
+type formatResponse struct {
+	Formatted   string              `json:"formatted"`
+	Language    sqlfmt.Language     `json:"language"`
+	Tokens      []sqlfmt.Token      `json:"tokens"`
+	Diagnostics []sqlfmt.Diagnostic `json:"diagnostics"`
+}
+
+// detectRequest is the body of POST /detect. Filename is optional and only
+// its extension is used.
+type detectRequest struct {
+	Query    string `json:"query"`
+	Filename string `json:"filename,omitempty"`
+}
+
+type detectResponse struct {
+	Language sqlfmt.Language `json:"language,omitempty"`
+	Detected bool            `json:"detected"`
+}
+
+type healthResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler returns the handler of the API:
+//
+//	GET  /health    reports that the server is up
+//	GET  /dialects  lists the supported dialects
+//	POST /format    formats a query
+//	POST /detect    detects the dialect of a query
+func NewHandler(opts Options) http.Handler {
+	if opts.MaxRequestSize <= 0 {
+		opts.MaxRequestSize = DefaultMaxRequestSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Config == nil {
+		opts.Config = func(string) *sqlfmt.Config { return sqlfmt.NewDefaultConfig() }
+	}
+	h := &handler{Options: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", h.health)
+	mux.HandleFunc("GET /dialects", h.dialects)
+	mux.HandleFunc("POST /format", h.format)
+	mux.HandleFunc("POST /detect", h.detect)
+
+	timeoutBody, _ := json.Marshal(errorResponse{Error: "request timed out"})
+	timeout := http.TimeoutHandler(recoverPanics(mux), opts.Timeout, string(timeoutBody))
+
+	// TimeoutHandler writes its body without a content type
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		timeout.ServeHTTP(w, r)
+	})
+}
+
+// Serve serves the API on listener until ctx is done, and then waits for
+// running requests to finish.
+func Serve(ctx context.Context, listener net.Listener, opts Options) error {
+	handler := NewHandler(opts)
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: timeout,
+		ReadTimeout:       timeout,
+		WriteTimeout:      timeout + time.Second,
+		IdleTimeout:       time.Minute,
+		MaxHeaderBytes:    1 << 16,
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(listener) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (h *handler) health(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok", Version: version.Version})
+}
+
+func (h *handler) dialects(w http.ResponseWriter, _ *http.Request) {
+	dialects := h.Dialects
+	if dialects == nil {
+		dialects = []Dialect{}
+	}
+	writeJSON(w, http.StatusOK, dialects)
+}
+
+func (h *handler) format(w http.ResponseWriter, r *http.Request) {
+	var req formatRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	config, err := h.requestConfig(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	formatted := sqlfmt.Format(req.Query, config)
+	diagnostics := sqlfmt.Diagnose(req.Query, config)
+	if diagnostics == nil {
+		diagnostics = []sqlfmt.Diagnostic{}
+	}
+	writeJSON(w, http.StatusOK, formatResponse{
+		Formatted:   formatted,
+		Language:    config.Language,
+		Tokens:      sqlfmt.Tokenize(formatted, config),
+		Diagnostics: diagnostics,
+	})
+}
+
+// requestConfig resolves the configuration of a format request: the base
+// configuration, then the options, then the language.
+func (h *handler) requestConfig(req formatRequest) (*sqlfmt.Config, error) {
+	config := h.Config(req.Query)
+
+	if len(req.Options) > 0 && string(req.Options) != "null" {
+		options, err := sqlfmt.ParseConfigFile("options.json", req.Options)
+		if err != nil {
+			return nil, err
+		}
+		if options.Extends != "" || len(options.Overrides) > 0 {
+			return nil, errors.New("options cannot use extends or overrides")
+		}
+		if err := options.ApplyToConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Language != "" {
+		lang, err := sqlfmt.ParseLanguage(req.Language)
+		if err != nil {
+			return nil, err
+		}
+		config.WithLang(lang)
+	}
+
+	// Responses are plain text, whatever the color settings
+	config.WithColorConfig(&sqlfmt.ColorConfig{})
+	return config, nil
+}
+
+func (h *handler) detect(w http.ResponseWriter, r *http.Request) {
+	var req detectRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	lang, detected := sqlfmt.DetectDialect(req.Filename, req.Query)
+	if !detected {
+		lang = ""
+	}
+	writeJSON(w, http.StatusOK, detectResponse{Language: lang, Detected: detected})
+}
+
+// decode reads the JSON request body into v. It writes an error response and
+// returns false if the body is too large or invalid.
+func (h *handler) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	body := http.MaxBytesReader(w, r.Body, h.MaxRequestSize)
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the JSON object")
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+		return false
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// recoverPanics turns a panic in next into an internal server error, so a
+// query that crashes the formatter does not drop the connection.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				log.Printf("panic serving %s %s: %v", r.Method, r.URL.Path, p)
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("internal error: %v", p))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// request sends a request to handler and decodes the JSON response into v.
+func request(t *testing.T, handler http.Handler, method, path, body string, v any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if v != nil {
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+	}
+	return rec.Code
+}
+
+func TestHealth(t *testing.T) {
+	var resp healthResponse
+	code := request(t, NewHandler(Options{}), http.MethodGet, "/health", "", &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", resp.Status)
+	assert.NotEmpty(t, resp.Version)
+}
+
+func TestDialects(t *testing.T) {
+	dialects := []Dialect{{Name: "postgresql", Description: "PostgreSQL", Aliases: []string{"postgres"}}}
+
+	var resp []Dialect
+	code := request(t, NewHandler(Options{Dialects: dialects}), http.MethodGet, "/dialects", "", &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, dialects, resp)
+
+	code = request(t, NewHandler(Options{Dialects: dialects}), http.MethodPost, "/dialects", "", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestFormat(t *testing.T) {
+	handler := NewHandler(Options{})
+
+	var resp formatResponse
+	code := request(t, handler, http.MethodPost, "/format",
+		`{"query": "select id::text from t where s = 'x", "language": "postgres",
+		  "options": {"keyword_case": "uppercase", "indent": "    "}}`, &resp)
+	require.Equal(t, http.StatusOK, code)
+
+	assert.Equal(t, "SELECT\n    id::text\nFROM\n    t\nWHERE\n    s = 'x", resp.Formatted)
+	assert.Equal(t, sqlfmt.PostgreSQL, resp.Language)
+	require.NotEmpty(t, resp.Tokens)
+	assert.Equal(t, sqlfmt.Token{Type: "reserved-top-level", Value: "SELECT", Offset: 0}, resp.Tokens[0])
+	assert.Equal(t, []sqlfmt.Diagnostic{{Start: 33, End: 35, Message: "unterminated quoted string"}}, resp.Diagnostics)
+}
+
+func TestFormatUsesBaseConfig(t *testing.T) {
+	handler := NewHandler(Options{Config: func(query string) *sqlfmt.Config {
+		return sqlfmt.NewDefaultConfig().WithKeywordCase(sqlfmt.KeywordCaseLowercase)
+	}})
+
+	var resp formatResponse
+	code := request(t, handler, http.MethodPost, "/format", `{"query": "SELECT 1"}`, &resp)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "select\n  1", resp.Formatted)
+	assert.Equal(t, sqlfmt.StandardSQL, resp.Language)
+	assert.Equal(t, []sqlfmt.Diagnostic{}, resp.Diagnostics)
+}
+
+func TestFormatErrors(t *testing.T) {
+	handler := NewHandler(Options{MaxRequestSize: 64})
+
+	tests := []struct {
+		name    string
+		body    string
+		code    int
+		message string
+	}{
+		{"invalid JSON", `{"query": `, http.StatusBadRequest, "invalid request"},
+		{"unknown field", `{"query": "select 1", "lang": "mysql"}`, http.StatusBadRequest, "unknown field"},
+		{"trailing data", `{"query": "select 1"} {}`, http.StatusBadRequest, "unexpected data"},
+		{"unknown language", `{"query": "select 1", "language": "cobol"}`, http.StatusBadRequest, "cobol"},
+		{"unknown option", `{"query": "select 1", "options": {"casing": 1}}`, http.StatusBadRequest, "casing"},
+		{"extends", `{"query": "select 1", "options": {"extends": "x"}}`, http.StatusBadRequest, "extends"},
+		{"too large", `{"query": "` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge, "64 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp errorResponse
+			code := request(t, handler, http.MethodPost, "/format", tt.body, &resp)
+			assert.Equal(t, tt.code, code)
+			assert.Contains(t, resp.Error, tt.message)
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	handler := NewHandler(Options{})
+
+	var resp detectResponse
+	code := request(t, handler, http.MethodPost, "/detect", `{"query": "SELECT data->>'name' FROM t"}`, &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, detectResponse{Language: sqlfmt.PostgreSQL, Detected: true}, resp)
+
+	resp = detectResponse{}
+	code = request(t, handler, http.MethodPost, "/detect", `{"query": "SELECT 1", "filename": "q.sql"}`, &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, detectResponse{}, resp)
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := NewHandler(Options{
+		Timeout: 10 * time.Millisecond,
+		Config: func(string) *sqlfmt.Config {
+			<-release
+			return sqlfmt.NewDefaultConfig()
+		},
+	})
+
+	var resp errorResponse
+	code := request(t, handler, http.MethodPost, "/format", `{"query": "select 1"}`, &resp)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "request timed out", resp.Error)
+}
+
+func TestPanic(t *testing.T) {
+	handler := NewHandler(Options{Config: func(string) *sqlfmt.Config { panic("boom") }})
+
+	var resp errorResponse
+	code := request(t, handler, http.MethodPost, "/format", `{"query": "select 1"}`, &resp)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "internal error: boom", resp.Error)
+}
+
+func TestServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Serve(ctx, listener, Options{}) }()
+
+	resp, err := http.Post("http://"+listener.Addr().String()+"/format", "application/json",
+		strings.NewReader(`{"query": "select 1"}`))
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `"formatted":"select\n  1"`)
+
+	cancel()
+	require.NoError(t, <-done)
+}
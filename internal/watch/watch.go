@@ -0,0 +1,156 @@
+// Package watch polls a directory tree for files that change.
+//
+// Polling works the same on every platform and file system, including network
+// mounts and containers where change notifications are unreliable. A change is
+// noticed when the modification time or size of a file differs from the
+// previous scan, and is reported once the file has stayed unchanged for the
+// debounce period, so that editors that save in several steps cause a single
+// report.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Default timing of a Watcher.
+const (
+	DefaultInterval = 250 * time.Millisecond
+	DefaultDebounce = 500 * time.Millisecond
+)
+
+// Options configures a Watcher.
+type Options struct {
+	// Interval is the time between scans, DefaultInterval if zero.
+	Interval time.Duration
+	// Debounce is how long a changed file must stay unchanged before it is
+	// reported, DefaultDebounce if zero.
+	Debounce time.Duration
+	// Skip excludes a file, or a directory with everything below it. The root
+	// itself is never skipped. Nil watches every file.
+	Skip func(path string, entry fs.DirEntry) bool
+}
+
+// Watcher reports the files below a root directory that change.
+type Watcher struct {
+	root    string
+	opts    Options
+	files   map[string]fileState // state of each file in the last scan
+	pending map[string]time.Time // changed files and the time of their last change
+}
+
+// fileState is what a scan compares to notice a change.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// New returns a Watcher for the files below root. The files that exist now
+// are only reported once they change.
+func New(root string, opts Options) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+
+	w := &Watcher{root: root, opts: opts, pending: map[string]time.Time{}}
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	return w, nil
+}
+
+// Poll scans the files and returns, in sorted order, those whose change has
+// settled by now. Files that are created count as changed; files that are
+// removed are forgotten.
+func (w *Watcher) Poll(now time.Time) ([]string, error) {
+	files, err := w.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	for path, state := range files {
+		if previous, ok := w.files[path]; !ok || previous != state {
+			w.pending[path] = now
+		}
+	}
+	w.files = files
+
+	var settled []string
+	for path, changed := range w.pending {
+		if _, ok := files[path]; !ok {
+			delete(w.pending, path)
+			continue
+		}
+		if now.Sub(changed) >= w.opts.Debounce {
+			settled = append(settled, path)
+			delete(w.pending, path)
+		}
+	}
+	sort.Strings(settled)
+	return settled, nil
+}
+
+// Run polls until ctx is done and calls changed for each file whose change
+// has settled. It returns nil when ctx is done, or the first scan error.
+func (w *Watcher) Run(ctx context.Context, changed func(path string)) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			paths, err := w.Poll(now)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				changed(path)
+			}
+		}
+	}
+}
+
+// scan records the state of the regular files below the root.
+func (w *Watcher) scan() (map[string]fileState, error) {
+	files := map[string]fileState{}
+	err := filepath.WalkDir(w.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may disappear while the tree is walked
+			if path != w.root && errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if path != w.root && w.opts.Skip != nil && w.opts.Skip(path, entry) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		files[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,142 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes content to path with the modification time modTime, so
+// that changes are noticed regardless of the time resolution of the file system.
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestPollDebounces(t *testing.T) {
+	root := t.TempDir()
+	a := filepath.Join(root, "a.sql")
+	b := filepath.Join(root, "sub", "b.sql")
+	start := time.Now()
+	writeFile(t, a, "select 1", start)
+
+	w, err := New(root, Options{Debounce: time.Second})
+	require.NoError(t, err)
+
+	// Existing files are not reported
+	paths, err := w.Poll(start)
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	writeFile(t, a, "select 2", start.Add(time.Minute))
+	writeFile(t, b, "select 3", start.Add(time.Minute))
+	paths, err = w.Poll(start.Add(time.Second))
+	require.NoError(t, err)
+	assert.Empty(t, paths, "changes are reported once they settle")
+
+	// A further change restarts the debounce period of the file
+	writeFile(t, a, "select 22", start.Add(2*time.Minute))
+	paths, err = w.Poll(start.Add(1500 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	paths, err = w.Poll(start.Add(2 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, []string{b}, paths)
+
+	paths, err = w.Poll(start.Add(3 * time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, []string{a}, paths)
+
+	paths, err = w.Poll(start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, paths, "each change is reported once")
+}
+
+func TestPollForgetsRemovedFiles(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.sql")
+	start := time.Now()
+
+	w, err := New(root, Options{Debounce: time.Second})
+	require.NoError(t, err)
+
+	writeFile(t, path, "select 1", start)
+	paths, err := w.Poll(start)
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	require.NoError(t, os.Remove(path))
+	paths, err = w.Poll(start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestPollSkips(t *testing.T) {
+	root := t.TempDir()
+	start := time.Now()
+
+	var skipped []string
+	w, err := New(root, Options{
+		Debounce: time.Second,
+		Skip: func(path string, entry fs.DirEntry) bool {
+			skip := entry.Name() == "vendor" || (!entry.IsDir() && !strings.HasSuffix(path, ".sql"))
+			if skip {
+				skipped = append(skipped, entry.Name())
+			}
+			return skip
+		},
+	})
+	require.NoError(t, err)
+
+	writeFile(t, filepath.Join(root, "a.sql"), "select 1", start)
+	writeFile(t, filepath.Join(root, "notes.txt"), "notes", start)
+	writeFile(t, filepath.Join(root, "vendor", "b.sql"), "select 2", start)
+
+	_, err = w.Poll(start)
+	require.NoError(t, err)
+	paths, err := w.Poll(start.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "a.sql")}, paths)
+	assert.ElementsMatch(t, []string{"notes.txt", "vendor", "notes.txt", "vendor"}, skipped)
+}
+
+func TestNewMissingRoot(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing"), Options{})
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.sql")
+
+	w, err := New(root, Options{Interval: 10 * time.Millisecond, Debounce: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	changed := make(chan string, 1)
+	go func() {
+		done <- w.Run(ctx, func(path string) { changed <- path })
+	}()
+
+	writeFile(t, path, "select 1", time.Now())
+	select {
+	case got := <-changed:
+		assert.Equal(t, path, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not reported")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
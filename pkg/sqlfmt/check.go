@@ -0,0 +1,86 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CheckResult represents the result of checking a single file or input.
+type CheckResult struct {
+	File  string `json:"file"`
+	Valid bool   `json:"valid"`
+	Diff  string `json:"diff,omitempty"`
+	Error string `json:"error,omitempty"`
+
+	// Original and Formatted hold the checked input and its formatted form.
+	// They are not part of the JSON report.
+	Original  string `json:"-"`
+	Formatted string `json:"-"`
+}
+
+// CheckSummary represents the overall result of checking several inputs.
+type CheckSummary struct {
+	TotalFiles   int           `json:"total_files"`
+	ValidFiles   int           `json:"valid_files"`
+	InvalidFiles int           `json:"invalid_files"`
+	ErrorFiles   int           `json:"error_files"`
+	Results      []CheckResult `json:"results"`
+}
+
+// Check reports whether content is already formatted according to an optional
+// config, the way FormatDocument would write it.
+func Check(content string, cfg ...*Config) (CheckResult, error) {
+	formatted := FormatDocument(content, cfg...)
+	return CheckResult{
+		Valid:     IsFormatted(content, formatted),
+		Original:  content,
+		Formatted: formatted,
+	}, nil
+}
+
+// CheckFile reads the file at path and reports whether it is already formatted.
+// A read failure is returned as an error and also recorded in the result.
+func CheckFile(path string, cfg ...*Config) (CheckResult, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("failed to read file: %w", err)
+		return CheckResult{File: path, Error: err.Error()}, err
+	}
+
+	result, err := Check(string(content), cfg...)
+	result.File = path
+	return result, err
+}
+
+// CheckFiles checks every file in paths. Per-file failures are recorded in the
+// corresponding result instead of aborting the run.
+func CheckFiles(paths []string, cfg ...*Config) CheckSummary {
+	summary := CheckSummary{Results: make([]CheckResult, 0, len(paths))}
+	for _, path := range paths {
+		result, _ := CheckFile(path, cfg...)
+		summary.Add(result)
+	}
+	return summary
+}
+
+// Add appends a result to the summary and updates its counters.
+func (s *CheckSummary) Add(result CheckResult) {
+	s.Results = append(s.Results, result)
+	s.TotalFiles++
+	switch {
+	case result.Error != "":
+		s.ErrorFiles++
+	case result.Valid:
+		s.ValidFiles++
+	default:
+		s.InvalidFiles++
+	}
+}
+
+// IsFormatted compares an input with its formatted form byte for byte, so that
+// final newlines and line endings count. Input of only whitespace has nothing
+// to format and is always formatted.
+func IsFormatted(original, formatted string) bool {
+	return original == formatted || strings.TrimSpace(original) == ""
+}
@@ -0,0 +1,81 @@
+package sqlfmt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("formatted input is valid", func(t *testing.T) {
+		result, err := Check("SELECT\n  *\nFROM\n  users\n")
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+	})
+
+	t.Run("unformatted input is invalid", func(t *testing.T) {
+		result, err := Check("SELECT * FROM users")
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, "SELECT\n  *\nFROM\n  users", result.Formatted)
+	})
+
+	t.Run("uses the given config", func(t *testing.T) {
+		cfg := NewDefaultConfig().WithKeywordCase(KeywordCaseUppercase)
+		result, err := Check("select\n  *\nfrom\n  users", cfg)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+	})
+
+	t.Run("final newline counts", func(t *testing.T) {
+		cfg := NewDefaultConfig().WithInsertFinalNewline(true)
+		result, err := Check("SELECT\n  *\nFROM\n  users", cfg)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, "SELECT\n  *\nFROM\n  users\n", result.Formatted)
+
+		result, err = Check("SELECT\n  *\nFROM\n  users\n\n\n")
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+	})
+
+	t.Run("whitespace only input is valid", func(t *testing.T) {
+		result, err := Check(" \n\n")
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+	})
+}
+
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "query.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT * FROM users"), 0o644))
+
+	result, err := CheckFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, result.File)
+	assert.False(t, result.Valid)
+
+	result, err = CheckFile(filepath.Join(dir, "missing.sql"))
+	require.Error(t, err)
+	assert.Contains(t, result.Error, "failed to read file")
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.sql")
+	invalid := filepath.Join(dir, "invalid.sql")
+	require.NoError(t, os.WriteFile(valid, []byte("SELECT\n  1"), 0o644))
+	require.NoError(t, os.WriteFile(invalid, []byte("SELECT 1 FROM t"), 0o644))
+
+	summary := CheckFiles([]string{valid, invalid, filepath.Join(dir, "missing.sql")})
+	assert.Equal(t, 3, summary.TotalFiles)
+	assert.Equal(t, 1, summary.ValidFiles)
+	assert.Equal(t, 1, summary.InvalidFiles)
+	assert.Equal(t, 1, summary.ErrorFiles)
+	require.Len(t, summary.Results, 3)
+	assert.Equal(t, invalid, summary.Results[1].File)
+}
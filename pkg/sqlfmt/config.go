@@ -1,8 +1,11 @@
 package sqlfmt
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/core"
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/utils"
 )
 
@@ -40,6 +43,17 @@ const (
 	JoinIndentRootLevel JoinIndentStyle = "root-level"
 )
 
+// LineEnding selects the line break written between output lines.
+type LineEnding string
+
+const (
+	LineEndingAuto LineEnding = "auto" // the line ending of the input
+	LineEndingLF   LineEnding = "lf"
+	LineEndingCRLF LineEnding = "crlf"
+
+	DefaultLineEnding = LineEndingAuto
+)
+
 type Config struct {
 	Language                          Language
 	Indent                            string
@@ -56,6 +70,8 @@ type Config struct {
 	CommentMinSpacing                 int
 	JoinIndentStyle                   JoinIndentStyle
 	PreserveEmptyLinesBetweenComments bool
+	LineEnding                        LineEnding
+	InsertFinalNewline                bool
 }
 
 func NewDefaultConfig() *Config {
@@ -75,6 +91,8 @@ func NewDefaultConfig() *Config {
 		CommentMinSpacing:                 1,
 		JoinIndentStyle:                   JoinIndentDefault,
 		PreserveEmptyLinesBetweenComments: false,
+		LineEnding:                        DefaultLineEnding,
+		InsertFinalNewline:                false,
 	}
 }
 
@@ -159,10 +177,79 @@ func (c *Config) WithPreserveEmptyLinesBetweenComments(preserve bool) *Config {
 	return c
 }
 
+func (c *Config) WithLineEnding(lineEnding LineEnding) *Config {
+	c.LineEnding = lineEnding
+	return c
+}
+
+func (c *Config) WithInsertFinalNewline(insert bool) *Config {
+	c.InsertFinalNewline = insert
+	return c
+}
+
 func (c *Config) Empty() bool {
 	return reflect.DeepEqual(*c, Config{})
 }
 
+// Clone returns a shallow copy of the config. Nested configs such as Params
+// are shared with the original.
+func (c *Config) Clone() *Config {
+	clone := *c
+	return &clone
+}
+
+// ParseLanguage resolves a dialect name or one of its aliases to a Language.
+func ParseLanguage(name string) (Language, error) {
+	switch strings.ToLower(name) {
+	case string(StandardSQL), "standard":
+		return StandardSQL, nil
+	case string(PostgreSQL), "postgres":
+		return PostgreSQL, nil
+	case string(MySQL), "mariadb":
+		return MySQL, nil
+	case string(PLSQL), "plsql", "oracle":
+		return PLSQL, nil
+	case string(DB2):
+		return DB2, nil
+	case string(N1QL):
+		return N1QL, nil
+	case string(SQLite):
+		return SQLite, nil
+	default:
+		return StandardSQL, fmt.Errorf("unknown language: %s", name)
+	}
+}
+
+// ParseKeywordCase resolves a keyword case name to a KeywordCase.
+func ParseKeywordCase(name string) (KeywordCase, error) {
+	switch kc := KeywordCase(strings.ToLower(name)); kc {
+	case KeywordCasePreserve, KeywordCaseUppercase, KeywordCaseLowercase, KeywordCaseDialect:
+		return kc, nil
+	default:
+		return DefaultKeywordCase, fmt.Errorf("unknown keyword case: %s", name)
+	}
+}
+
+// ParseJoinIndentStyle resolves a JOIN indentation style name to a JoinIndentStyle.
+func ParseJoinIndentStyle(name string) (JoinIndentStyle, error) {
+	switch style := JoinIndentStyle(strings.ToLower(name)); style {
+	case JoinIndentDefault, JoinIndentRootLevel:
+		return style, nil
+	default:
+		return JoinIndentDefault, fmt.Errorf("unknown join indent style: %s", name)
+	}
+}
+
+// ParseLineEnding resolves a line ending name to a LineEnding.
+func ParseLineEnding(name string) (LineEnding, error) {
+	switch lineEnding := LineEnding(strings.ToLower(name)); lineEnding {
+	case LineEndingAuto, LineEndingLF, LineEndingCRLF:
+		return lineEnding, nil
+	default:
+		return DefaultLineEnding, fmt.Errorf("unknown line ending: %s", name)
+	}
+}
+
 type Params struct {
 	MapParams  map[string]string
 	ListParams []string
@@ -200,6 +287,12 @@ type TokenizerConfig struct {
 	SpecialWordChars              []string
 }
 
+// Merge returns a tokenizer config holding the entries of tc followed by the
+// entries of other that tc does not already contain. Neither config is modified.
+func (tc *TokenizerConfig) Merge(other *TokenizerConfig) *TokenizerConfig {
+	return (*TokenizerConfig)((*core.TokenizerConfig)(tc).Merge((*core.TokenizerConfig)(other)))
+}
+
 type ColorConfig struct {
 	ReservedWordFormatOptions []utils.ANSIFormatOption
 	StringFormatOptions       []utils.ANSIFormatOption
@@ -514,3 +514,71 @@ func TestTokenizerConfigCustomReservedWords(t *testing.T) {
 	assert.NotNil(t, config.TokenizerConfig)
 	assert.Contains(t, config.TokenizerConfig.ReservedWords, "CUSTOM_KEYWORD")
 }
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Language
+	}{
+		{"sql", StandardSQL},
+		{"standard", StandardSQL},
+		{"Postgres", PostgreSQL},
+		{"mariadb", MySQL},
+		{"oracle", PLSQL},
+		{"pl/sql", PLSQL},
+		{"db2", DB2},
+		{"n1ql", N1QL},
+		{"SQLite", SQLite},
+	}
+	for _, tt := range tests {
+		got, err := ParseLanguage(tt.input)
+		require.NoError(t, err, tt.input)
+		require.Equal(t, tt.want, got, tt.input)
+	}
+
+	_, err := ParseLanguage("cobol")
+	require.Error(t, err)
+}
+
+func TestParseLineEnding(t *testing.T) {
+	lineEnding, err := ParseLineEnding("CRLF")
+	require.NoError(t, err)
+	require.Equal(t, LineEndingCRLF, lineEnding)
+
+	lineEnding, err = ParseLineEnding("lf")
+	require.NoError(t, err)
+	require.Equal(t, LineEndingLF, lineEnding)
+
+	lineEnding, err = ParseLineEnding("auto")
+	require.NoError(t, err)
+	require.Equal(t, LineEndingAuto, lineEnding)
+
+	_, err = ParseLineEnding("cr")
+	require.EqualError(t, err, "unknown line ending: cr")
+}
+
+func TestParseKeywordCaseAndJoinIndentStyle(t *testing.T) {
+	keywordCase, err := ParseKeywordCase("UPPERCASE")
+	require.NoError(t, err)
+	require.Equal(t, KeywordCaseUppercase, keywordCase)
+
+	_, err = ParseKeywordCase("shouting")
+	require.Error(t, err)
+
+	style, err := ParseJoinIndentStyle("root-level")
+	require.NoError(t, err)
+	require.Equal(t, JoinIndentRootLevel, style)
+
+	_, err = ParseJoinIndentStyle("nested")
+	require.Error(t, err)
+}
+
+func TestConfigClone(t *testing.T) {
+	original := NewDefaultConfig().WithLang(MySQL)
+	clone := original.Clone()
+	clone.WithLang(PostgreSQL).WithIndent("\t")
+
+	require.Equal(t, MySQL, original.Language)
+	require.Equal(t, DefaultIndent, original.Indent)
+	require.Equal(t, PostgreSQL, clone.Language)
+}
@@ -1,42 +1,457 @@
 package sqlfmt
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/core"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/utils"
 	"gopkg.in/yaml.v3"
 )
 
 // ConfigFile represents the structure of a sqlfmt configuration file.
 type ConfigFile struct {
-	Language            string `yaml:"language,omitempty"`
-	Indent              string `yaml:"indent,omitempty"`
-	KeywordCase         string `yaml:"keyword_case,omitempty"`
-	LinesBetweenQueries int    `yaml:"lines_between_queries,omitempty"`
-	AlignColumnNames    *bool  `yaml:"align_column_names,omitempty"`
-	AlignAssignments    *bool  `yaml:"align_assignments,omitempty"`
-	AlignValues         *bool  `yaml:"align_values,omitempty"`
-	MaxLineLength       *int   `yaml:"max_line_length,omitempty"`
+	// Schema names the JSON Schema of the file for editors. It is not used
+	// by sqlfmt.
+	Schema string `yaml:"$schema,omitempty"`
+	// Root stops the upward search for further config files.
+	Root bool `yaml:"root,omitempty"`
+	// Extends names a config file, relative to this one, or a built-in preset
+	// whose settings this file refines.
+	Extends string `yaml:"extends,omitempty"`
+
+	ConfigSettings `yaml:",inline"`
+
+	// Overrides refine the settings for files matching their patterns. They
+	// are applied in order after the base settings.
+	Overrides []ConfigOverride `yaml:"overrides,omitempty"`
+
+	// path and lines locate settings for error messages
+	path  string
+	lines map[string]int
+
+	// bases are applied before this file, lowest precedence first: the config
+	// of the parent directory and the config named by Extends.
+	bases []*ConfigFile
 }
 
-// LoadConfigFile attempts to load configuration from various locations.
+// configPresets are the built-in configurations that can be named in extends.
+var configPresets = map[string]string{
+	"default":   "",
+	"uppercase": "keyword_case: uppercase",
+	"lowercase": "keyword_case: lowercase",
+	"aligned":   "align_column_names: true\nalign_assignments: true\nalign_values: true",
+	"compact":   "lines_between_queries: 1",
+}
+
+// ConfigSettings holds the formatting settings of a configuration file.
+// Pointer fields distinguish settings that are absent from explicit zero values.
+type ConfigSettings struct {
+	Language                          string               `yaml:"language,omitempty"`
+	Indent                            string               `yaml:"indent,omitempty"`
+	KeywordCase                       string               `yaml:"keyword_case,omitempty"`
+	LinesBetweenQueries               *int                 `yaml:"lines_between_queries,omitempty"`
+	AlignColumnNames                  *bool                `yaml:"align_column_names,omitempty"`
+	AlignAssignments                  *bool                `yaml:"align_assignments,omitempty"`
+	AlignValues                       *bool                `yaml:"align_values,omitempty"`
+	MaxLineLength                     *int                 `yaml:"max_line_length,omitempty"`
+	PreserveCommentIndent             *bool                `yaml:"preserve_comment_indent,omitempty"`
+	CommentMinSpacing                 *int                 `yaml:"comment_min_spacing,omitempty"`
+	JoinIndentStyle                   string               `yaml:"join_indent_style,omitempty"`
+	PreserveEmptyLinesBetweenComments *bool                `yaml:"preserve_empty_lines_between_comments,omitempty"`
+	LineEnding                        string               `yaml:"line_ending,omitempty"`
+	InsertFinalNewline                *bool                `yaml:"insert_final_newline,omitempty"`
+	Color                             *ColorConfigFile     `yaml:"color,omitempty"`
+	Params                            *ParamsConfigFile    `yaml:"params,omitempty"`
+	Tokenizer                         *TokenizerConfigFile `yaml:"tokenizer,omitempty"`
+}
+
+// ConfigOverride applies its settings to the files matching one of its glob
+// patterns. Patterns are relative to the directory of the config file; "**"
+// matches any number of directories and a pattern without a slash matches the
+// file name in any directory.
+type ConfigOverride struct {
+	Files          []string `yaml:"files"`
+	ConfigSettings `yaml:",inline"`
+}
+
+// NewConfigSettings describes config in configuration file form, so that a
+// resolved configuration can be written back as a config file.
+func NewConfigSettings(config *Config) ConfigSettings {
+	settings := ConfigSettings{
+		Language:                          string(config.Language),
+		Indent:                            config.Indent,
+		KeywordCase:                       string(config.KeywordCase),
+		LinesBetweenQueries:               ptrTo(config.LinesBetweenQueries),
+		AlignColumnNames:                  ptrTo(config.AlignColumnNames),
+		AlignAssignments:                  ptrTo(config.AlignAssignments),
+		AlignValues:                       ptrTo(config.AlignValues),
+		MaxLineLength:                     ptrTo(config.MaxLineLength),
+		PreserveCommentIndent:             ptrTo(config.PreserveCommentIndent),
+		CommentMinSpacing:                 ptrTo(config.CommentMinSpacing),
+		JoinIndentStyle:                   string(config.JoinIndentStyle),
+		PreserveEmptyLinesBetweenComments: ptrTo(config.PreserveEmptyLinesBetweenComments),
+		LineEnding:                        string(config.LineEnding),
+		InsertFinalNewline:                ptrTo(config.InsertFinalNewline),
+	}
+
+	if config.ColorConfig != nil && !config.ColorConfig.Empty() {
+		settings.Color = &ColorConfigFile{
+			ReservedWords: formatOptionNames(config.ColorConfig.ReservedWordFormatOptions),
+			Strings:       formatOptionNames(config.ColorConfig.StringFormatOptions),
+			Numbers:       formatOptionNames(config.ColorConfig.NumberFormatOptions),
+			Booleans:      formatOptionNames(config.ColorConfig.BooleanFormatOptions),
+			Comments:      formatOptionNames(config.ColorConfig.CommentFormatOptions),
+			FunctionCalls: formatOptionNames(config.ColorConfig.FunctionCallFormatOptions),
+		}
+	}
+
+	if config.Params != nil && (len(config.Params.MapParams) > 0 || len(config.Params.ListParams) > 0) {
+		settings.Params = &ParamsConfigFile{Named: config.Params.MapParams, Positional: config.Params.ListParams}
+	}
+
+	if tc := config.TokenizerConfig; tc != nil && !(*core.TokenizerConfig)(tc).Empty() {
+		settings.Tokenizer = &TokenizerConfigFile{
+			ReservedWords:                 tc.ReservedWords,
+			ReservedTopLevelWords:         tc.ReservedTopLevelWords,
+			ReservedNewlineWords:          tc.ReservedNewlineWords,
+			ReservedTopLevelWordsNoIndent: tc.ReservedTopLevelWordsNoIndent,
+			StringTypes:                   tc.StringTypes,
+			OpenParens:                    tc.OpenParens,
+			CloseParens:                   tc.CloseParens,
+			IndexedPlaceholderTypes:       tc.IndexedPlaceholderTypes,
+			NamedPlaceholderTypes:         tc.NamedPlaceholderTypes,
+			LineCommentTypes:              tc.LineCommentTypes,
+			SpecialWordChars:              tc.SpecialWordChars,
+		}
+	}
+
+	return settings
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
+// formatOptionNames names color options; an empty category is written as
+// "none" so that it does not fall back to the default colors.
+func formatOptionNames(options []utils.ANSIFormatOption) []string {
+	if len(options) == 0 {
+		return []string{"none"}
+	}
+	names := make([]string, 0, len(options))
+	for _, option := range options {
+		if name, ok := utils.ANSIFormatOptionName(option); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ColorConfigFile holds the color and style names used for each token category.
+// Categories that are not set keep the default color.
+type ColorConfigFile struct {
+	ReservedWords []string `yaml:"reserved_words,omitempty"`
+	Strings       []string `yaml:"strings,omitempty"`
+	Numbers       []string `yaml:"numbers,omitempty"`
+	Booleans      []string `yaml:"booleans,omitempty"`
+	Comments      []string `yaml:"comments,omitempty"`
+	FunctionCalls []string `yaml:"function_calls,omitempty"`
+}
+
+// ParamsConfigFile holds placeholder replacements, either by name or by position.
+type ParamsConfigFile struct {
+	Named      map[string]string `yaml:"named,omitempty"`
+	Positional []string          `yaml:"positional,omitempty"`
+}
+
+// TokenizerConfigFile holds entries that extend the dialect's tokenizer config.
+type TokenizerConfigFile struct {
+	ReservedWords                 []string `yaml:"reserved_words,omitempty"`
+	ReservedTopLevelWords         []string `yaml:"reserved_top_level_words,omitempty"`
+	ReservedNewlineWords          []string `yaml:"reserved_newline_words,omitempty"`
+	ReservedTopLevelWordsNoIndent []string `yaml:"reserved_top_level_words_no_indent,omitempty"`
+	StringTypes                   []string `yaml:"string_types,omitempty"`
+	OpenParens                    []string `yaml:"open_parens,omitempty"`
+	CloseParens                   []string `yaml:"close_parens,omitempty"`
+	IndexedPlaceholderTypes       []string `yaml:"indexed_placeholder_types,omitempty"`
+	NamedPlaceholderTypes         []string `yaml:"named_placeholder_types,omitempty"`
+	LineCommentTypes              []string `yaml:"line_comment_types,omitempty"`
+	SpecialWordChars              []string `yaml:"special_word_chars,omitempty"`
+}
+
+// ConfigError describes an invalid setting in a configuration file.
+type ConfigError struct {
+	File string // Path of the config file, if known
+	Line int    // Line of the offending key, or 0 if unknown
+	Key  string // Dotted path of the offending key, e.g. "color.strings"
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+		}
+		b.WriteString(": ")
+	} else if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	if e.Key != "" {
+		b.WriteString(e.Key)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// LoadConfigFile resolves the configuration for the current directory. The
+// config files from the directory up to the git root, or up to a file with
+// root: true, are merged with nearer files taking precedence per key. A config
+// in the home directory forms the base unless the search stopped at a root file.
 func LoadConfigFile() (*ConfigFile, error) {
-	searchPaths := getConfigSearchPaths()
+	return loadConfigCascade(getConfigSearchPaths())
+}
+
+// ReadConfigFile loads the config file at path together with the files and
+// presets it extends. Unlike LoadConfigFile it does not search other directories.
+func ReadConfigFile(path string) (*ConfigFile, error) {
+	return readConfigFile(path, nil)
+}
+
+func readConfigFile(path string, extendedBy []string) (*ConfigFile, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, visited := range extendedBy {
+		if visited == absPath {
+			return nil, fmt.Errorf("circular extends: %s", strings.Join(append(extendedBy, absPath), " -> "))
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := ParseConfigFile(path, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Extends != "" {
+		base, err := config.loadExtends(append(extendedBy, absPath))
+		if err != nil {
+			return nil, err
+		}
+		config.bases = append(config.bases, base)
+	}
+
+	return config, nil
+}
+
+// loadExtends loads the preset or config file named by Extends.
+func (cf *ConfigFile) loadExtends(extendedBy []string) (*ConfigFile, error) {
+	if preset, ok := configPresets[cf.Extends]; ok {
+		return ParseConfigFile("preset "+cf.Extends, []byte(preset))
+	}
+
+	path := cf.Extends
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(cf.path), path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, cf.errorAt("extends", fmt.Errorf("no preset or config file named %q", cf.Extends))
+	}
+	return readConfigFile(path, extendedBy)
+}
+
+// loadConfigCascade merges the config files found at searchPaths, which are
+// ordered from the nearest to the most general location. Only the first file
+// found in each directory is used.
+func loadConfigCascade(searchPaths []string) (*ConfigFile, error) {
+	var chain []*ConfigFile
+	seenDirs := map[string]bool{}
 
 	for _, path := range searchPaths {
-		if content, err := os.ReadFile(path); err == nil {
-			var config ConfigFile
-			if err := yaml.Unmarshal(content, &config); err != nil {
-				return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		dir := filepath.Dir(path)
+		if seenDirs[dir] {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() || !isConfigFile(path) {
+			continue
+		}
+		seenDirs[dir] = true
+
+		config, err := ReadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, config)
+		if config.Root {
+			break
+		}
+	}
+
+	if len(chain) == 0 {
+		// No config file found, return empty config
+		return &ConfigFile{}, nil
+	}
+
+	// Each file refines the config of the directory above it
+	for i := len(chain) - 2; i >= 0; i-- {
+		chain[i].bases = append([]*ConfigFile{chain[i+1]}, chain[i].bases...)
+	}
+	return chain[0], nil
+}
+
+// ParseConfigFile parses the content of a configuration file. Unknown keys and
+// values of the wrong type are reported as a *ConfigError naming the key and
+// line. The format is chosen by the name of path: TOML for .toml files, the
+// [tool.sqlfmt] table of pyproject.toml, and YAML or JSON otherwise.
+func ParseConfigFile(path string, content []byte) (*ConfigFile, error) {
+	node, err := parseConfigDocument(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	config := &ConfigFile{path: path, lines: map[string]int{}}
+	if node == nil {
+		return config, nil
+	}
+
+	if err := checkConfigNode(node, reflect.TypeOf(config).Elem(), "", config.lines); err != nil {
+		err.File = path
+		return nil, err
+	}
+	if err := node.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// checkConfigNode verifies that node matches the shape of type t, recording the
+// line of every key it visits in lines.
+func checkConfigNode(node *yaml.Node, t reflect.Type, key string, lines map[string]int) *ConfigError {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	invalid := func(expected string) *ConfigError {
+		return &ConfigError{Line: node.Line, Key: key, Err: fmt.Errorf("expected %s", expected)}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return invalid("a mapping")
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			path := joinConfigKey(key, keyNode.Value)
+			field, ok := fields[keyNode.Value]
+			if !ok {
+				return &ConfigError{Line: keyNode.Line, Key: path, Err: errors.New("unknown key")}
+			}
+			lines[path] = keyNode.Line
+			if err := checkConfigNode(valueNode, field, path, lines); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return invalid("a mapping")
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := joinConfigKey(key, node.Content[i].Value)
+			lines[path] = node.Content[i].Line
+			if err := checkConfigNode(node.Content[i+1], t.Elem(), path, lines); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return invalid("a list")
+		}
+		for i, item := range node.Content {
+			path := fmt.Sprintf("%s[%d]", key, i)
+			lines[path] = item.Line
+			if err := checkConfigNode(item, t.Elem(), path, lines); err != nil {
+				return err
 			}
-			return &config, nil
+		}
+	case reflect.String:
+		// Numbers and booleans would decode as their text, e.g. indent: 4
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+			return invalid(scalarKindName(t.Kind()))
+		}
+	default:
+		if node.Kind != yaml.ScalarNode || node.Decode(reflect.New(t).Interface()) != nil {
+			return invalid(scalarKindName(t.Kind()))
 		}
 	}
 
-	// No config file found, return empty config
-	return &ConfigFile{}, nil
+	return nil
+}
+
+// yamlFields maps the yaml keys of a struct to the types of their fields.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if strings.Contains(options, "inline") {
+			for key, fieldType := range yamlFields(field.Type) {
+				fields[key] = fieldType
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func joinConfigKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func scalarKindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int:
+		return "an integer"
+	default:
+		return "a string"
+	}
 }
 
 // getConfigSearchPaths returns the list of paths to search for config files.
@@ -51,11 +466,9 @@ func getConfigSearchPaths() []string {
 
 	// 2. User home directory
 	if homeDir, err := os.UserHomeDir(); err == nil {
-		paths = append(paths,
-			filepath.Join(homeDir, ".sqlfmtrc"),
-			filepath.Join(homeDir, ".sqlfmt.yaml"),
-			filepath.Join(homeDir, ".sqlfmt.yml"),
-		)
+		for _, filename := range homeConfigFileNames {
+			paths = append(paths, filepath.Join(homeDir, filename))
+		}
 	}
 
 	return paths
@@ -68,7 +481,7 @@ func findConfigInParentDirs(startDir string) []string {
 
 	for {
 		// Add potential config files in this directory
-		for _, filename := range []string{".sqlfmtrc", ".sqlfmt.yaml", ".sqlfmt.yml", "sqlfmt.yaml", "sqlfmt.yml"} {
+		for _, filename := range configFileNames {
 			paths = append(paths, filepath.Join(dir, filename))
 		}
 
@@ -94,56 +507,27 @@ func isGitRoot(dir string) bool {
 	return false
 }
 
-// LoadConfigFileForPath attempts to load configuration from various locations relative to a file path.
+// LoadConfigFileForPath resolves the configuration for a file the same way
+// LoadConfigFile does, starting from the file's directory.
 func LoadConfigFileForPath(filePath string) (*ConfigFile, error) {
-	searchPaths := getConfigSearchPathsForPath(filePath)
-
-	for _, path := range searchPaths {
-		if content, err := os.ReadFile(path); err == nil {
-			var config ConfigFile
-			if err := yaml.Unmarshal(content, &config); err != nil {
-				return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
-			}
-			return &config, nil
-		}
-	}
-
-	// No config file found, return empty config
-	return &ConfigFile{}, nil
+	return loadConfigCascade(getConfigSearchPathsForPath(filePath))
 }
 
 // getConfigSearchPathsForPath returns the list of paths to search for config files relative to a file path.
 func getConfigSearchPathsForPath(filePath string) []string {
-	var paths []string
-
-	// Get the directory of the file
-	fileDir := filepath.Dir(filePath)
+	// A relative path must be made absolute, since the parent of "." is "."
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		filePath = absPath
+	}
 
 	// Search from file directory up to git root
-	dir := fileDir
-	for {
-		// Add potential config files in this directory
-		for _, filename := range []string{".sqlfmtrc", ".sqlfmt.yaml", ".sqlfmt.yml", "sqlfmt.yaml", "sqlfmt.yml"} {
-			paths = append(paths, filepath.Join(dir, filename))
-		}
-
-		parent := filepath.Dir(dir)
-
-		// Stop if we've reached the root or found a git directory
-		if parent == dir || isGitRoot(dir) {
-			break
-		}
-
-		dir = parent
-	}
+	paths := findConfigInParentDirs(filepath.Dir(filePath))
 
 	// Also search user home directory (global configs)
 	if homeDir, err := os.UserHomeDir(); err == nil {
-		paths = append(paths,
-			filepath.Join(homeDir, ".sqlfmtrc"),
-			filepath.Join(homeDir, ".sqlfmt.yaml"),
-			filepath.Join(homeDir, ".sqlfmt.yml"),
-		)
+		for _, filename := range homeConfigFileNames {
+			paths = append(paths, filepath.Join(homeDir, filename))
+		}
 	}
 
 	return paths
@@ -160,21 +544,8 @@ func ParseInlineDialectHint(content string) (Language, bool) {
 				directive := strings.TrimSpace(strings.TrimPrefix(comment, "sqlfmt:"))
 				if strings.HasPrefix(directive, "dialect=") {
 					dialectStr := strings.TrimSpace(strings.TrimPrefix(directive, "dialect="))
-					switch strings.ToLower(dialectStr) {
-					case string(StandardSQL), "standard":
-						return StandardSQL, true
-					case string(PostgreSQL), "postgres":
-						return PostgreSQL, true
-					case string(MySQL), "mariadb":
-						return MySQL, true
-					case string(PLSQL), "plsql", "oracle":
-						return PLSQL, true
-					case string(DB2):
-						return DB2, true
-					case string(N1QL):
-						return N1QL, true
-					case string(SQLite):
-						return SQLite, true
+					if lang, err := ParseLanguage(dialectStr); err == nil {
+						return lang, true
 					}
 				}
 			}
@@ -187,81 +558,465 @@ func ParseInlineDialectHint(content string) (Language, bool) {
 	return StandardSQL, false
 }
 
-// ApplyToConfig applies the configuration file settings to a Config struct.
+// ApplyToConfig applies the base settings of the configuration file, after
+// those of the configs it refines, to a Config struct. Overrides are ignored;
+// use ApplyToConfigForPath to include them. Invalid values are reported as a
+// *ConfigError naming the key and its line.
 func (cf *ConfigFile) ApplyToConfig(config *Config) error {
-	if cf.Language != "" {
-		if err := applyConfigLanguage(cf.Language, config); err != nil {
+	for _, base := range cf.bases {
+		if err := base.ApplyToConfig(config); err != nil {
 			return err
 		}
 	}
+	return cf.applySettings(&cf.ConfigSettings, "", config)
+}
 
-	if cf.Indent != "" {
-		config.Indent = cf.Indent
+// ApplyToConfigForPath applies the base settings followed by every override
+// whose patterns match filePath, after doing the same for the configs it refines.
+func (cf *ConfigFile) ApplyToConfigForPath(config *Config, filePath string) error {
+	for _, base := range cf.bases {
+		if err := base.ApplyToConfigForPath(config, filePath); err != nil {
+			return err
+		}
+	}
+	if err := cf.applySettings(&cf.ConfigSettings, "", config); err != nil {
+		return err
+	}
+
+	for i := range cf.Overrides {
+		override := &cf.Overrides[i]
+		prefix := fmt.Sprintf("overrides[%d].", i)
+
+		matched, err := cf.overrideMatches(override, prefix, filePath)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+		if err := cf.applySettings(&override.ConfigSettings, prefix, config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overrideMatches reports whether one of the override's patterns matches filePath.
+func (cf *ConfigFile) overrideMatches(override *ConfigOverride, prefix, filePath string) (bool, error) {
+	if len(override.Files) == 0 {
+		return false, cf.errorAt(strings.TrimSuffix(prefix, "."), errors.New("files must list at least one pattern"))
+	}
+
+	rel, ok := cf.relativePath(filePath)
+	if !ok {
+		return false, nil
 	}
 
-	if cf.KeywordCase != "" {
-		if err := applyConfigKeywordCase(cf.KeywordCase, config); err != nil {
+	for i, pattern := range override.Files {
+		matched, err := MatchGlob(pattern, rel)
+		if err != nil {
+			return false, cf.errorAt(fmt.Sprintf("%sfiles[%d]", prefix, i), err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// relativePath returns filePath relative to the directory of the config file,
+// or false if the file lies outside of it.
+func (cf *ConfigFile) relativePath(filePath string) (string, bool) {
+	baseDir := "."
+	if cf.path != "" {
+		baseDir = filepath.Dir(cf.path)
+	}
+
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", false
+	}
+	absFile, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(absBase, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Files returns the config files and presets that make up the configuration,
+// lowest precedence first.
+func (cf *ConfigFile) Files() []string {
+	var files []string
+	for _, base := range cf.bases {
+		files = append(files, base.Files()...)
+	}
+	if cf.path != "" {
+		files = append(files, cf.path)
+	}
+	return files
+}
+
+// Validate checks every setting of the configuration, including all overrides
+// regardless of the files they match, and reports the first invalid one.
+func (cf *ConfigFile) Validate() error {
+	for _, base := range cf.bases {
+		if err := base.Validate(); err != nil {
 			return err
 		}
 	}
+	if err := cf.applySettings(&cf.ConfigSettings, "", NewDefaultConfig()); err != nil {
+		return err
+	}
 
-	if cf.LinesBetweenQueries > 0 {
-		config.LinesBetweenQueries = cf.LinesBetweenQueries
+	for i := range cf.Overrides {
+		override := &cf.Overrides[i]
+		prefix := fmt.Sprintf("overrides[%d].", i)
+
+		if len(override.Files) == 0 {
+			return cf.errorAt(strings.TrimSuffix(prefix, "."), errors.New("files must list at least one pattern"))
+		}
+		for j, pattern := range override.Files {
+			if _, err := MatchGlob(pattern, ""); err != nil {
+				return cf.errorAt(fmt.Sprintf("%sfiles[%d]", prefix, j), err)
+			}
+		}
+		if err := cf.applySettings(&override.ConfigSettings, prefix, NewDefaultConfig()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SettingSources reports for every setting that the configuration sets for
+// filePath where its value comes from, as "file:line" with the override's
+// position appended for settings from overrides. An empty filePath ignores
+// overrides. Keys are the top-level setting names, e.g. "language".
+func (cf *ConfigFile) SettingSources(filePath string) map[string]string {
+	sources := map[string]string{}
+	cf.collectSources(filePath, sources)
+	return sources
+}
+
+func (cf *ConfigFile) collectSources(filePath string, sources map[string]string) {
+	for _, base := range cf.bases {
+		base.collectSources(filePath, sources)
+	}
+
+	cf.collectBlockSources("", sources)
+	if filePath == "" {
+		return
+	}
+	for i := range cf.Overrides {
+		prefix := fmt.Sprintf("overrides[%d].", i)
+		if matched, err := cf.overrideMatches(&cf.Overrides[i], prefix, filePath); err == nil && matched {
+			cf.collectBlockSources(prefix, sources)
+		}
+	}
+}
+
+// collectBlockSources records the settings of the block at prefix.
+func (cf *ConfigFile) collectBlockSources(prefix string, sources map[string]string) {
+	settingKeys := yamlFields(reflect.TypeOf(ConfigSettings{}))
+	for key, line := range cf.lines {
+		name, found := strings.CutPrefix(key, prefix)
+		if !found {
+			continue
+		}
+		if _, ok := settingKeys[name]; !ok {
+			continue
+		}
+		source := fmt.Sprintf("%s:%d", cf.path, line)
+		if prefix != "" {
+			source += " (" + strings.TrimSuffix(prefix, ".") + ")"
+		}
+		sources[name] = source
+	}
+}
+
+// applySettings applies one block of settings. prefix locates the block in the
+// file for error messages.
+func (cf *ConfigFile) applySettings(s *ConfigSettings, prefix string, config *Config) error {
+	if s.Language != "" {
+		if err := applyConfigLanguage(s.Language, config); err != nil {
+			return cf.errorAt(prefix+"language", err)
+		}
+	}
+
+	if s.Indent != "" {
+		config.Indent = s.Indent
+	}
+
+	if s.KeywordCase != "" {
+		if err := applyConfigKeywordCase(s.KeywordCase, config); err != nil {
+			return cf.errorAt(prefix+"keyword_case", err)
+		}
+	}
+
+	if s.LinesBetweenQueries != nil {
+		if *s.LinesBetweenQueries < 0 {
+			return cf.errorAt(prefix+"lines_between_queries", errors.New("must not be negative"))
+		}
+		config.LinesBetweenQueries = *s.LinesBetweenQueries
 	}
 
 	// Apply alignment options (only if explicitly set in config)
-	if cf.AlignColumnNames != nil {
-		config.AlignColumnNames = *cf.AlignColumnNames
+	if s.AlignColumnNames != nil {
+		config.AlignColumnNames = *s.AlignColumnNames
 	}
-	if cf.AlignAssignments != nil {
-		config.AlignAssignments = *cf.AlignAssignments
+	if s.AlignAssignments != nil {
+		config.AlignAssignments = *s.AlignAssignments
 	}
-	if cf.AlignValues != nil {
-		config.AlignValues = *cf.AlignValues
+	if s.AlignValues != nil {
+		config.AlignValues = *s.AlignValues
 	}
 
 	// Apply max line length (only if explicitly set in config)
-	if cf.MaxLineLength != nil {
-		config.MaxLineLength = *cf.MaxLineLength
+	if s.MaxLineLength != nil {
+		if *s.MaxLineLength < 0 {
+			return cf.errorAt(prefix+"max_line_length", errors.New("must not be negative"))
+		}
+		config.MaxLineLength = *s.MaxLineLength
+	}
+
+	if err := cf.applyCommentSettings(s, prefix, config); err != nil {
+		return err
+	}
+
+	if s.JoinIndentStyle != "" {
+		style, err := ParseJoinIndentStyle(s.JoinIndentStyle)
+		if err != nil {
+			return cf.errorAt(prefix+"join_indent_style", err)
+		}
+		config.JoinIndentStyle = style
+	}
+
+	if s.LineEnding != "" {
+		lineEnding, err := ParseLineEnding(s.LineEnding)
+		if err != nil {
+			return cf.errorAt(prefix+"line_ending", err)
+		}
+		config.LineEnding = lineEnding
+	}
+	if s.InsertFinalNewline != nil {
+		config.InsertFinalNewline = *s.InsertFinalNewline
+	}
+
+	if s.Color != nil {
+		colors, err := cf.colorConfig(s, prefix, config.ColorConfig)
+		if err != nil {
+			return err
+		}
+		config.ColorConfig = colors
+	}
+
+	if s.Params != nil {
+		if len(s.Params.Named) > 0 && len(s.Params.Positional) > 0 {
+			return cf.errorAt(prefix+"params", errors.New("named and positional params cannot be combined"))
+		}
+		if s.Params.Positional != nil {
+			config.Params = NewListParams(s.Params.Positional)
+		} else {
+			config.Params = NewMapParams(s.Params.Named)
+		}
+	}
+
+	if s.Tokenizer != nil {
+		tokenizer, err := cf.tokenizerConfig(s, prefix)
+		if err != nil {
+			return err
+		}
+		config.TokenizerConfig = config.TokenizerConfig.Merge(tokenizer)
 	}
 
 	return nil
 }
 
+func (cf *ConfigFile) applyCommentSettings(s *ConfigSettings, prefix string, config *Config) error {
+	if s.PreserveCommentIndent != nil {
+		config.PreserveCommentIndent = *s.PreserveCommentIndent
+	}
+	if s.CommentMinSpacing != nil {
+		if *s.CommentMinSpacing < 0 {
+			return cf.errorAt(prefix+"comment_min_spacing", errors.New("must not be negative"))
+		}
+		config.CommentMinSpacing = *s.CommentMinSpacing
+	}
+	if s.PreserveEmptyLinesBetweenComments != nil {
+		config.PreserveEmptyLinesBetweenComments = *s.PreserveEmptyLinesBetweenComments
+	}
+	return nil
+}
+
+// colorConfig resolves the color names of the color section. Categories that
+// are not listed keep their current colors, or the default colors if none are set.
+func (cf *ConfigFile) colorConfig(s *ConfigSettings, prefix string, current *ColorConfig) (*ColorConfig, error) {
+	colors := NewDefaultColorConfig()
+	if current != nil && !current.Empty() {
+		copied := *current
+		colors = &copied
+	}
+	categories := []struct {
+		key    string
+		names  []string
+		target *[]utils.ANSIFormatOption
+	}{
+		{"reserved_words", s.Color.ReservedWords, &colors.ReservedWordFormatOptions},
+		{"strings", s.Color.Strings, &colors.StringFormatOptions},
+		{"numbers", s.Color.Numbers, &colors.NumberFormatOptions},
+		{"booleans", s.Color.Booleans, &colors.BooleanFormatOptions},
+		{"comments", s.Color.Comments, &colors.CommentFormatOptions},
+		{"function_calls", s.Color.FunctionCalls, &colors.FunctionCallFormatOptions},
+	}
+
+	for _, category := range categories {
+		if category.names == nil {
+			continue
+		}
+		options := make([]utils.ANSIFormatOption, 0, len(category.names))
+		for i, name := range category.names {
+			option, err := utils.ParseANSIFormatOption(name)
+			if err != nil {
+				return nil, cf.errorAt(prefix+fmt.Sprintf("color.%s[%d]", category.key, i), err)
+			}
+			if option != utils.NoFormatting {
+				options = append(options, option)
+			}
+		}
+		*category.target = options
+	}
+
+	return colors, nil
+}
+
+// reservedWordPattern matches the words the tokenizer can use in its reserved
+// word patterns without escaping.
+var reservedWordPattern = regexp.MustCompile(`^\w+( \w+)*$`)
+
+// symbolPattern matches the punctuation that placeholder types and single
+// character parentheses consist of. Word characters would make them match
+// inside identifiers.
+var symbolPattern = regexp.MustCompile(`^[^\w\s]+$`)
+
+// tokenizerConfig validates the tokenizer section. Entries end up in regular
+// expressions, so only values the tokenizer can handle are accepted.
+func (cf *ConfigFile) tokenizerConfig(s *ConfigSettings, prefix string) (*TokenizerConfig, error) {
+	tc := s.Tokenizer
+
+	wordLists := []struct {
+		key   string
+		words []string
+	}{
+		{"reserved_words", tc.ReservedWords},
+		{"reserved_top_level_words", tc.ReservedTopLevelWords},
+		{"reserved_newline_words", tc.ReservedNewlineWords},
+		{"reserved_top_level_words_no_indent", tc.ReservedTopLevelWordsNoIndent},
+	}
+	for _, list := range wordLists {
+		for i, word := range list.words {
+			if !reservedWordPattern.MatchString(word) {
+				return nil, cf.errorAt(prefix+fmt.Sprintf("tokenizer.%s[%d]", list.key, i),
+					fmt.Errorf("invalid reserved word %q: use letters, digits and underscores separated by single spaces", word))
+			}
+		}
+	}
+
+	for i, stringType := range tc.StringTypes {
+		if !core.IsStringType(stringType) {
+			return nil, cf.errorAt(prefix+fmt.Sprintf("tokenizer.string_types[%d]", i),
+				fmt.Errorf("unsupported string type %q", stringType))
+		}
+	}
+
+	// Single characters match anywhere, words only on word boundaries
+	parenLists := []struct {
+		key    string
+		parens []string
+	}{
+		{"open_parens", tc.OpenParens},
+		{"close_parens", tc.CloseParens},
+	}
+	for _, list := range parenLists {
+		for i, paren := range list.parens {
+			valid := (len(paren) == 1 && symbolPattern.MatchString(paren)) ||
+				(len(paren) > 1 && reservedWordPattern.MatchString(paren))
+			if !valid {
+				return nil, cf.errorAt(prefix+fmt.Sprintf("tokenizer.%s[%d]", list.key, i),
+					fmt.Errorf("invalid parenthesis %q: use a punctuation character or a word", paren))
+			}
+		}
+	}
+
+	placeholderLists := []struct {
+		key   string
+		types []string
+	}{
+		{"indexed_placeholder_types", tc.IndexedPlaceholderTypes},
+		{"named_placeholder_types", tc.NamedPlaceholderTypes},
+	}
+	for _, list := range placeholderLists {
+		for i, placeholderType := range list.types {
+			if !symbolPattern.MatchString(placeholderType) {
+				return nil, cf.errorAt(prefix+fmt.Sprintf("tokenizer.%s[%d]", list.key, i),
+					fmt.Errorf("invalid placeholder type %q: use punctuation characters", placeholderType))
+			}
+		}
+	}
+
+	for i, commentType := range tc.LineCommentTypes {
+		if commentType == "" || regexp.QuoteMeta(commentType) != commentType {
+			return nil, cf.errorAt(prefix+fmt.Sprintf("tokenizer.line_comment_types[%d]", i),
+				fmt.Errorf("invalid line comment type %q", commentType))
+		}
+	}
+
+	for i, char := range tc.SpecialWordChars {
+		if len([]rune(char)) != 1 || char == "-" || regexp.QuoteMeta(char) != char {
+			return nil, cf.errorAt(prefix+fmt.Sprintf("tokenizer.special_word_chars[%d]", i),
+				fmt.Errorf("invalid special word character %q", char))
+		}
+	}
+
+	return &TokenizerConfig{
+		ReservedWords:                 tc.ReservedWords,
+		ReservedTopLevelWords:         tc.ReservedTopLevelWords,
+		ReservedNewlineWords:          tc.ReservedNewlineWords,
+		ReservedTopLevelWordsNoIndent: tc.ReservedTopLevelWordsNoIndent,
+		StringTypes:                   tc.StringTypes,
+		OpenParens:                    tc.OpenParens,
+		CloseParens:                   tc.CloseParens,
+		IndexedPlaceholderTypes:       tc.IndexedPlaceholderTypes,
+		NamedPlaceholderTypes:         tc.NamedPlaceholderTypes,
+		LineCommentTypes:              tc.LineCommentTypes,
+		SpecialWordChars:              tc.SpecialWordChars,
+	}, nil
+}
+
+// errorAt wraps err in a ConfigError locating key in the config file.
+func (cf *ConfigFile) errorAt(key string, err error) error {
+	return &ConfigError{File: cf.path, Line: cf.lines[key], Key: key, Err: err}
+}
+
 func applyConfigLanguage(langStr string, config *Config) error {
-	switch strings.ToLower(langStr) {
-	case "sql", "standard":
-		config.Language = StandardSQL
-	case "postgresql", "postgres":
-		config.Language = PostgreSQL
-	case "mysql", "mariadb":
-		config.Language = MySQL
-	case "pl/sql", "plsql", "oracle":
-		config.Language = PLSQL
-	case "db2":
-		config.Language = DB2
-	case "n1ql":
-		config.Language = N1QL
-	case "sqlite":
-		config.Language = SQLite
-	default:
+	lang, err := ParseLanguage(langStr)
+	if err != nil {
 		return fmt.Errorf("unknown language in config: %s", langStr)
 	}
+	config.Language = lang
 	return nil
 }
 
 func applyConfigKeywordCase(kcStr string, config *Config) error {
-	switch strings.ToLower(kcStr) {
-	case "preserve":
-		config.KeywordCase = KeywordCasePreserve
-	case "uppercase":
-		config.KeywordCase = KeywordCaseUppercase
-	case "lowercase":
-		config.KeywordCase = KeywordCaseLowercase
-	case "dialect":
-		config.KeywordCase = KeywordCaseDialect
-	default:
+	keywordCase, err := ParseKeywordCase(kcStr)
+	if err != nil {
 		return fmt.Errorf("unknown keyword_case in config: %s", kcStr)
 	}
+	config.KeywordCase = keywordCase
 	return nil
 }
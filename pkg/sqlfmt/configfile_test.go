@@ -5,7 +5,9 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/utils"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -419,7 +421,7 @@ func TestNoConfigFileFound(t *testing.T) {
 	require.Empty(t, configFile.Language)
 	require.Empty(t, configFile.Indent)
 	require.Empty(t, configFile.KeywordCase)
-	require.Equal(t, 0, configFile.LinesBetweenQueries)
+	require.Nil(t, configFile.LinesBetweenQueries)
 }
 
 // TestEmptyConfigFile tests behavior with empty config file.
@@ -826,3 +828,460 @@ func TestCaseInsensitiveKeywordCaseParsing(t *testing.T) {
 		})
 	}
 }
+
+// TestParseCommentAndJoinOptions tests the comment and JOIN settings.
+func TestParseCommentAndJoinOptions(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte(`preserve_comment_indent: true
+comment_min_spacing: 3
+join_indent_style: root-level
+preserve_empty_lines_between_comments: true`))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+
+	require.True(t, config.PreserveCommentIndent)
+	require.Equal(t, 3, config.CommentMinSpacing)
+	require.Equal(t, JoinIndentRootLevel, config.JoinIndentStyle)
+	require.True(t, config.PreserveEmptyLinesBetweenComments)
+}
+
+// TestParseLineEndingOptions tests the line ending and final newline settings.
+func TestParseLineEndingOptions(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte("line_ending: crlf\ninsert_final_newline: true"))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, LineEndingCRLF, config.LineEnding)
+	require.True(t, config.InsertFinalNewline)
+}
+
+// TestZeroLinesBetweenQueries tests that an explicit zero is not treated as unset.
+func TestZeroLinesBetweenQueries(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte("lines_between_queries: 0"))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, 0, config.LinesBetweenQueries)
+	require.Equal(t, "select\n  1;\nselect\n  2;", Format("select 1; select 2;", config))
+}
+
+// TestConfigFileValidationErrors tests that errors name the offending key and line.
+func TestConfigFileValidationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		key     string
+		message string
+	}{
+		{
+			name:    "unknown top-level key",
+			content: "language: mysql\nlangauge: postgresql",
+			line:    2,
+			key:     "langauge",
+			message: "unknown key",
+		},
+		{
+			name:    "unknown nested key",
+			content: "color:\n  strings: [green]\n  keywords: [red]",
+			line:    3,
+			key:     "color.keywords",
+			message: "unknown key",
+		},
+		{
+			name:    "wrong scalar type",
+			content: "align_values: maybe",
+			line:    1,
+			key:     "align_values",
+			message: "expected a boolean",
+		},
+		{
+			name:    "number instead of string",
+			content: "language: mysql\nindent: 4",
+			line:    2,
+			key:     "indent",
+			message: "expected a string",
+		},
+		{
+			name:    "boolean in string list",
+			content: "params:\n  positional: [\"1\", true]",
+			line:    2,
+			key:     "params.positional[1]",
+			message: "expected a string",
+		},
+		{
+			name:    "scalar instead of list",
+			content: "tokenizer:\n  reserved_words: MERGE",
+			line:    2,
+			key:     "tokenizer.reserved_words",
+			message: "expected a list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfigFile(".sqlfmt.yaml", []byte(tt.content))
+			require.Error(t, err)
+
+			var configErr *ConfigError
+			require.ErrorAs(t, err, &configErr)
+			require.Equal(t, ".sqlfmt.yaml", configErr.File)
+			require.Equal(t, tt.line, configErr.Line)
+			require.Equal(t, tt.key, configErr.Key)
+			require.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+// TestApplyErrorsNameKeyAndLine tests that invalid values are located in the file.
+func TestApplyErrorsNameKeyAndLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"language", "indent: \"  \"\nlanguage: cobol", ".sqlfmtrc:2: language: unknown language"},
+		{"join style", "join_indent_style: sideways", ".sqlfmtrc:1: join_indent_style: unknown join indent style"},
+		{"line ending", "line_ending: cr", ".sqlfmtrc:1: line_ending: unknown line ending: cr"},
+		{"negative spacing", "comment_min_spacing: -1", ".sqlfmtrc:1: comment_min_spacing: must not be negative"},
+		{"color name", "color:\n  strings: [green, mauve]", ".sqlfmtrc:2: color.strings[1]: unknown color: mauve"},
+		{"string type", "tokenizer:\n  string_types: [\"%%\"]", ".sqlfmtrc:2: tokenizer.string_types[0]: unsupported string type"},
+		{"reserved word", "tokenizer:\n  reserved_words: [\"A|B\"]", ".sqlfmtrc:2: tokenizer.reserved_words[0]: invalid reserved word"},
+		{"empty placeholder", "tokenizer:\n  named_placeholder_types: [\"\"]", ".sqlfmtrc:2: tokenizer.named_placeholder_types[0]: invalid placeholder type"},
+		{"word placeholder", "tokenizer:\n  indexed_placeholder_types: [\"p\"]", ".sqlfmtrc:2: tokenizer.indexed_placeholder_types[0]: invalid placeholder type"},
+		{"empty paren", "tokenizer:\n  open_parens: [\"\"]", ".sqlfmtrc:2: tokenizer.open_parens[0]: invalid parenthesis"},
+		{"single letter paren", "tokenizer:\n  close_parens: [\"e\"]", ".sqlfmtrc:2: tokenizer.close_parens[0]: invalid parenthesis"},
+		{"mixed params", "params:\n  named: {a: \"1\"}\n  positional: [\"1\"]", ".sqlfmtrc:1: params: named and positional"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile, err := ParseConfigFile(".sqlfmtrc", []byte(tt.content))
+			require.NoError(t, err)
+
+			err = configFile.ApplyToConfig(NewDefaultConfig())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+// TestParseColorSection tests that listed categories replace the default colors.
+func TestParseColorSection(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte(`color:
+  reserved_words: [bright-red, bold]
+  comments: []
+  numbers: [none]`))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+
+	defaults := NewDefaultColorConfig()
+	require.Equal(t, []utils.ANSIFormatOption{utils.ColorBrightRed, utils.FormatBold},
+		config.ColorConfig.ReservedWordFormatOptions)
+	require.Empty(t, config.ColorConfig.CommentFormatOptions)
+	require.Empty(t, config.ColorConfig.NumberFormatOptions)
+	require.Equal(t, defaults.StringFormatOptions, config.ColorConfig.StringFormatOptions)
+}
+
+// TestParseParamsSection tests named and positional placeholder replacements.
+func TestParseParamsSection(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte("params:\n  named:\n    id: \"42\""))
+	require.NoError(t, err)
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, "SELECT\n  *\nWHERE\n  x = 42", Format("SELECT * WHERE x = @id", config))
+
+	configFile, err = ParseConfigFile(".sqlfmtrc", []byte("params:\n  positional: [\"'a'\", \"'b'\"]"))
+	require.NoError(t, err)
+	config = NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, "SELECT\n  'a',\n  'b'", Format("SELECT ?, ?", config))
+}
+
+// TestParseTokenizerSection tests that tokenizer entries extend the dialect.
+func TestParseTokenizerSection(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte(`tokenizer:
+  reserved_top_level_words: [QUALIFY]
+  line_comment_types: ["//"]`))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, []string{"QUALIFY"}, config.TokenizerConfig.ReservedTopLevelWords)
+
+	result := Format("SELECT a FROM t QUALIFY a > 1 // note", config)
+	require.Equal(t, "SELECT\n  a\nFROM\n  t\nQUALIFY\n  a > 1 // note", result)
+
+	// The dialect's own lists are left untouched
+	require.NotContains(t, NewStandardSQLTokenizerConfig().ReservedTopLevelWords, "QUALIFY")
+}
+
+// TestConfigOverrides tests that matching overrides are applied in order.
+func TestConfigOverrides(t *testing.T) {
+	root := t.TempDir()
+	configFile, err := ParseConfigFile(filepath.Join(root, ".sqlfmt.yaml"), []byte(`language: mysql
+indent: "  "
+overrides:
+  - files: ["services/new/**/*.sql", "*.pg.sql"]
+    language: postgresql
+    max_line_length: 120
+  - files: ["services/new/migrations/"]
+    max_line_length: 0
+    keyword_case: uppercase`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		file          string
+		language      Language
+		maxLineLength int
+		keywordCase   KeywordCase
+	}{
+		{"legacy/report.sql", MySQL, 0, KeywordCasePreserve},
+		{"services/new/api/users.sql", PostgreSQL, 120, KeywordCasePreserve},
+		{"services/new/migrations/001.sql", PostgreSQL, 0, KeywordCaseUppercase},
+		{"legacy/report.pg.sql", PostgreSQL, 120, KeywordCasePreserve},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			config := NewDefaultConfig()
+			require.NoError(t, configFile.ApplyToConfigForPath(config, filepath.Join(root, tt.file)))
+			require.Equal(t, tt.language, config.Language)
+			require.Equal(t, tt.maxLineLength, config.MaxLineLength)
+			require.Equal(t, tt.keywordCase, config.KeywordCase)
+			require.Equal(t, "  ", config.Indent)
+		})
+	}
+
+	// Files outside the config file's directory never match
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfigForPath(config, filepath.Join(t.TempDir(), "x.pg.sql")))
+	require.Equal(t, MySQL, config.Language)
+
+	// ApplyToConfig only applies the base settings
+	config = NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, MySQL, config.Language)
+}
+
+// TestConfigOverrideErrors tests that errors inside overrides are located.
+func TestConfigOverrideErrors(t *testing.T) {
+	_, err := ParseConfigFile(".sqlfmtrc", []byte("overrides:\n  - files: [\"*.sql\"]\n    colour: {}"))
+	require.ErrorContains(t, err, ".sqlfmtrc:3: overrides[0].colour: unknown key")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"missing files", "overrides:\n  - language: mysql", ".sqlfmtrc:2: overrides[0]: files must list at least one pattern"},
+		{"bad pattern", "overrides:\n  - files: [\"[.sql\"]", ".sqlfmtrc:2: overrides[0].files[0]: syntax error in pattern"},
+		{"bad value", "overrides:\n  - files: [\"*.sql\"]\n    language: cobol", ".sqlfmtrc:3: overrides[0].language: unknown language"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile, err := ParseConfigFile(".sqlfmtrc", []byte(tt.content))
+			require.NoError(t, err)
+			err = configFile.ApplyToConfigForPath(NewDefaultConfig(), "query.sql")
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+// newTestRepo creates a temporary directory holding files, which maps
+// slash-separated paths to contents, and returns its path. The directory is
+// marked as a repository root so that config files outside of it are not
+// found.
+func newTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o755))
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+// TestLoadConfigFileForRelativePath tests that a relative path finds the
+// config files of the parent directories of the working directory.
+func TestLoadConfigFileForRelativePath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newTestRepo(t, map[string]string{
+		".sqlfmt.yaml": "language: mysql",
+		"sub/.keep":    "",
+	})
+	chdirTest(t, filepath.Join(root, "sub"))
+
+	configFile, err := LoadConfigFileForPath("x.sql")
+	require.NoError(t, err)
+	require.Equal(t, "mysql", configFile.Language)
+}
+
+// TestConfigCascadeMergesPerKey tests that nearer config files refine farther ones.
+func TestConfigCascadeMergesPerKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newTestRepo(t, map[string]string{
+		".sqlfmt.yaml": `language: postgresql
+indent: "    "
+overrides:
+  - files: ["services/legacy/**"]
+    language: mysql`,
+		"services/.sqlfmt.yaml":        "keyword_case: uppercase\nindent: \"  \"",
+		"services/legacy/.sqlfmt.yaml": "max_line_length: 100",
+	})
+
+	configFile, err := LoadConfigFileForPath(filepath.Join(root, "services", "legacy", "a.sql"))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfigForPath(config, filepath.Join(root, "services", "legacy", "a.sql")))
+	require.Equal(t, MySQL, config.Language)
+	require.Equal(t, "  ", config.Indent)
+	require.Equal(t, KeywordCaseUppercase, config.KeywordCase)
+	require.Equal(t, 100, config.MaxLineLength)
+
+	configFile, err = LoadConfigFileForPath(filepath.Join(root, "b.sql"))
+	require.NoError(t, err)
+	config = NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfigForPath(config, filepath.Join(root, "b.sql")))
+	require.Equal(t, PostgreSQL, config.Language)
+	require.Equal(t, "    ", config.Indent)
+	require.Equal(t, KeywordCasePreserve, config.KeywordCase)
+}
+
+// TestConfigCascadeHomeAndRoot tests that the home config is the base unless a root file stops the search.
+func TestConfigCascadeHomeAndRoot(t *testing.T) {
+	home := newTestRepo(t, map[string]string{".sqlfmtrc": "keyword_case: lowercase\nmax_line_length: 80"})
+	t.Setenv("HOME", home)
+
+	project := newTestRepo(t, map[string]string{
+		".sqlfmt.yaml":     "language: sqlite",
+		"vendor/.sqlfmtrc": "root: true\nlanguage: mysql",
+	})
+
+	configFile, err := LoadConfigFileForPath(filepath.Join(project, "a.sql"))
+	require.NoError(t, err)
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, SQLite, config.Language)
+	require.Equal(t, KeywordCaseLowercase, config.KeywordCase)
+	require.Equal(t, 80, config.MaxLineLength)
+
+	configFile, err = LoadConfigFileForPath(filepath.Join(project, "vendor", "a.sql"))
+	require.NoError(t, err)
+	config = NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, MySQL, config.Language)
+	require.Equal(t, KeywordCasePreserve, config.KeywordCase)
+	require.Equal(t, 0, config.MaxLineLength)
+}
+
+// TestConfigExtends tests extending config files and presets.
+func TestConfigExtends(t *testing.T) {
+	root := newTestRepo(t, map[string]string{
+		"shared/base.yaml": "extends: aligned\nlanguage: postgresql\nindent: \"\\t\"",
+		"app/.sqlfmt.yaml": "extends: ../shared/base.yaml\nindent: \"  \"",
+	})
+
+	configFile, err := ReadConfigFile(filepath.Join(root, "app", ".sqlfmt.yaml"))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, PostgreSQL, config.Language)
+	require.Equal(t, "  ", config.Indent)
+	require.True(t, config.AlignColumnNames)
+	require.True(t, config.AlignValues)
+}
+
+// TestConfigExtendsErrors tests unknown targets and cycles.
+func TestConfigExtendsErrors(t *testing.T) {
+	root := newTestRepo(t, map[string]string{
+		"missing.yaml": "language: mysql\nextends: nowhere.yaml",
+		"a.yaml":       "extends: b.yaml",
+		"b.yaml":       "extends: a.yaml",
+	})
+
+	_, err := ReadConfigFile(filepath.Join(root, "missing.yaml"))
+	require.ErrorContains(t, err, "missing.yaml:2: extends: no preset or config file named \"nowhere.yaml\"")
+
+	_, err = ReadConfigFile(filepath.Join(root, "a.yaml"))
+	require.ErrorContains(t, err, "circular extends")
+
+	_, err = ReadConfigFile(filepath.Join(root, "absent.yaml"))
+	require.ErrorContains(t, err, "failed to read config file")
+}
+
+// TestConfigFileSources tests Files and SettingSources across a cascade.
+func TestConfigFileSources(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newTestRepo(t, map[string]string{
+		".sqlfmt.yaml":     "extends: uppercase\nlanguage: mysql\noverrides:\n  - files: [\"app/*.sql\"]\n    indent: \"\\t\"",
+		"app/.sqlfmt.yaml": "max_line_length: 80\nlanguage: sqlite",
+	})
+	rootConfig := filepath.Join(root, ".sqlfmt.yaml")
+	appConfig := filepath.Join(root, "app", ".sqlfmt.yaml")
+	file := filepath.Join(root, "app", "a.sql")
+
+	configFile, err := LoadConfigFileForPath(file)
+	require.NoError(t, err)
+	require.Equal(t, []string{"preset uppercase", rootConfig, appConfig}, configFile.Files())
+
+	require.Equal(t, map[string]string{
+		"keyword_case":    "preset uppercase:1",
+		"indent":          rootConfig + ":5 (overrides[0])",
+		"language":        appConfig + ":2",
+		"max_line_length": appConfig + ":1",
+	}, configFile.SettingSources(file))
+
+	require.NotContains(t, configFile.SettingSources(""), "indent")
+}
+
+// TestConfigFileValidate tests that Validate checks overrides that match no file.
+func TestConfigFileValidate(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmtrc", []byte("language: mysql\noverrides:\n  - files: [\"x/*.sql\"]\n    keyword_case: shouting"))
+	require.NoError(t, err)
+	require.NoError(t, configFile.ApplyToConfigForPath(NewDefaultConfig(), "y/a.sql"))
+	require.ErrorContains(t, configFile.Validate(), ".sqlfmtrc:4: overrides[0].keyword_case: unknown keyword_case")
+
+	configFile, err = ParseConfigFile(".sqlfmtrc", []byte("overrides:\n  - files: [\"x/[.sql\"]"))
+	require.NoError(t, err)
+	require.ErrorContains(t, configFile.Validate(), ".sqlfmtrc:2: overrides[0].files[0]")
+}
+
+// TestNewConfigSettingsRoundTrip tests that settings written from a Config load back unchanged.
+func TestNewConfigSettingsRoundTrip(t *testing.T) {
+	config := NewDefaultConfig().
+		WithLang(PostgreSQL).
+		WithIndent("\t").
+		WithLinesBetweenQueries(0).
+		WithAlignValues(true).
+		WithParams(NewListParams([]string{"1", "2"})).
+		WithColorConfig(&ColorConfig{StringFormatOptions: []utils.ANSIFormatOption{utils.ColorRed}}).
+		WithTokenizerConfig(&TokenizerConfig{ReservedTopLevelWords: []string{"QUALIFY"}})
+
+	content, err := yaml.Marshal(NewConfigSettings(config))
+	require.NoError(t, err)
+
+	configFile, err := ParseConfigFile("settings", content)
+	require.NoError(t, err)
+	loaded := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(loaded))
+
+	require.Equal(t, config.Language, loaded.Language)
+	require.Equal(t, config.Indent, loaded.Indent)
+	require.Equal(t, 0, loaded.LinesBetweenQueries)
+	require.True(t, loaded.AlignValues)
+	require.Equal(t, config.Params, loaded.Params)
+	require.Equal(t, config.TokenizerConfig, loaded.TokenizerConfig)
+	require.Equal(t, []utils.ANSIFormatOption{utils.ColorRed}, loaded.ColorConfig.StringFormatOptions)
+	require.Empty(t, loaded.ColorConfig.ReservedWordFormatOptions)
+}
@@ -0,0 +1,259 @@
+package sqlfmt
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are the config files looked for in each directory, in order
+// of preference. Only the first one found in a directory is used.
+var configFileNames = []string{
+	".sqlfmtrc",
+	".sqlfmt.yaml",
+	".sqlfmt.yml",
+	".sqlfmt.toml",
+	".sqlfmt.json",
+	"sqlfmt.yaml",
+	"sqlfmt.yml",
+	pyprojectFileName,
+}
+
+// homeConfigFileNames are the config files looked for in the home directory.
+var homeConfigFileNames = []string{".sqlfmtrc", ".sqlfmt.yaml", ".sqlfmt.yml", ".sqlfmt.toml", ".sqlfmt.json"}
+
+// pyprojectFileName is read for its [tool.sqlfmt] table. Without that table
+// the file is not a config file.
+const pyprojectFileName = "pyproject.toml"
+
+// pyprojectSection is the table of pyproject.toml holding the settings.
+var pyprojectSection = []string{"tool", "sqlfmt"}
+
+//go:embed sqlfmt.schema.json
+var configSchema []byte
+
+// ConfigSchema returns the JSON Schema of configuration files, which editors
+// can use to validate and complete settings.
+func ConfigSchema() []byte {
+	return bytes.Clone(configSchema)
+}
+
+// isConfigFile reports whether the file at path holds sqlfmt settings, which
+// is only in question for pyproject.toml. Unreadable and invalid files count
+// as config files so that their errors are reported.
+func isConfigFile(path string) bool {
+	if filepath.Base(path) != pyprojectFileName {
+		return true
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return true
+	}
+	node, err := parseTOMLConfig(content, pyprojectSection)
+	return node != nil || err != nil
+}
+
+// parseConfigDocument parses content in the format indicated by the name of
+// path: TOML for .toml files, the [tool.sqlfmt] table of pyproject.toml, and
+// YAML otherwise, which includes JSON. It returns nil for a document without
+// settings.
+func parseConfigDocument(path string, content []byte) (*yaml.Node, error) {
+	switch {
+	case filepath.Base(path) == pyprojectFileName:
+		return parseTOMLConfig(content, pyprojectSection)
+	case strings.EqualFold(filepath.Ext(path), ".toml"):
+		return parseTOMLConfig(content, nil)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+	// An empty file has no document node
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	return root.Content[0], nil
+}
+
+// parseTOMLConfig converts the TOML table at section into the equivalent YAML
+// node, so that TOML files are checked and decoded like YAML files. Keys keep
+// their line numbers for error messages.
+func parseTOMLConfig(content []byte, section []string) (*yaml.Node, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(content, &doc); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, _ := decodeErr.Position()
+			return nil, fmt.Errorf("toml: line %d: %s", row, strings.TrimPrefix(err.Error(), "toml: "))
+		}
+		return nil, err
+	}
+
+	var value any = doc
+	for _, key := range section {
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		if value, ok = table[key]; !ok {
+			return nil, nil
+		}
+	}
+
+	return tomlNode(value, strings.Join(section, "."), tomlKeyLines(content), 0), nil
+}
+
+// tomlNode builds the YAML node for a decoded TOML value at path.
+func tomlNode(value any, path string, lines map[string]int, line int) *yaml.Node {
+	if keyLine := lines[path]; keyLine > 0 {
+		line = keyLine
+	}
+	scalar := func(tag, text string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: text, Line: line}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: line}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// Keep the order of the document where it is known
+		sort.Slice(keys, func(i, j int) bool {
+			li, lj := lines[joinConfigKey(path, keys[i])], lines[joinConfigKey(path, keys[j])]
+			if li != lj {
+				return li < lj
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			child := tomlNode(v[key], joinConfigKey(path, key), lines, line)
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, Line: child.Line}
+			node.Content = append(node.Content, keyNode, child)
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Line: line}
+		for i, item := range v {
+			node.Content = append(node.Content, tomlNode(item, fmt.Sprintf("%s[%d]", path, i), lines, line))
+		}
+		return node
+	case string:
+		// Quoted like a YAML string, so that "100" is not read as a number
+		node := scalar("!!str", v)
+		node.Style = yaml.DoubleQuotedStyle
+		return node
+	case bool:
+		return scalar("!!bool", strconv.FormatBool(v))
+	case int64:
+		return scalar("!!int", strconv.FormatInt(v, 10))
+	case float64:
+		return scalar("!!float", strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		// Dates and times are not valid for any setting
+		return scalar("!!timestamp", fmt.Sprint(v))
+	}
+}
+
+// tomlKeyLines maps the dotted path of every key in a TOML document, with
+// "[i]" for elements of arrays and array tables, to its line.
+func tomlKeyLines(content []byte) map[string]int {
+	lines := map[string]int{}
+	arrayTables := map[string]int{}
+
+	var p unstable.Parser
+	p.Reset(content)
+
+	lineOf := func(node *unstable.Node) int {
+		if node.Raw.Length == 0 {
+			return 0
+		}
+		return p.Shape(node.Raw).Start.Line
+	}
+
+	// resolve joins key to the table path, selecting the last element of
+	// array tables along the way. Implicitly defined tables get the line of
+	// their first key.
+	resolve := func(table string, key unstable.Iterator, line int) string {
+		path := table
+		for key.Next() {
+			path = joinConfigKey(path, string(key.Node().Data))
+			if n, ok := arrayTables[path]; ok {
+				path = fmt.Sprintf("%s[%d]", path, n-1)
+			}
+			if _, ok := lines[path]; !ok {
+				lines[path] = line
+			}
+		}
+		return path
+	}
+
+	var recordValue func(node *unstable.Node, path string)
+	recordKeyValue := func(node *unstable.Node, table string) {
+		path := resolve(table, node.Key(), lineOf(node))
+		lines[path] = lineOf(node)
+		recordValue(node.Value(), path)
+	}
+	recordValue = func(node *unstable.Node, path string) {
+		children := node.Children()
+		switch node.Kind {
+		case unstable.Array:
+			for i := 0; children.Next(); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				if line := lineOf(children.Node()); line > 0 {
+					lines[itemPath] = line
+				}
+				recordValue(children.Node(), itemPath)
+			}
+		case unstable.InlineTable:
+			for children.Next() {
+				recordKeyValue(children.Node(), path)
+			}
+		}
+	}
+
+	table := ""
+	for p.NextExpression() {
+		expr := p.Expression()
+		switch expr.Kind {
+		case unstable.Table:
+			table = resolve("", expr.Key(), lineOf(expr.Child()))
+			lines[table] = lineOf(expr.Child())
+		case unstable.ArrayTable:
+			var parts []string
+			for key := expr.Key(); key.Next(); {
+				parts = append(parts, string(key.Node().Data))
+			}
+			parent := ""
+			for _, part := range parts[:len(parts)-1] {
+				parent = joinConfigKey(parent, part)
+				if n, ok := arrayTables[parent]; ok {
+					parent = fmt.Sprintf("%s[%d]", parent, n-1)
+				}
+			}
+			array := joinConfigKey(parent, parts[len(parts)-1])
+			if _, ok := lines[array]; !ok {
+				lines[array] = lineOf(expr.Child())
+			}
+			table = fmt.Sprintf("%s[%d]", array, arrayTables[array])
+			arrayTables[array]++
+			lines[table] = lineOf(expr.Child())
+		case unstable.KeyValue:
+			recordKeyValue(expr, table)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,244 @@
+package sqlfmt
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/utils"
+	"github.com/stretchr/testify/require"
+)
+
+const tomlTestConfig = `language = "postgresql"
+keyword_case = "uppercase"
+lines_between_queries = 1
+align_values = true
+
+[color]
+strings = ["red", "bold"]
+
+[params.named]
+tenant = "'acme'"
+
+[tokenizer]
+reserved_words = ["QUALIFY"]
+
+[[overrides]]
+files = ["legacy/**"]
+language = "mysql"
+
+[[overrides]]
+files = ["*.tab.sql"]
+indent = "\t"
+`
+
+// TestTOMLConfigFile tests that TOML files support the same settings as YAML files.
+func TestTOMLConfigFile(t *testing.T) {
+	configFile, err := ParseConfigFile(".sqlfmt.toml", []byte(tomlTestConfig))
+	require.NoError(t, err)
+
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfigForPath(config, "queries/report.tab.sql"))
+	require.Equal(t, PostgreSQL, config.Language)
+	require.Equal(t, KeywordCaseUppercase, config.KeywordCase)
+	require.Equal(t, 1, config.LinesBetweenQueries)
+	require.True(t, config.AlignValues)
+	require.Equal(t, "\t", config.Indent)
+	require.Equal(t, []utils.ANSIFormatOption{utils.ColorRed, utils.FormatBold}, config.ColorConfig.StringFormatOptions)
+	require.Equal(t, map[string]string{"tenant": "'acme'"}, config.Params.MapParams)
+	require.Contains(t, config.TokenizerConfig.ReservedWords, "QUALIFY")
+
+	config = NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfigForPath(config, "legacy/old.sql"))
+	require.Equal(t, MySQL, config.Language)
+
+	require.Equal(t, map[string]string{
+		"language":              ".sqlfmt.toml:1",
+		"keyword_case":          ".sqlfmt.toml:2",
+		"lines_between_queries": ".sqlfmt.toml:3",
+		"align_values":          ".sqlfmt.toml:4",
+		"color":                 ".sqlfmt.toml:6",
+		"params":                ".sqlfmt.toml:9",
+		"tokenizer":             ".sqlfmt.toml:12",
+		"indent":                ".sqlfmt.toml:21 (overrides[1])",
+	}, configFile.SettingSources("x.tab.sql"))
+}
+
+// TestTOMLConfigErrors tests that TOML errors name the line of the offending key.
+func TestTOMLConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "unknown key",
+			content: "language = \"mysql\"\nlangauge = \"mysql\"",
+			err:     ".sqlfmt.toml:2: langauge: unknown key",
+		},
+		{
+			name:    "wrong type",
+			content: "align_values = \"maybe\"",
+			err:     ".sqlfmt.toml:1: align_values: expected a boolean",
+		},
+		{
+			name:    "unknown key in table",
+			content: "[color]\nstrings = [\"red\"]\ncolour = [\"red\"]",
+			err:     ".sqlfmt.toml:3: color.colour: unknown key",
+		},
+		{
+			name:    "invalid value in array table",
+			content: "[[overrides]]\nfiles = [\"*.sql\"]\n\n[[overrides]]\nfiles = [\"*.sql\"]\nkeyword_case = \"shouting\"",
+			err:     ".sqlfmt.toml:6: overrides[1].keyword_case: unknown keyword_case",
+		},
+		{
+			name:    "invalid color in multi-line array",
+			content: "[color]\nstrings = [\n  \"red\",\n  \"mauve\",\n]",
+			err:     ".sqlfmt.toml:4: color.strings[1]: unknown color: mauve",
+		},
+		{
+			name:    "syntax error",
+			content: "language = \"mysql\"\nindent = ",
+			err:     "failed to parse config file .sqlfmt.toml: toml: line 2:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile, err := ParseConfigFile(".sqlfmt.toml", []byte(tt.content))
+			if err == nil {
+				err = configFile.Validate()
+			}
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+// TestJSONConfigFile tests that JSON files are read like YAML files.
+func TestJSONConfigFile(t *testing.T) {
+	content := `{
+	"$schema": "./sqlfmt.schema.json",
+	"language": "mysql",
+	"max_line_length": 100,
+	"color": {
+		"strings": ["green"]
+	},
+	"colour": {}
+}`
+	_, err := ParseConfigFile(".sqlfmt.json", []byte(content))
+	require.EqualError(t, err, ".sqlfmt.json:8: colour: unknown key")
+
+	configFile, err := ParseConfigFile(".sqlfmt.json", []byte(`{
+	"$schema": "./sqlfmt.schema.json",
+	"language": "mysql",
+	"max_line_length": 100,
+	"color": {"strings": ["green"]}
+}`))
+	require.NoError(t, err)
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, MySQL, config.Language)
+	require.Equal(t, 100, config.MaxLineLength)
+	require.Equal(t, []utils.ANSIFormatOption{utils.ColorGreen}, config.ColorConfig.StringFormatOptions)
+}
+
+// TestPyprojectConfigFile tests reading the [tool.sqlfmt] table of pyproject.toml.
+func TestPyprojectConfigFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := newTestRepo(t, map[string]string{
+		"pyproject.toml": `[project]
+name = "analytics"
+
+[tool.black]
+line-length = 100
+
+[tool.sqlfmt]
+language = "postgresql"
+keyword_case = "lowercase"
+
+[[tool.sqlfmt.overrides]]
+files = ["legacy/*.sql"]
+language = "sqlite"
+`,
+		"other/pyproject.toml": "[project]\nname = \"other\"",
+	})
+	pyproject := filepath.Join(root, "pyproject.toml")
+
+	configFile, err := LoadConfigFileForPath(filepath.Join(root, "a.sql"))
+	require.NoError(t, err)
+	require.Equal(t, []string{pyproject}, configFile.Files())
+	config := NewDefaultConfig()
+	require.NoError(t, configFile.ApplyToConfig(config))
+	require.Equal(t, PostgreSQL, config.Language)
+	require.Equal(t, KeywordCaseLowercase, config.KeywordCase)
+
+	require.Equal(t, pyproject+":13 (overrides[0])", configFile.SettingSources(filepath.Join(root, "legacy", "a.sql"))["language"])
+
+	// A pyproject.toml without a [tool.sqlfmt] table is skipped
+	configFile, err = LoadConfigFileForPath(filepath.Join(root, "other", "a.sql"))
+	require.NoError(t, err)
+	require.Equal(t, []string{pyproject}, configFile.Files())
+
+	_, err = ParseConfigFile("pyproject.toml", []byte("[tool.sqlfmt]\nlangauge = \"mysql\""))
+	require.EqualError(t, err, "pyproject.toml:2: langauge: unknown key")
+}
+
+// TestConfigSchema tests that the JSON Schema describes exactly the keys of config files.
+func TestConfigSchema(t *testing.T) {
+	var schema struct {
+		Properties  map[string]json.RawMessage `json:"properties"`
+		Definitions struct {
+			ColorList struct {
+				Items struct {
+					Enum []string `json:"enum"`
+				} `json:"items"`
+			} `json:"colorList"`
+			Override struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"override"`
+		} `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(ConfigSchema(), &schema))
+
+	keys := func(m map[string]json.RawMessage) []string {
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	fieldNames := func(t reflect.Type) []string {
+		names := make([]string, 0)
+		for name := range yamlFields(t) {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	require.Equal(t, fieldNames(reflect.TypeOf(ConfigFile{})), keys(schema.Properties))
+	require.Equal(t, fieldNames(reflect.TypeOf(ConfigOverride{})), keys(schema.Definitions.Override.Properties))
+
+	for _, section := range []struct {
+		name string
+		t    reflect.Type
+	}{
+		{"color", reflect.TypeOf(ColorConfigFile{})},
+		{"params", reflect.TypeOf(ParamsConfigFile{})},
+		{"tokenizer", reflect.TypeOf(TokenizerConfigFile{})},
+	} {
+		var property struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+		require.NoError(t, json.Unmarshal(schema.Properties[section.name], &property))
+		require.Equal(t, fieldNames(section.t), keys(property.Properties), section.name)
+	}
+
+	for _, name := range schema.Definitions.ColorList.Items.Enum {
+		_, err := utils.ParseANSIFormatOption(name)
+		require.NoError(t, err, name)
+	}
+}
@@ -7,6 +7,13 @@ type Formatter interface {
 	Format(query string) string
 }
 
+// MappingFormatter is a Formatter that also reports where the tokens of the
+// formatted query come from.
+type MappingFormatter interface {
+	Formatter
+	FormatWithMappings(query string) (string, []Mapping)
+}
+
 // Language type for SQL dialect identification.
 type Language string
 
@@ -70,6 +77,57 @@ type TokenizerConfig struct {
 	SpecialWordChars              []string
 }
 
+// Merge returns a tokenizer config holding the entries of c followed by the
+// entries of other that c does not already contain. Neither config is
+// modified, so package-level dialect lists stay untouched.
+func (c *TokenizerConfig) Merge(other *TokenizerConfig) *TokenizerConfig {
+	if c == nil {
+		c = &TokenizerConfig{}
+	}
+	if other == nil {
+		other = &TokenizerConfig{}
+	}
+	return &TokenizerConfig{
+		ReservedWords:                 mergeUnique(c.ReservedWords, other.ReservedWords),
+		ReservedTopLevelWords:         mergeUnique(c.ReservedTopLevelWords, other.ReservedTopLevelWords),
+		ReservedNewlineWords:          mergeUnique(c.ReservedNewlineWords, other.ReservedNewlineWords),
+		ReservedTopLevelWordsNoIndent: mergeUnique(c.ReservedTopLevelWordsNoIndent, other.ReservedTopLevelWordsNoIndent),
+		StringTypes:                   mergeUnique(c.StringTypes, other.StringTypes),
+		OpenParens:                    mergeUnique(c.OpenParens, other.OpenParens),
+		CloseParens:                   mergeUnique(c.CloseParens, other.CloseParens),
+		IndexedPlaceholderTypes:       mergeUnique(c.IndexedPlaceholderTypes, other.IndexedPlaceholderTypes),
+		NamedPlaceholderTypes:         mergeUnique(c.NamedPlaceholderTypes, other.NamedPlaceholderTypes),
+		LineCommentTypes:              mergeUnique(c.LineCommentTypes, other.LineCommentTypes),
+		SpecialWordChars:              mergeUnique(c.SpecialWordChars, other.SpecialWordChars),
+	}
+}
+
+// Empty reports whether the config has no entries at all.
+func (c *TokenizerConfig) Empty() bool {
+	return c == nil ||
+		len(c.ReservedWords)+len(c.ReservedTopLevelWords)+len(c.ReservedNewlineWords)+
+			len(c.ReservedTopLevelWordsNoIndent)+len(c.StringTypes)+len(c.OpenParens)+
+			len(c.CloseParens)+len(c.IndexedPlaceholderTypes)+len(c.NamedPlaceholderTypes)+
+			len(c.LineCommentTypes)+len(c.SpecialWordChars) == 0
+}
+
+func mergeUnique(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	merged := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, s := range list {
+			if !seen[s] {
+				seen[s] = true
+				merged = append(merged, s)
+			}
+		}
+	}
+	return merged
+}
+
 // ColorConfig represents color formatting configuration.
 type ColorConfig struct {
 	ReservedWordFormatOptions []utils.ANSIFormatOption
@@ -3,6 +3,7 @@ package core
 import (
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/utils"
@@ -58,6 +59,8 @@ type formatter struct {
 	blockStack []string
 	// Procedural block tracking (for BEGIN/END depth)
 	proceduralDepth int
+	// Source mapping, only recorded if mappings is not nil
+	mappings []Mapping
 }
 
 // newFormatter creates a new formatter instance.
@@ -112,6 +115,9 @@ func (f *formatter) format(query string) string {
 	}
 
 	formattedQuery := f.getFormattedQueryFromTokens()
+	if f.mappings != nil {
+		f.finishMappings(formattedQuery)
+	}
 	return strings.TrimSpace(formattedQuery)
 }
 
@@ -366,12 +372,40 @@ func FormatQuery(
 	return formatter.format(query)
 }
 
+// Mapping links a token of a formatted query to the token of the input it was
+// formatted from. Offsets and lengths are in bytes. The lengths differ when
+// the formatter changes a token, e.g. when it collapses the whitespace in
+// "LEFT   JOIN" or substitutes a placeholder.
+type Mapping struct {
+	Output       int
+	OutputLength int
+	Input        int
+	InputLength  int
+}
+
+// FormatQueryWithMappings formats a query like FormatQuery and also returns
+// a mapping for every token of the formatted query except whitespace, in
+// output order.
+func FormatQueryWithMappings(
+	cfg *Config,
+	tokenOverride func(tok types.Token, previousReservedWord types.Token) types.Token,
+	query string,
+) (string, []Mapping) {
+	tokenizer := newTokenizer(cfg.TokenizerConfig)
+	formatter := newFormatter(cfg, tokenizer, tokenOverride)
+	formatter.mappings = []Mapping{}
+	formatted := formatter.format(query)
+	return formatted, formatter.mappings
+}
+
 // getFormattedQueryFromTokens processes the types.Tokens to create a formatted query.
 func (f *formatter) getFormattedQueryFromTokens() string {
 	formattedQuery := &strings.Builder{}
+	inputOffset := 0
 
 	for i, tok := range f.tokens {
 		f.index = i
+		inputLength := len(tok.Value)
 
 		if f.tokenOverride != nil {
 			tok = f.tokenOverride(tok, f.previousReservedWord)
@@ -382,7 +416,16 @@ func (f *formatter) getFormattedQueryFromTokens() string {
 			f.trackEmptyLinesBetweenComments(tok)
 		}
 
-		f.formatToken(tok, formattedQuery)
+		if f.mappings != nil && tok.Type != types.TokenTypeWhitespace {
+			// Formatters only ever trim spaces and tabs off the end before they
+			// write, so the token starts at the first other byte after that
+			written := len(strings.TrimRight(formattedQuery.String(), " \t"))
+			f.formatToken(tok, formattedQuery)
+			f.addMapping(formattedQuery.String(), written, inputOffset, inputLength)
+		} else {
+			f.formatToken(tok, formattedQuery)
+		}
+		inputOffset += inputLength
 
 		// Update previous token type for comment tracking
 		if tok.Type != types.TokenTypeWhitespace {
@@ -392,6 +435,32 @@ func (f *formatter) getFormattedQueryFromTokens() string {
 	return formattedQuery.String()
 }
 
+// addMapping records the token that the last formatter wrote after the
+// offset written of query, if it wrote one.
+func (f *formatter) addMapping(query string, written, input, inputLength int) {
+	start := strings.IndexFunc(query[written:], func(r rune) bool { return !unicode.IsSpace(r) })
+	if start < 0 {
+		return
+	}
+	f.mappings = append(f.mappings, Mapping{Output: written + start, Input: input, InputLength: inputLength})
+}
+
+// finishMappings sets the output lengths of the mappings, each up to the
+// whitespace before the next token, and makes the output offsets relative
+// to the trimmed query.
+func (f *formatter) finishMappings(query string) {
+	leading := len(query) - len(strings.TrimLeftFunc(query, unicode.IsSpace))
+	for i := range f.mappings {
+		end := len(query)
+		if i+1 < len(f.mappings) {
+			end = f.mappings[i+1].Output
+		}
+		m := &f.mappings[i]
+		m.OutputLength = len(strings.TrimRightFunc(query[m.Output:end], unicode.IsSpace))
+		m.Output -= leading
+	}
+}
+
 func (f *formatter) formatToken(tok types.Token, formattedQuery *strings.Builder) {
 	formatters := map[types.TokenType]func(types.Token, *strings.Builder){
 		types.TokenTypeWhitespace:               func(t types.Token, q *strings.Builder) {},
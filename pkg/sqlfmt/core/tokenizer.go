@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
 )
@@ -29,7 +30,54 @@ type tokenizer struct {
 	stringNamedPlaceholderRegex   *regexp.Regexp
 }
 
+// maxCachedTokenizers bounds the number of compiled tokenizers kept by
+// newTokenizer. Programs rarely use more than a few configs.
+const maxCachedTokenizers = 32
+
+// tokenizers caches compiled tokenizers by their config. Compiling the
+// regular expressions of a dialect takes longer than formatting most queries,
+// and tokenizers are never modified, so formatting with the same config again
+// reuses them.
+var tokenizers = struct {
+	sync.Mutex
+	byConfig map[string]*tokenizer
+}{byConfig: map[string]*tokenizer{}}
+
+// newTokenizer returns the tokenizer for cfg, compiling it if it is not cached.
 func newTokenizer(cfg *TokenizerConfig) *tokenizer {
+	tokenizers.Lock()
+	defer tokenizers.Unlock()
+
+	key := cfg.cacheKey()
+	if t, ok := tokenizers.byConfig[key]; ok {
+		return t
+	}
+	if len(tokenizers.byConfig) >= maxCachedTokenizers {
+		clear(tokenizers.byConfig)
+	}
+	t := compileTokenizer(cfg)
+	tokenizers.byConfig[key] = t
+	return t
+}
+
+// cacheKey identifies the tokenizer compiled from c.
+func (c *TokenizerConfig) cacheKey() string {
+	var key strings.Builder
+	for _, list := range [][]string{
+		c.ReservedWords, c.ReservedTopLevelWords, c.ReservedNewlineWords, c.ReservedTopLevelWordsNoIndent,
+		c.StringTypes, c.OpenParens, c.CloseParens, c.IndexedPlaceholderTypes, c.NamedPlaceholderTypes,
+		c.LineCommentTypes, c.SpecialWordChars,
+	} {
+		for _, entry := range list {
+			key.WriteString(entry)
+			key.WriteByte(0)
+		}
+		key.WriteByte(1)
+	}
+	return key.String()
+}
+
+func compileTokenizer(cfg *TokenizerConfig) *tokenizer {
 	regex := `^(!=|<>|<=>|==|<=|>=|=>|!<|!>|\|\||::|->>|->|#>>|#>|<<|>>|` +
 		`\?\||\?&|\?|@>|<@|~~\*|~~|!~~\*|!~~|~\*|!~\*|!~|.)`
 	return &tokenizer{
@@ -62,11 +110,14 @@ func createLineCommentRegex(lineCommentTypes []string) *regexp.Regexp {
 
 func createReservedWordRegex(reservedWords []string) *regexp.Regexp {
 	// Sort reserved words by length in descending order. This is crucial for the tokenizer
-	// to prioritize longer matches, like "DO UPDATE" over "DO".
-	sort.Slice(reservedWords, func(i, j int) bool {
-		return len(reservedWords[i]) > len(reservedWords[j])
+	// to prioritize longer matches, like "DO UPDATE" over "DO". The words may
+	// be the shared list of a dialect, so a copy is sorted.
+	sorted := make([]string, len(reservedWords))
+	copy(sorted, reservedWords)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
 	})
-	pattern := strings.Join(reservedWords, `|`)
+	pattern := strings.Join(sorted, `|`)
 	pattern = strings.ReplaceAll(pattern, " ", `\s+`)
 	return regexp.MustCompile(`(?i)^(` + pattern + `)\b`)
 }
@@ -84,20 +135,28 @@ func createStringRegex(stringTypes []string) *regexp.Regexp {
 	return regexp.MustCompile(pattern)
 }
 
+// stringPatterns maps the supported string types to their regular expressions.
+var stringPatterns = map[string]string{
+	"``":   "((`[^`]*($|`))+)",
+	"[]":   "((\\[[^\\]]*($|\\]))(\\][^\\]]*($|\\]))*)",
+	"\"\"": "((\"[^\"\\\\]*(?:\\\\.[^\"\\\\]*)*(\"|$))+)",
+	"''":   "(('[^'\\\\]*(?:\\\\.[^'\\\\]*)*('|$))+)",
+	"N''":  "((N'[^N'\\\\]*(?:\\\\.[^N'\\\\]*)*('|$))+)",
+	"X''":  "(((?i)[Xx]'[0-9a-fA-F]*($|'))+)", // Hex blob literals
+	"B''":  "(((?i)[Bb]'[01]*($|'))+)",        // Binary literals
+	"$$":   "((\\$\\$[^\\$]*($|\\$\\$))+)",
+}
+
+// IsStringType reports whether t is a string type the tokenizer supports.
+func IsStringType(t string) bool {
+	_, ok := stringPatterns[t]
+	return ok
+}
+
 func createStringPattern(stringTypes []string) string {
-	patterns := map[string]string{
-		"``":   "((`[^`]*($|`))+)",
-		"[]":   "((\\[[^\\]]*($|\\]))(\\][^\\]]*($|\\]))*)",
-		"\"\"": "((\"[^\"\\\\]*(?:\\\\.[^\"\\\\]*)*(\"|$))+)",
-		"''":   "(('[^'\\\\]*(?:\\\\.[^'\\\\]*)*('|$))+)",
-		"N''":  "((N'[^N'\\\\]*(?:\\\\.[^N'\\\\]*)*('|$))+)",
-		"X''":  "(((?i)[Xx]'[0-9a-fA-F]*($|'))+)", // Hex blob literals
-		"B''":  "(((?i)[Bb]'[01]*($|'))+)",        // Binary literals
-		"$$":   "((\\$\\$[^\\$]*($|\\$\\$))+)",
-	}
 	result := make([]string, 0, len(stringTypes))
 	for _, t := range stringTypes {
-		result = append(result, patterns[t])
+		result = append(result, stringPatterns[t])
 	}
 	return strings.Join(result, "|")
 }
@@ -140,6 +199,12 @@ func createPlaceholderRegex(types []string, pattern string) *regexp.Regexp {
 	return regexp.MustCompile(`^((?:` + typesRegex + `)(?:` + pattern + `))`)
 }
 
+// Tokenize splits query into tokens using the given tokenizer config. The
+// values of the tokens add up to query.
+func Tokenize(cfg *TokenizerConfig, query string) []types.Token {
+	return newTokenizer(cfg).tokenize(query)
+}
+
 func (t *tokenizer) tokenize(input string) []types.Token {
 	var (
 		tok  types.Token
@@ -0,0 +1,11 @@
+package sqlfmt
+
+// FormatWithCursor formats query like Format and also maps the byte offset
+// cursor in query to the offset in the result where it belongs, so editors
+// can keep the cursor in place when they format. A cursor inside a token
+// stays at the same place in the token, and a cursor between tokens moves to
+// the end of the token before it. A nil cfg uses the default config.
+func FormatWithCursor(query string, cursor int, cfg *Config) (string, int) {
+	formatted, sourceMap := formatMapped(query, false, cfg)
+	return formatted, sourceMap.OutputOffset(cursor)
+}
@@ -0,0 +1,105 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// formatWithCursorAt formats marked with the cursor marked by "|" and marks
+// the mapped cursor in the result the same way.
+func formatWithCursorAt(marked string, cfg *Config) string {
+	cursor := strings.Index(marked, "|")
+	formatted, mapped := FormatWithCursor(marked[:cursor]+marked[cursor+1:], cursor, cfg)
+	return formatted[:mapped] + "|" + formatted[mapped:]
+}
+
+func TestFormatWithCursor(t *testing.T) {
+	tests := []struct {
+		name     string
+		marked   string
+		cfg      *Config
+		expected string
+	}{
+		{
+			name:     "start of a token",
+			marked:   "select a, |b from t",
+			expected: "select\n  a,\n  |b\nfrom\n  t",
+		},
+		{
+			name:     "inside an identifier",
+			marked:   "select col|umn_name from t",
+			expected: "select\n  col|umn_name\nfrom\n  t",
+		},
+		{
+			name:     "end of a token",
+			marked:   "select a| from t",
+			expected: "select\n  a|\nfrom\n  t",
+		},
+		{
+			name:     "between tokens",
+			marked:   "select a,  |   b from t",
+			expected: "select\n  a,|\n  b\nfrom\n  t",
+		},
+		{
+			name:     "before the first token",
+			marked:   "  | select 1",
+			expected: "|select\n  1",
+		},
+		{
+			name:     "end of the query",
+			marked:   "select 1;  \n|",
+			expected: "select\n  1;|",
+		},
+		{
+			name:     "inside a recased keyword",
+			marked:   "sel|ect 1",
+			cfg:      NewDefaultConfig().WithKeywordCase(KeywordCaseUppercase),
+			expected: "SEL|ECT\n  1",
+		},
+		{
+			name:     "inside a shortened token",
+			marked:   "select a from t left     jo|in u",
+			expected: "select\n  a\nfrom\n  t\n  left join| u",
+		},
+		{
+			name:     "second statement",
+			marked:   "select 1; select |2",
+			expected: "select\n  1;\n\nselect\n  |2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatWithCursorAt(tt.marked, tt.cfg))
+		})
+	}
+}
+
+func TestFormatWithCursorLineEndings(t *testing.T) {
+	query := utf8BOM + "select a,\r\nb from t"
+
+	formatted, cursor := FormatWithCursor(query, strings.Index(query, "b"), nil)
+	assert.Equal(t, utf8BOM+"select\r\n  a,\r\n  b\r\nfrom\r\n  t", formatted)
+	assert.Equal(t, "b", formatted[cursor:cursor+1])
+
+	formatted, cursor = FormatWithCursor(utf8BOM, 0, nil)
+	assert.Empty(t, formatted)
+	assert.Equal(t, 0, cursor)
+}
+
+func TestFormatWithCursorMatchesFormat(t *testing.T) {
+	queries := []string{
+		"select a, b from t where x = ? and y in (1, 2) -- note\norder by a;",
+		"insert into t (a, b) values (1, 'x'), (2, 'y'); update t set a = 1",
+		"SELECT id::text, data->>'name' FROM users LEFT JOIN orders USING (id)",
+	}
+	for _, language := range []Language{StandardSQL, PostgreSQL, MySQL, PLSQL} {
+		for _, query := range queries {
+			cfg := NewDefaultConfig().WithLang(language)
+			formatted, _ := FormatWithCursor(query, 0, cfg)
+			assert.Equal(t, Format(query, cfg), formatted, "%s: %s", language, query)
+		}
+	}
+}
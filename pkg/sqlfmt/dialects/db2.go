@@ -580,14 +580,19 @@ func NewDB2TokenizerConfig() *TokenizerConfig {
 const setKeyword = "SET"
 
 func (ssf *DB2Formatter) Format(query string) string {
-	return core.FormatQuery(
-		ssf.cfg,
-		func(tok types.Token, previousReservedWord types.Token) types.Token {
-			if tok.Type == types.TokenTypeReservedTopLevel && tok.Value == setKeyword && previousReservedWord.Value == "BY" {
-				tok.Type = types.TokenTypeReserved
-			}
-			return tok
-		},
-		query,
-	)
+	return core.FormatQuery(ssf.cfg, ssf.tokenOverride, query)
+}
+
+// FormatWithMappings formats query like Format and also returns where the
+// tokens of the formatted query come from.
+func (ssf *DB2Formatter) FormatWithMappings(query string) (string, []core.Mapping) {
+	return core.FormatQueryWithMappings(ssf.cfg, ssf.tokenOverride, query)
+}
+
+// tokenOverride makes SET after BY an ordinary reserved word.
+func (ssf *DB2Formatter) tokenOverride(tok types.Token, previousReservedWord types.Token) types.Token {
+	if tok.Type == types.TokenTypeReservedTopLevel && tok.Value == setKeyword && previousReservedWord.Value == "BY" {
+		tok.Type = types.TokenTypeReserved
+	}
+	return tok
 }
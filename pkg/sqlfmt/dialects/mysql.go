@@ -132,6 +132,12 @@ func (msf *MySQLFormatter) Format(query string) string {
 	)
 }
 
+// FormatWithMappings formats query like Format and also returns where the
+// tokens of the formatted query come from.
+func (msf *MySQLFormatter) FormatWithMappings(query string) (string, []core.Mapping) {
+	return core.FormatQueryWithMappings(msf.cfg, msf.tokenOverride, query)
+}
+
 // tokenOverride handles MySQL-specific token formatting.
 func (msf *MySQLFormatter) tokenOverride(
 	tok types.Token,
@@ -138,6 +138,12 @@ func (psf *PostgreSQLFormatter) Format(query string) string {
 	)
 }
 
+// FormatWithMappings formats query like Format and also returns where the
+// tokens of the formatted query come from.
+func (psf *PostgreSQLFormatter) FormatWithMappings(query string) (string, []core.Mapping) {
+	return core.FormatQueryWithMappings(psf.cfg, psf.tokenOverride, query)
+}
+
 // tokenOverride handles PostgreSQL-specific token formatting overrides.
 // Implements context-aware formatting for:
 //
@@ -2,6 +2,7 @@ package dialects
 
 import (
 	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/core"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
 )
 
 // Formatter interface is re-exported from core package.
@@ -33,7 +34,27 @@ const (
 )
 
 // CreateFormatterForLanguage creates a formatter based on the language configuration.
+// Entries of the config's TokenizerConfig extend the dialect's tokenizer config.
 func CreateFormatterForLanguage(c *Config) Formatter {
+	custom := c.TokenizerConfig
+	formatter := newFormatterForLanguage(c)
+
+	// The dialect constructors install their own tokenizer config
+	if !custom.Empty() {
+		c.TokenizerConfig = c.TokenizerConfig.Merge(custom)
+	}
+
+	return formatter
+}
+
+// Tokenize splits query into tokens with the tokenizer of the configured
+// language, including the custom entries of the config's TokenizerConfig.
+func Tokenize(c *Config, query string) []types.Token {
+	CreateFormatterForLanguage(c)
+	return core.Tokenize(c.TokenizerConfig, query)
+}
+
+func newFormatterForLanguage(c *Config) Formatter {
 	switch c.Language {
 	case DB2:
 		return NewDB2Formatter(c)
@@ -51,3 +72,13 @@ func CreateFormatterForLanguage(c *Config) Formatter {
 		return NewStandardSQLFormatter(c)
 	}
 }
+
+// FormatWithMappings formats query with the formatter of the configured
+// language and also returns where the tokens of the formatted query come from.
+func FormatWithMappings(c *Config, query string) (string, []core.Mapping) {
+	formatter := CreateFormatterForLanguage(c)
+	if mf, ok := formatter.(core.MappingFormatter); ok {
+		return mf.FormatWithMappings(query)
+	}
+	return formatter.Format(query), nil
+}
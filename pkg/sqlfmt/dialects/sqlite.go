@@ -80,3 +80,9 @@ func (sf *SQLiteFormatter) Format(query string) string {
 		query,
 	)
 }
+
+// FormatWithMappings formats query like Format and also returns where the
+// tokens of the formatted query come from.
+func (sf *SQLiteFormatter) FormatWithMappings(query string) (string, []core.Mapping) {
+	return core.FormatQueryWithMappings(sf.cfg, nil, query)
+}
@@ -83,3 +83,9 @@ func (ssf *StandardSQLFormatter) Format(query string) string {
 		query,
 	)
 }
+
+// FormatWithMappings formats query like Format and also returns where the
+// tokens of the formatted query come from.
+func (ssf *StandardSQLFormatter) FormatWithMappings(query string) (string, []core.Mapping) {
+	return core.FormatQueryWithMappings(ssf.cfg, nil, query)
+}
@@ -0,0 +1,331 @@
+package sqlfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// editorConfigFileName is the name of EditorConfig files.
+const editorConfigFileName = ".editorconfig"
+
+// maxBraceRange limits the expansion of numeric ranges such as {1..10} in
+// EditorConfig section patterns.
+const maxBraceRange = 1000
+
+// EditorConfig holds the EditorConfig properties that apply to a file. They
+// provide defaults for the indentation, line length, line ending and final
+// newline that sqlfmt config files and flags can override.
+type EditorConfig struct {
+	properties map[string]editorConfigProperty
+}
+
+type editorConfigProperty struct {
+	value  string
+	source string // path:line of the property
+}
+
+type editorConfigFile struct {
+	path     string
+	root     bool
+	sections []editorConfigSection
+}
+
+type editorConfigSection struct {
+	pattern    string
+	properties []editorConfigEntry
+}
+
+type editorConfigEntry struct {
+	name, value string
+	line        int
+}
+
+// LoadEditorConfig reads the .editorconfig files from the directory of
+// filePath upwards, stopping at a file with root = true, and collects the
+// properties of the sections matching filePath. Nearer files and later
+// sections take precedence.
+func LoadEditorConfig(filePath string) (*EditorConfig, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*editorConfigFile
+	for dir := filepath.Dir(absPath); ; {
+		path := filepath.Join(dir, editorConfigFileName)
+		if content, err := os.ReadFile(path); err == nil {
+			file := parseEditorConfig(path, content)
+			files = append([]*editorConfigFile{file}, files...)
+			if file.root {
+				break
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	ec := &EditorConfig{properties: map[string]editorConfigProperty{}}
+	for _, file := range files {
+		rel, err := filepath.Rel(filepath.Dir(file.path), absPath)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, section := range file.sections {
+			if !matchEditorConfigPattern(section.pattern, rel) {
+				continue
+			}
+			for _, entry := range section.properties {
+				if entry.value == "unset" {
+					delete(ec.properties, entry.name)
+					continue
+				}
+				ec.properties[entry.name] = editorConfigProperty{
+					value:  entry.value,
+					source: fmt.Sprintf("%s:%d", file.path, entry.line),
+				}
+			}
+		}
+	}
+
+	return ec, nil
+}
+
+// parseEditorConfig parses the INI-style content of an EditorConfig file.
+// Property names and values are case-insensitive and lowercased; lines that
+// cannot be parsed are ignored, as the EditorConfig specification requires.
+func parseEditorConfig(path string, content []byte) *editorConfigFile {
+	file := &editorConfigFile{path: path}
+	var section *editorConfigSection
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			file.sections = append(file.sections, editorConfigSection{pattern: line[1 : len(line)-1]})
+			section = &file.sections[len(file.sections)-1]
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		if section == nil {
+			// Only root is valid before the first section
+			if name == "root" {
+				file.root = value == "true"
+			}
+			continue
+		}
+		section.properties = append(section.properties, editorConfigEntry{name: name, value: value, line: lineNumber})
+	}
+
+	return file
+}
+
+// matchEditorConfigPattern reports whether the slash-separated path name,
+// relative to the directory of the .editorconfig file, matches a section
+// pattern. Patterns follow MatchGlob, extended by {a,b} alternatives, {1..3}
+// numeric ranges and [!...] negated character classes.
+func matchEditorConfigPattern(pattern, name string) bool {
+	for _, alternative := range expandBraces(pattern) {
+		alternative = strings.ReplaceAll(alternative, "[!", "[^")
+		if matched, err := MatchGlob(alternative, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces expands the first {...} group of pattern, recursively, into
+// the list of patterns it stands for. Braces that hold neither alternatives
+// nor a numeric range are kept literally.
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth, end := 0, -1
+	var alternatives []string
+	last := start + 1
+	for i := start; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if end < 0 {
+		return []string{pattern}
+	}
+
+	prefix, body, suffix := pattern[:start], pattern[start+1:end], pattern[end+1:]
+	if alternatives == nil {
+		alternatives = numericRange(body)
+		if alternatives == nil {
+			// Not a group: keep the braces and expand the rest
+			var expanded []string
+			for _, rest := range expandBraces(suffix) {
+				expanded = append(expanded, prefix+"{"+body+"}"+rest)
+			}
+			return expanded
+		}
+	} else {
+		alternatives = append(alternatives, pattern[last:end])
+	}
+
+	var expanded []string
+	for _, alternative := range alternatives {
+		expanded = append(expanded, expandBraces(prefix+alternative+suffix)...)
+	}
+	return expanded
+}
+
+// numericRange expands "n1..n2" into the integers between n1 and n2.
+func numericRange(body string) []string {
+	from, to, ok := strings.Cut(body, "..")
+	if !ok {
+		return nil
+	}
+	n1, err1 := strconv.Atoi(from)
+	n2, err2 := strconv.Atoi(to)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+	if n1 > n2 {
+		n1, n2 = n2, n1
+	}
+	if n2-n1 >= maxBraceRange {
+		return nil
+	}
+
+	numbers := make([]string, 0, n2-n1+1)
+	for n := n1; n <= n2; n++ {
+		numbers = append(numbers, strconv.Itoa(n))
+	}
+	return numbers
+}
+
+// Property returns the value of an EditorConfig property, lowercased.
+func (ec *EditorConfig) Property(name string) (string, bool) {
+	property, ok := ec.properties[name]
+	return property.value, ok
+}
+
+// ApplyToConfig maps indent_style, indent_size, tab_width, end_of_line,
+// insert_final_newline and max_line_length onto config. Unsupported values,
+// such as end_of_line = cr, are ignored.
+func (ec *EditorConfig) ApplyToConfig(config *Config) {
+	for key, value := range ec.settings() {
+		switch key {
+		case "indent":
+			config.Indent = value.(string)
+		case "max_line_length":
+			config.MaxLineLength = value.(int)
+		case "line_ending":
+			config.LineEnding = value.(LineEnding)
+		case "insert_final_newline":
+			config.InsertFinalNewline = value.(bool)
+		}
+	}
+}
+
+// SettingSources maps the sqlfmt settings that ApplyToConfig changes to the
+// .editorconfig file and line of the property that sets them.
+func (ec *EditorConfig) SettingSources() map[string]string {
+	sources := map[string]string{}
+	for key := range ec.settings() {
+		sources[key] = ec.settingSource(key)
+	}
+	return sources
+}
+
+// settings converts the supported properties to sqlfmt settings, keyed by
+// their config file names.
+func (ec *EditorConfig) settings() map[string]any {
+	settings := map[string]any{}
+
+	style, _ := ec.Property("indent_style")
+	size, _ := ec.Property("indent_size")
+	if size == "tab" {
+		size, _ = ec.Property("tab_width")
+	}
+	width, err := strconv.Atoi(size)
+	hasWidth := err == nil && width > 0
+	switch {
+	case style == "tab":
+		settings["indent"] = "\t"
+	case hasWidth && (style == "space" || style == ""):
+		settings["indent"] = strings.Repeat(" ", width)
+	case style == "space":
+		settings["indent"] = DefaultIndent
+	}
+
+	switch value, _ := ec.Property("max_line_length"); value {
+	case "off":
+		settings["max_line_length"] = 0
+	default:
+		if length, err := strconv.Atoi(value); err == nil && length >= 0 {
+			settings["max_line_length"] = length
+		}
+	}
+
+	if value, ok := ec.Property("end_of_line"); ok {
+		if lineEnding, err := ParseLineEnding(value); err == nil {
+			settings["line_ending"] = lineEnding
+		}
+	}
+
+	if value, ok := ec.Property("insert_final_newline"); ok && (value == "true" || value == "false") {
+		settings["insert_final_newline"] = value == "true"
+	}
+
+	return settings
+}
+
+func (ec *EditorConfig) settingSource(key string) string {
+	var names []string
+	switch key {
+	case "indent":
+		names = []string{"indent_style", "indent_size"}
+	case "line_ending":
+		names = []string{"end_of_line"}
+	default:
+		names = []string{key}
+	}
+	for _, name := range names {
+		if property, ok := ec.properties[name]; ok {
+			return property.source
+		}
+	}
+	return ""
+}
@@ -0,0 +1,139 @@
+package sqlfmt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadEditorConfig tests section matching and precedence across .editorconfig files.
+func TestLoadEditorConfig(t *testing.T) {
+	root := newTestRepo(t, map[string]string{
+		".editorconfig": `# top-most EditorConfig file
+root = true
+
+[*]
+indent_style = space
+indent_size = 2
+end_of_line = lf
+
+[*.{sql,ddl}]
+indent_size = 4
+insert_final_newline = true
+
+[migrations/**.sql]
+end_of_line = CRLF
+`,
+		"app/.editorconfig": `[*.sql]
+indent_style = tab
+max_line_length = 100
+
+[legacy_[!x]*.sql]
+max_line_length = unset
+`,
+	})
+	rootConfig := filepath.Join(root, ".editorconfig")
+	appConfig := filepath.Join(root, "app", ".editorconfig")
+
+	ec, err := LoadEditorConfig(filepath.Join(root, "query.sql"))
+	require.NoError(t, err)
+	config := NewDefaultConfig()
+	ec.ApplyToConfig(config)
+	require.Equal(t, "    ", config.Indent)
+	require.True(t, config.InsertFinalNewline)
+	require.Equal(t, LineEndingLF, config.LineEnding)
+	require.Equal(t, 0, config.MaxLineLength)
+
+	ec, err = LoadEditorConfig(filepath.Join(root, "migrations", "001.sql"))
+	require.NoError(t, err)
+	config = NewDefaultConfig()
+	ec.ApplyToConfig(config)
+	require.Equal(t, LineEndingCRLF, config.LineEnding)
+
+	ec, err = LoadEditorConfig(filepath.Join(root, "app", "report.sql"))
+	require.NoError(t, err)
+	config = NewDefaultConfig()
+	ec.ApplyToConfig(config)
+	require.Equal(t, "\t", config.Indent)
+	require.Equal(t, 100, config.MaxLineLength)
+	require.Equal(t, map[string]string{
+		"indent":               appConfig + ":2",
+		"max_line_length":      appConfig + ":3",
+		"line_ending":          rootConfig + ":7",
+		"insert_final_newline": rootConfig + ":11",
+	}, ec.SettingSources())
+
+	ec, err = LoadEditorConfig(filepath.Join(root, "app", "legacy_a.sql"))
+	require.NoError(t, err)
+	_, ok := ec.Property("max_line_length")
+	require.False(t, ok)
+
+	// Files that only match [*] keep the other defaults
+	ec, err = LoadEditorConfig(filepath.Join(root, "notes.txt"))
+	require.NoError(t, err)
+	config = NewDefaultConfig()
+	ec.ApplyToConfig(config)
+	require.Equal(t, NewDefaultConfig().WithLineEnding(LineEndingLF), config.WithIndent("  "))
+}
+
+// TestLoadEditorConfigRoot tests that the search stops at a file with root = true.
+func TestLoadEditorConfigRoot(t *testing.T) {
+	root := newTestRepo(t, map[string]string{
+		".editorconfig":     "[*]\nmax_line_length = 80",
+		"sub/.editorconfig": "root = true\n[*]\nindent_size = 8",
+	})
+
+	ec, err := LoadEditorConfig(filepath.Join(root, "sub", "a.sql"))
+	require.NoError(t, err)
+	_, ok := ec.Property("max_line_length")
+	require.False(t, ok)
+	size, _ := ec.Property("indent_size")
+	require.Equal(t, "8", size)
+
+	ec, err = LoadEditorConfig(filepath.Join(root, "a.sql"))
+	require.NoError(t, err)
+	length, _ := ec.Property("max_line_length")
+	require.Equal(t, "80", length)
+}
+
+func TestEditorConfigIndent(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties string
+		indent     string
+	}{
+		{"tab style", "indent_style = tab\nindent_size = 4", "\t"},
+		{"space style with size", "indent_style = space\nindent_size = 3", "   "},
+		{"space style without size", "indent_style = space", DefaultIndent},
+		{"size only", "indent_size = 4", "    "},
+		{"size from tab width", "indent_style = space\nindent_size = tab\ntab_width = 8", "        "},
+		{"max line length off", "max_line_length = off", DefaultIndent},
+		{"unknown end of line", "end_of_line = cr", DefaultIndent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(root, ".editorconfig"), []byte("root = true\n[*]\n"+tt.properties), 0o644))
+
+			ec, err := LoadEditorConfig(filepath.Join(root, "a.sql"))
+			require.NoError(t, err)
+			config := NewDefaultConfig().WithMaxLineLength(50)
+			ec.ApplyToConfig(config)
+			require.Equal(t, tt.indent, config.Indent)
+			require.Equal(t, DefaultLineEnding, config.LineEnding)
+		})
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	require.Equal(t, []string{"*.sql"}, expandBraces("*.sql"))
+	require.Equal(t, []string{"*.sql", "*.ddl"}, expandBraces("*.{sql,ddl}"))
+	require.Equal(t, []string{"v1.sql", "v2.sql", "v3.sql"}, expandBraces("v{1..3}.sql"))
+	require.Equal(t, []string{"a/x.sql", "a/y.sql", "b/x.sql", "b/y.sql"}, expandBraces("{a,b}/{x,y}.sql"))
+	require.Equal(t, []string{"ab.sql", "ac.sql", "d.sql"}, expandBraces("{a{b,c},d}.sql"))
+	require.Equal(t, []string{"{single}.sql"}, expandBraces("{single}.sql"))
+	require.Equal(t, []string{"{unclosed.sql"}, expandBraces("{unclosed.sql"))
+}
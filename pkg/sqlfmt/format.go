@@ -11,27 +11,134 @@ import (
 
 type Formatter = dialects.Formatter
 
-// Format formats the SQL query according to an optional config.
+// Format formats the SQL query according to an optional config. A byte order
+// mark at the start of query is kept, and the line ending of the config
+// applies; with LineEndingAuto, the line ending of query is kept.
 func Format(query string, cfg ...*Config) string {
-	// Return empty string for empty input
-	if strings.TrimSpace(query) == "" {
-		return ""
-	}
-	return getFormatter(false, cfg...).Format(query)
+	return format(query, false, false, cfg...)
 }
 
 // PrettyFormat formats the SQL query the same as Format but with coloring added.
 func PrettyFormat(query string, cfg ...*Config) string {
+	return format(query, true, false, cfg...)
+}
+
+// FormatDocument formats the content of a SQL file the same as Format, but
+// also keeps a final newline of content. Format trims the output, which suits
+// single queries but not files.
+func FormatDocument(content string, cfg ...*Config) string {
+	return format(content, false, true, cfg...)
+}
+
+// PrettyFormatDocument formats the content of a SQL file the same as
+// FormatDocument but with coloring added.
+func PrettyFormatDocument(content string, cfg ...*Config) string {
+	return format(content, true, true, cfg...)
+}
+
+// PrettyPrint calls PrettyFormat and prints the formatted query.
+func PrettyPrint(query string, cfg ...*Config) {
+	fmt.Println(PrettyFormat(query, cfg...))
+}
+
+// utf8BOM is the byte order mark that some Windows editors write at the start
+// of UTF-8 files.
+const utf8BOM = "\ufeff"
+
+func format(input string, withColor, document bool, cfg ...*Config) string {
+	// The byte order mark is not part of the first token
+	query := strings.TrimPrefix(input, utf8BOM)
+
 	// Return empty string for empty input
 	if strings.TrimSpace(query) == "" {
 		return ""
 	}
-	return getFormatter(true, cfg...).Format(query)
+
+	formatted := getFormatter(withColor, cfg...).Format(query)
+	return finishOutput(input, formatted, document && hasFinalNewline(input), cfg...)
 }
 
-// PrettyPrint calls PrettyFormat and prints the formatted query.
-func PrettyPrint(query string, cfg ...*Config) {
-	fmt.Println(PrettyFormat(query, cfg...))
+// formatMapped formats input like Format, or like FormatDocument if
+// document is set, and also returns the source map of the result.
+func formatMapped(input string, document bool, cfg *Config) (string, SourceMap) {
+	query := strings.TrimPrefix(input, utf8BOM)
+	if strings.TrimSpace(query) == "" {
+		return "", SourceMap{}
+	}
+	if cfg == nil {
+		cfg = NewDefaultConfig()
+	}
+
+	formatted, mappings := dialects.FormatWithMappings(convertToInternalConfig(cfg), query)
+	finished := finishOutput(input, formatted, document && hasFinalNewline(input), cfg)
+
+	// finishOutput adds the byte order mark and may turn "\n" into "\r\n",
+	// which moves the tokens after them
+	bom := len(input) - len(query)
+	i, j := 0, bom
+	shift := func(offset int) int {
+		for ; i < offset; i++ {
+			if formatted[i] == '\n' && finished[j] == '\r' {
+				j++
+			}
+			j++
+		}
+		return j
+	}
+	sourceMap := make(SourceMap, 0, len(mappings))
+	for _, m := range mappings {
+		output := shift(m.Output)
+		sourceMap = append(sourceMap, SourceMapping{
+			Output:       output,
+			OutputLength: shift(m.Output+m.OutputLength) - output,
+			Input:        m.Input + bom,
+			InputLength:  m.InputLength,
+		})
+	}
+	return finished, sourceMap
+}
+
+// finishOutput restores the byte order mark of the input and applies the line
+// ending and final newline of the config to formatted, which the formatters
+// produce with "\n" line breaks and without surrounding whitespace.
+func finishOutput(input, formatted string, finalNewline bool, cfg ...*Config) string {
+	c := NewDefaultConfig()
+	if len(cfg) > 0 && cfg[0] != nil {
+		c = cfg[0]
+	}
+
+	if finalNewline || c.InsertFinalNewline {
+		formatted += "\n"
+	}
+
+	lineEnding := c.LineEnding
+	if lineEnding == LineEndingAuto || lineEnding == "" {
+		lineEnding = DetectLineEnding(input)
+	}
+	if lineEnding == LineEndingCRLF {
+		// Line breaks inside strings and comments may already be CRLF
+		formatted = strings.ReplaceAll(strings.ReplaceAll(formatted, "\r\n", "\n"), "\n", "\r\n")
+	}
+
+	if strings.HasPrefix(input, utf8BOM) {
+		formatted = utf8BOM + formatted
+	}
+	return formatted
+}
+
+// hasFinalNewline reports whether s ends with a line break, ignoring trailing
+// spaces and tabs.
+func hasFinalNewline(s string) bool {
+	return strings.HasSuffix(strings.TrimRight(s, " \t"), "\n")
+}
+
+// DetectLineEnding returns the line ending of the first line break in s, or
+// LineEndingLF if s has none.
+func DetectLineEnding(s string) LineEnding {
+	if i := strings.IndexByte(s, '\n'); i > 0 && s[i-1] == '\r' {
+		return LineEndingCRLF
+	}
+	return LineEndingLF
 }
 
 func getFormatter(forceWithColor bool, cfg ...*Config) Formatter {
@@ -54,23 +161,7 @@ func getFormatter(forceWithColor bool, cfg ...*Config) Formatter {
 
 func createFormatterForLanguage(c *Config) Formatter {
 	// Convert public Config to internal core.Config
-	coreCfg := &core.Config{
-		Language:                          core.Language(c.Language),
-		Indent:                            c.Indent,
-		KeywordCase:                       core.KeywordCase(c.KeywordCase),
-		LinesBetweenQueries:               c.LinesBetweenQueries,
-		Params:                            convertParams(c.Params, c.Language),
-		ColorConfig:                       convertColorConfig(c.ColorConfig),
-		TokenizerConfig:                   convertTokenizerConfig(c.TokenizerConfig),
-		AlignColumnNames:                  c.AlignColumnNames,
-		AlignAssignments:                  c.AlignAssignments,
-		AlignValues:                       c.AlignValues,
-		MaxLineLength:                     c.MaxLineLength,
-		JoinIndentStyle:                   core.JoinIndentStyle(c.JoinIndentStyle),
-		PreserveEmptyLinesBetweenComments: c.PreserveEmptyLinesBetweenComments,
-	}
-
-	return dialects.CreateFormatterForLanguage(coreCfg)
+	return dialects.CreateFormatterForLanguage(convertToInternalConfig(c))
 }
 
 func convertParams(p *Params, language Language) *utils.ParamsConfig {
@@ -782,3 +782,39 @@ func TestColorConstants(t *testing.T) {
 	assert.NotEmpty(t, ColorBrightBlue)
 	assert.NotEmpty(t, ColorBrightCyan)
 }
+
+// TestFormatWithLineEndingAndFinalNewline tests the output line ending and final newline.
+func TestFormatWithLineEndingAndFinalNewline(t *testing.T) {
+	query := "SELECT a FROM t"
+
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t", Format(query, NewDefaultConfig()))
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t\n", Format(query, NewDefaultConfig().WithInsertFinalNewline(true)))
+	assert.Equal(t, "SELECT\r\n  a\r\nFROM\r\n  t", Format(query, NewDefaultConfig().WithLineEnding(LineEndingCRLF)))
+	assert.Equal(t, "SELECT\r\n  a\r\nFROM\r\n  t\r\n",
+		Format(query, NewDefaultConfig().WithLineEnding(LineEndingCRLF).WithInsertFinalNewline(true)))
+
+	// Line breaks inside comments are not doubled
+	assert.Equal(t, "/* a\r\n b */\r\nSELECT\r\n  1",
+		Format("/* a\r\nb */ SELECT 1", NewDefaultConfig().WithLineEnding(LineEndingCRLF)))
+
+	// The default line ending is the one of the input
+	assert.Equal(t, "SELECT\r\n  a\r\nFROM\r\n  t", Format("SELECT a\r\nFROM t"))
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t", Format("SELECT a\r\nFROM t", NewDefaultConfig().WithLineEnding(LineEndingLF)))
+}
+
+// TestFormatByteOrderMark tests that a byte order mark is kept but not formatted.
+func TestFormatByteOrderMark(t *testing.T) {
+	assert.Equal(t, "\ufeffSELECT\n  a\nFROM\n  t", Format("\ufeffSELECT a FROM t"))
+	assert.Equal(t, "\ufeffSELECT\n  a", Format("\ufeff  select a", NewDefaultConfig().WithKeywordCase(KeywordCaseUppercase)))
+	assert.Equal(t, "", Format("\ufeff\n"))
+}
+
+// TestFormatDocument tests that FormatDocument keeps a final newline.
+func TestFormatDocument(t *testing.T) {
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t\n", FormatDocument("SELECT a FROM t\n"))
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t\n", FormatDocument("SELECT a FROM t  \n\n\n"))
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t", FormatDocument("SELECT a FROM t"))
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t\n", FormatDocument("SELECT a FROM t", NewDefaultConfig().WithInsertFinalNewline(true)))
+	assert.Equal(t, "\ufeffSELECT\r\n  a\r\nFROM\r\n  t\r\n", FormatDocument("\ufeffSELECT a\r\nFROM t\r\n"))
+	assert.Equal(t, "SELECT\n  a\nFROM\n  t", Format("SELECT a FROM t\n"))
+}
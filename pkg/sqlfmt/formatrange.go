@@ -0,0 +1,201 @@
+package sqlfmt
+
+import (
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
+)
+
+// span is a range of byte offsets in a query.
+type span struct {
+	start, end int
+}
+
+// FormatRange formats the part of query selected by the byte offsets start
+// and end, for editors that format a selection. The selection is expanded to
+// the innermost parenthesized block that encloses it, or else to the whole
+// statements it touches. That region is formatted at the indentation of the
+// line it starts on.
+//
+// The result is an edit: the returned text replaces the bytes between the
+// returned offsets, and every byte outside of them stays as it is. If the
+// selection touches no statement, the edit is empty. A nil cfg uses the
+// default config.
+func FormatRange(query string, start, end int, cfg *Config) (string, int, int) {
+	start = min(max(start, 0), len(query))
+	end = min(max(end, start), len(query))
+
+	config := NewDefaultConfig()
+	if cfg != nil {
+		config = cfg.Clone()
+	}
+
+	tokens := Tokenize(query, config)
+	region, ok := enclosingBlock(tokens, start, end)
+	if !ok {
+		region, ok = expandToStatements(statementSpans(tokens), start, end)
+	}
+	if !ok {
+		return "", start, start
+	}
+
+	// The region is part of a larger text, so it keeps the line ending of the
+	// whole query and does not get a final newline of its own
+	if config.LineEnding == LineEndingAuto || config.LineEnding == "" {
+		config.WithLineEnding(DetectLineEnding(query))
+	}
+	config.WithInsertFinalNewline(false)
+
+	formatted := Format(query[region.start:region.end], config)
+	return IndentLines(formatted, LineIndentation(query, region.start), config), region.start, region.end
+}
+
+// enclosingBlock returns the innermost pair of parentheses, including the
+// parentheses, that contains the byte range [start, end).
+func enclosingBlock(tokens []Token, start, end int) (span, bool) {
+	var (
+		open  []Token
+		block = span{start: -1}
+	)
+	for _, tok := range tokens {
+		switch {
+		case tok.Type == types.TokenTypeOpenParen && tok.Value == "(":
+			open = append(open, tok)
+		case tok.Type == types.TokenTypeCloseParen && tok.Value == ")" && len(open) > 0:
+			candidate := span{start: open[len(open)-1].Offset, end: tok.Offset + len(tok.Value)}
+			open = open[:len(open)-1]
+
+			// Blocks close innermost first, so the first match is the innermost.
+			// A cursor directly before or after the parentheses is outside.
+			inside := candidate.start <= start && end <= candidate.end &&
+				(start < end || (candidate.start < start && end < candidate.end))
+			if block.start < 0 && inside {
+				block = candidate
+			}
+		}
+	}
+	return block, block.start >= 0
+}
+
+// statementSpans splits a query into statements, each from its first token
+// to its terminating semicolon. Whitespace and comments between statements
+// belong to no statement. Semicolons inside parentheses and BEGIN...END or
+// CASE...END blocks do not end statements.
+func statementSpans(tokens []Token) []span {
+	var (
+		spans   []span
+		current = span{start: -1}
+		depth   int
+		prev    Token
+	)
+	for i, tok := range tokens {
+		if tok.Type == types.TokenTypeWhitespace {
+			continue
+		}
+
+		switch {
+		case tok.Type == types.TokenTypeOpenParen && opensBlock(tok, prev, tokens[i+1:]):
+			depth++
+		case tok.Type == types.TokenTypeCloseParen && (tok.Value == ")" || tok.Value == "]" || tok.Value == "}" ||
+			strings.EqualFold(tok.Value, "END")):
+			depth = max(depth-1, 0)
+		}
+
+		if current.start < 0 && !isCommentToken(tok) {
+			current.start = tok.Offset
+		}
+		if current.start >= 0 {
+			current.end = tok.Offset + len(tok.Value)
+			if depth == 0 && tok.Value == ";" {
+				spans = append(spans, current)
+				current = span{start: -1}
+			}
+		}
+		prev = tok
+	}
+	if current.start >= 0 {
+		spans = append(spans, current)
+	}
+	return spans
+}
+
+// opensBlock reports whether an opening token starts a block that a closing
+// token ends. Only brackets and the keywords that a plain END closes count;
+// BEGIN followed by a semicolon starts a transaction, and CASE directly after
+// END is the end of a CASE statement.
+func opensBlock(tok, prev Token, rest []Token) bool {
+	switch strings.ToUpper(tok.Value) {
+	case "(", "[", "{":
+		return true
+	case "BEGIN":
+		for _, next := range rest {
+			if next.Type != types.TokenTypeWhitespace && !isCommentToken(next) {
+				return next.Value != ";"
+			}
+		}
+		return false
+	case "CASE":
+		return !strings.EqualFold(prev.Value, "END")
+	default:
+		return false
+	}
+}
+
+func isCommentToken(tok Token) bool {
+	return tok.Type == types.TokenTypeLineComment || tok.Type == types.TokenTypeBlockComment
+}
+
+// expandToStatements widens the byte range [start, end) to the statements it
+// overlaps. An empty range selects the statement it is in or touches.
+func expandToStatements(spans []span, start, end int) (span, bool) {
+	expanded := span{start: -1}
+	for _, s := range spans {
+		if s.end < start || s.start > end || (start < end && (s.start == end || s.end == start)) {
+			continue
+		}
+		if expanded.start < 0 {
+			expanded.start = s.start
+		}
+		expanded.end = s.end
+	}
+	return expanded, expanded.start >= 0
+}
+
+// LineIndentation returns the spaces and tabs that start the line of text
+// containing offset, e.g. to format a query at the indentation of the code
+// that embeds it.
+func LineIndentation(text string, offset int) string {
+	lineStart := strings.LastIndexAny(text[:offset], "\r\n") + 1
+	line := text[lineStart:]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// IndentLines prefixes every non-empty line of the formatted query but the
+// first with indent, the first line being placed by the caller. Line breaks
+// inside strings and block comments are left alone, since indenting them
+// would change their content. The dialect of cfg tokenizes formatted; a nil
+// cfg uses the default config.
+func IndentLines(formatted, indent string, cfg *Config) string {
+	if indent == "" || !strings.ContainsAny(formatted, "\r\n") {
+		return formatted
+	}
+
+	var out strings.Builder
+	for _, tok := range Tokenize(formatted, cfg) {
+		if tok.Type != types.TokenTypeWhitespace && tok.Type != types.TokenTypeLineComment {
+			out.WriteString(tok.Value)
+			continue
+		}
+		offset := tok.Offset
+		for _, line := range strings.SplitAfter(tok.Value, "\n") {
+			out.WriteString(line)
+			offset += len(line)
+			// Empty lines stay empty
+			if strings.HasSuffix(line, "\n") && offset < len(formatted) &&
+				formatted[offset] != '\r' && formatted[offset] != '\n' {
+				out.WriteString(indent)
+			}
+		}
+	}
+	return out.String()
+}
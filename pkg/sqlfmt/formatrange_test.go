@@ -0,0 +1,129 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// formatRangeAt formats the selection marked by "[" and "]" in marked, or the
+// cursor marked by "|", and applies the edit.
+func formatRangeAt(marked string, cfg *Config) string {
+	var query string
+	var start, end int
+	if i := strings.Index(marked, "|"); i >= 0 {
+		query = marked[:i] + marked[i+1:]
+		start, end = i, i
+	} else {
+		start = strings.Index(marked, "[")
+		end = strings.Index(marked, "]") - 1
+		query = strings.Replace(strings.Replace(marked, "[", "", 1), "]", "", 1)
+	}
+
+	newText, editStart, editEnd := FormatRange(query, start, end, cfg)
+	return query[:editStart] + newText + query[editEnd:]
+}
+
+func TestFormatRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		marked   string
+		expected string
+	}{
+		{
+			name:     "selection inside a statement",
+			marked:   "select  1;\nselect a [from] t;\nselect  2;\n",
+			expected: "select  1;\nselect\n  a\nfrom\n  t;\nselect  2;\n",
+		},
+		{
+			name:     "selection across statements",
+			marked:   "select  1;\nselect [a;\nselect] b;\nselect  2;\n",
+			expected: "select  1;\nselect\n  a;\n\nselect\n  b;\nselect  2;\n",
+		},
+		{
+			name:     "cursor after a statement",
+			marked:   "select a from t;|\nselect  2;",
+			expected: "select\n  a\nfrom\n  t;\nselect  2;",
+		},
+		{
+			name:     "statement without semicolon",
+			marked:   "select  1;\n\nselect |a from t\n",
+			expected: "select  1;\n\nselect\n  a\nfrom\n  t\n",
+		},
+		{
+			name:     "indented statement",
+			marked:   "select  1;\n    select |a from t;\n",
+			expected: "select  1;\n    select\n      a\n    from\n      t;\n",
+		},
+		{
+			name:   "enclosing parentheses",
+			marked: "SELECT\n  a\nFROM\n  t\nWHERE\n  id IN (select id |from x where y = 1)\n  AND b  =  2",
+			expected: "SELECT\n  a\nFROM\n  t\nWHERE\n  id IN (\n    select\n      id\n    from\n      x\n" +
+				"    where\n      y = 1\n  )\n  AND b  =  2",
+		},
+		{
+			name:     "cursor before parentheses",
+			marked:   "select |(1),  2",
+			expected: "select\n  (1),\n  2",
+		},
+		{
+			name:     "multi-line strings keep their content",
+			marked:   "  select |'a\nb' from t",
+			expected: "  select\n    'a\nb'\n  from\n    t",
+		},
+		{
+			name:     "only whitespace",
+			marked:   "select  1;\n|\nselect  2;",
+			expected: "select  1;\n\nselect  2;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatRangeAt(tt.marked, nil))
+		})
+	}
+}
+
+func TestFormatRangeLineEndingAndConfig(t *testing.T) {
+	cfg := NewDefaultConfig().WithKeywordCase(KeywordCaseUppercase).WithInsertFinalNewline(true)
+	query := "select 1;\r\n  select a from t;\r\n"
+
+	newText, start, end := FormatRange(query, 14, 14, cfg)
+	assert.Equal(t, "SELECT\r\n    a\r\n  FROM\r\n    t;", newText)
+	assert.Equal(t, "select a from t;", query[start:end])
+
+	// The config of the caller is not changed
+	assert.True(t, cfg.InsertFinalNewline)
+	assert.Equal(t, LineEndingAuto, cfg.LineEnding)
+
+	// Offsets out of range are clamped
+	newText, start, end = FormatRange(query, -5, 100, nil)
+	assert.Equal(t, 0, start)
+	assert.Equal(t, len(query)-2, end)
+	assert.Equal(t, "select\r\n  1;\r\n\r\nselect\r\n  a\r\nfrom\r\n  t;", newText)
+}
+
+func TestStatementSpans(t *testing.T) {
+	query := "BEGIN;\nSELECT (1; 2);\n-- c\nCREATE PROCEDURE p() BEGIN SELECT 1; " +
+		"SELECT CASE WHEN a THEN 1 END; END;\nSELECT 3"
+	var got []string
+	for _, s := range statementSpans(Tokenize(query, NewDefaultConfig().WithLang(MySQL))) {
+		got = append(got, query[s.start:s.end])
+	}
+	assert.Equal(t, []string{
+		"BEGIN;",
+		"SELECT (1; 2);",
+		"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT CASE WHEN a THEN 1 END; END;",
+		"SELECT 3",
+	}, got)
+}
+
+func TestIndentLines(t *testing.T) {
+	code := "func f() {\n\tdb.Query(`select`)\n}"
+	assert.Equal(t, "\t", LineIndentation(code, strings.Index(code, "`")))
+	assert.Empty(t, LineIndentation(code, 0))
+
+	formatted := "select\n  'a\nb'\n\nfrom\n  t"
+	assert.Equal(t, "select\n\t  'a\nb'\n\n\tfrom\n\t  t", IndentLines(formatted, "\t", nil))
+}
@@ -0,0 +1,64 @@
+package sqlfmt
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the slash-separated relative path name matches the
+// glob pattern. Segments use path.Match syntax and "**" matches any number of
+// directories. A pattern without a slash matches the last element of name in
+// any directory, and a pattern ending in a slash matches everything below
+// that directory.
+func MatchGlob(pattern, name string) (bool, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	patternSegments := strings.Split(pattern, "/")
+	for _, segment := range patternSegments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false, err
+		}
+	}
+
+	return matchSegments(patternSegments, strings.Split(name, "/"), false), nil
+}
+
+// matchSegments matches path segments against pattern segments. A "**"
+// matches zero or more directories. With insideOnly, a trailing "**" matches
+// everything inside a directory but not the directory itself, as in
+// .gitignore.
+func matchSegments(pattern, name []string, insideOnly bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated "**" and try every possible split
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return !insideOnly || len(name) > 0
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:], insideOnly) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
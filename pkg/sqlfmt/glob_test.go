@@ -0,0 +1,43 @@
+package sqlfmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.sql", "query.sql", true},
+		{"*.sql", "db/migrations/001.sql", true},
+		{"*.sql", "query.txt", false},
+		{"migrations/*.sql", "migrations/001.sql", true},
+		{"migrations/*.sql", "migrations/v1/001.sql", false},
+		{"migrations/*.sql", "db/migrations/001.sql", false},
+		{"migrations/**/*.sql", "migrations/001.sql", true},
+		{"migrations/**/*.sql", "migrations/v1/pg/001.sql", true},
+		{"**/legacy/*.sql", "services/legacy/a.sql", true},
+		{"**/legacy/*.sql", "legacy/a.sql", true},
+		{"services/mysql/", "services/mysql/a/b.sql", true},
+		{"services/mysql/", "services/postgres/a.sql", false},
+		{"./report_?.sql", "report_1.sql", true},
+		{"/report_[0-9].sql", "report_7.sql", true},
+		{"/report_[0-9].sql", "sub/report_7.sql", false},
+		{"services/**", "services/a/b/c.sql", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			matched, err := MatchGlob(tt.pattern, tt.name)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, matched)
+		})
+	}
+
+	_, err := MatchGlob("migrations/[.sql", "migrations/a.sql")
+	require.Error(t, err)
+}
@@ -0,0 +1,387 @@
+// Package gosql formats SQL embedded in the raw string literals of Go source.
+//
+// A literal is taken to be SQL when it is:
+//
+//   - an argument of a query method of database/sql, sqlx or pgx, such as
+//     db.QueryContext(ctx, `select ...`);
+//   - the value of a constant or variable whose name ends in Query or SQL, or
+//     is query or sql;
+//   - preceded by a /* sql */ comment, as in q := /* sql */ `select ...`;
+//   - marked by a //sqlfmt:sql directive on a line of its own before it, at
+//     the end of the line it ends on, or on the declaration group that
+//     contains it.
+//
+// Method and variable names such as Get or searchQuery are used for other
+// text too, so the literals they find must also start like a SQL statement,
+// e.g. with SELECT, WITH or INSERT. Comments mark literals unconditionally.
+//
+// A directive can name the dialect of the literal instead, e.g.
+// //sqlfmt:postgresql or //sqlfmt:standard, and //sqlfmt:ignore leaves a
+// literal alone that would be formatted otherwise. Literals containing fmt
+// verbs or text/template actions outside of SQL strings are never changed,
+// since formatting would break them.
+//
+// Formatted literals that span lines start with a line break, are indented
+// one tab deeper than the line that contains them, and end on a line of their
+// own at the indentation of that line:
+//
+//	rows, err := db.QueryContext(ctx, `
+//		select
+//		  name
+//		from
+//		  users
+//	`)
+package gosql
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
+)
+
+// Options configures Format.
+type Options struct {
+	// Config formats the literals. Nil uses the default config.
+	Config *sqlfmt.Config
+	// Language chooses the dialect of a literal from its content, or reports
+	// false to keep that of Config. Nil uses sqlfmt.ParseInlineDialectHint.
+	// Directives naming a dialect take precedence.
+	Language func(query string) (sqlfmt.Language, bool)
+}
+
+// queryMethods are the methods and functions of database/sql, sqlx and pgx
+// whose first raw string argument is a query.
+var queryMethods = map[string]bool{
+	// database/sql and pgx
+	"Exec": true, "ExecContext": true, "Query": true, "QueryContext": true,
+	"QueryRow": true, "QueryRowContext": true, "Prepare": true, "PrepareContext": true,
+	"Queue": true,
+	// sqlx
+	"Get": true, "GetContext": true, "Select": true, "SelectContext": true,
+	"Queryx": true, "QueryxContext": true, "QueryRowx": true, "QueryRowxContext": true,
+	"MustExec": true, "MustExecContext": true, "NamedExec": true, "NamedExecContext": true,
+	"NamedQuery": true, "NamedQueryContext": true, "Preparex": true, "PreparexContext": true,
+	"PrepareNamed": true, "PrepareNamedContext": true, "Rebind": true,
+}
+
+// directivePrefix starts the comments that mark and configure literals.
+const directivePrefix = "//sqlfmt:"
+
+// Directives that do not name a dialect: sqlDirective only marks a literal
+// as SQL and ignoreDirective leaves it alone.
+const (
+	sqlDirective    = "sql"
+	ignoreDirective = "ignore"
+)
+
+// statementKeywords are the words that SQL statements start with.
+var statementKeywords = map[string]bool{
+	"ALTER": true, "ANALYZE": true, "BEGIN": true, "CALL": true, "COMMENT": true, "COMMIT": true,
+	"COPY": true, "CREATE": true, "DECLARE": true, "DELETE": true, "DESCRIBE": true, "DO": true,
+	"DROP": true, "EXEC": true, "EXECUTE": true, "EXPLAIN": true, "GRANT": true, "INSERT": true,
+	"LOCK": true, "MERGE": true, "PRAGMA": true, "REPLACE": true, "REVOKE": true, "ROLLBACK": true,
+	"SAVEPOINT": true, "SELECT": true, "SET": true, "SHOW": true, "TRUNCATE": true, "UPDATE": true,
+	"UPSERT": true, "USE": true, "VACUUM": true, "VALUES": true, "WITH": true,
+}
+
+// templatePattern matches fmt verbs, such as %s or %[1]d, and the delimiters
+// of text/template actions.
+var templatePattern = regexp.MustCompile(`%[-+#0]*(\[\d+\])?[\d*]*(\.[\d*]*)?[a-zA-Z%]|\{\{|\}\}`)
+
+// literal is a raw string literal that holds SQL.
+type literal struct {
+	lit      *ast.BasicLit
+	language sqlfmt.Language // empty for the language of the options
+	ignored  bool
+	// marked literals are SQL by a comment. Others are only taken to be SQL
+	// if they start like a statement, since method and variable names such
+	// as Get or searchQuery are used for other text too.
+	marked bool
+}
+
+// Format formats the SQL literals of the Go source src and returns the new
+// source, formatted with go/format. If no literal changes, src is returned
+// as it is. The filename is only used in error messages.
+func Format(filename string, src []byte, opts Options) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	literals, err := findLiterals(fset, file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, l := range literals {
+		if l.ignored {
+			continue
+		}
+		start, end := fset.Position(l.lit.Pos()).Offset, fset.Position(l.lit.End()).Offset
+		text, ok := formatLiteral(l, sqlfmt.LineIndentation(string(src), start), opts)
+		if ok && text != l.lit.Value {
+			edits = append(edits, edit{start: start, end: end, text: text})
+		}
+	}
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	// Edits are applied from the end, so that earlier offsets stay valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := bytes.Clone(src)
+	for _, e := range edits {
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	return format.Source(out)
+}
+
+// findLiterals returns the SQL literals of file in source order.
+func findLiterals(fset *token.FileSet, file *ast.File, src []byte) ([]*literal, error) {
+	// Raw string literals by the offset, the line before they start and the
+	// line they end on
+	var (
+		byOffset   = map[int]*ast.BasicLit{}
+		byLineFrom = map[int][]*ast.BasicLit{}
+		byLineTo   = map[int][]*ast.BasicLit{}
+		groups     []*ast.GenDecl
+	)
+	found := map[*ast.BasicLit]*literal{}
+	add := func(expr ast.Expr) *literal {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING || !strings.HasPrefix(lit.Value, "`") {
+			return nil
+		}
+		if found[lit] == nil {
+			found[lit] = &literal{lit: lit}
+		}
+		return found[lit]
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.BasicLit:
+			if node.Kind == token.STRING && strings.HasPrefix(node.Value, "`") {
+				byOffset[fset.Position(node.Pos()).Offset] = node
+				startLine, endLine := fset.Position(node.Pos()).Line, fset.Position(node.End()).Line
+				byLineFrom[startLine-1] = append(byLineFrom[startLine-1], node)
+				byLineTo[endLine] = append(byLineTo[endLine], node)
+			}
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok && queryMethods[sel.Sel.Name] {
+				for _, arg := range node.Args {
+					if add(arg) != nil {
+						break
+					}
+				}
+			}
+		case *ast.GenDecl:
+			if node.Doc != nil && node.Lparen.IsValid() {
+				groups = append(groups, node)
+			}
+		case *ast.ValueSpec:
+			for i, name := range node.Names {
+				if i < len(node.Values) && isQueryName(name.Name) {
+					add(node.Values[i])
+				}
+			}
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				break
+			}
+			for i, lhs := range node.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && isQueryName(ident.Name) {
+					add(node.Rhs[i])
+				}
+			}
+		}
+		return true
+	})
+
+	// Comments mark literals that the code alone does not reveal
+	var directiveErr error
+	apply := func(comment *ast.Comment, lit ast.Expr) {
+		l := add(lit)
+		if l == nil {
+			return
+		}
+		l.marked = true
+		if err := applyDirective(comment.Text, l); err != nil && directiveErr == nil {
+			directiveErr = fmt.Errorf("%s: %w", fset.Position(comment.Pos()), err)
+		}
+	}
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			switch {
+			case strings.HasPrefix(comment.Text, directivePrefix):
+				// A trailing directive belongs to the literal it follows, only a
+				// directive on a line of its own marks the literal below it
+				pos := fset.Position(comment.Pos())
+				lits := byLineTo[pos.Line]
+				lineStart := bytes.LastIndexByte(src[:pos.Offset], '\n') + 1
+				if len(bytes.TrimLeft(src[lineStart:pos.Offset], " \t")) == 0 {
+					lits = byLineFrom[pos.Line]
+				}
+				for _, lit := range lits {
+					apply(comment, lit)
+				}
+			case isSQLComment(comment.Text):
+				end := fset.Position(comment.End()).Offset
+				next := end + len(src[end:]) - len(bytes.TrimLeft(src[end:], " \t\r\n"))
+				if lit := byOffset[next]; lit != nil {
+					add(lit).marked = true
+				}
+			}
+		}
+	}
+	for _, decl := range groups {
+		for _, comment := range decl.Doc.List {
+			if !strings.HasPrefix(comment.Text, directivePrefix) {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					for _, value := range valueSpec.Values {
+						apply(comment, value)
+					}
+				}
+			}
+		}
+	}
+	if directiveErr != nil {
+		return nil, directiveErr
+	}
+
+	literals := make([]*literal, 0, len(found))
+	for _, l := range found {
+		literals = append(literals, l)
+	}
+	sort.Slice(literals, func(i, j int) bool { return literals[i].lit.Pos() < literals[j].lit.Pos() })
+	return literals, nil
+}
+
+// applyDirective applies the //sqlfmt: directive text to l. The directive
+// may name a dialect or ignore the literal.
+func applyDirective(text string, l *literal) error {
+	arg := strings.TrimSpace(strings.TrimPrefix(text, directivePrefix))
+	switch arg {
+	case "":
+		// gofmt would turn the comment into "// sqlfmt:", which is no directive
+		return fmt.Errorf("missing sqlfmt directive, use %s%s", directivePrefix, sqlDirective)
+	case sqlDirective:
+		return nil
+	case ignoreDirective:
+		l.ignored = true
+		return nil
+	}
+
+	lang, err := sqlfmt.ParseLanguage(arg)
+	if err != nil {
+		return fmt.Errorf("invalid sqlfmt directive: %w", err)
+	}
+	l.language = lang
+	return nil
+}
+
+// isQueryName reports whether the name of a constant or variable says that
+// it holds SQL.
+func isQueryName(name string) bool {
+	return name == "query" || name == "sql" ||
+		strings.HasSuffix(name, "Query") || strings.HasSuffix(name, "SQL") || strings.HasSuffix(name, "Sql")
+}
+
+// isSQLComment reports whether a comment is a /* sql */ marker.
+func isSQLComment(text string) bool {
+	if !strings.HasPrefix(text, "/*") {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")), "sql")
+}
+
+// formatLiteral returns the formatted Go literal for l, whose line is indented
+// with indent. It reports false for literals that must not be changed.
+func formatLiteral(l *literal, indent string, opts Options) (string, bool) {
+	query := strings.TrimPrefix(strings.TrimSuffix(l.lit.Value, "`"), "`")
+	if strings.TrimSpace(query) == "" {
+		return "", false
+	}
+
+	config := sqlfmt.NewDefaultConfig()
+	if opts.Config != nil {
+		config = opts.Config.Clone()
+	}
+	switch {
+	case l.language != "":
+		config.WithLang(l.language)
+	case opts.Language != nil:
+		if lang, ok := opts.Language(query); ok {
+			config.WithLang(lang)
+		}
+	default:
+		if lang, ok := sqlfmt.ParseInlineDialectHint(query); ok {
+			config.WithLang(lang)
+		}
+	}
+	// Raw string literals cannot hold carriage returns
+	config.WithLineEnding(sqlfmt.LineEndingLF)
+	config.WithColorConfig(&sqlfmt.ColorConfig{})
+
+	if (!l.marked && !startsStatement(query, config)) || templated(query, config) {
+		return "", false
+	}
+
+	formatted := sqlfmt.Format(query, config)
+	if strings.Contains(formatted, "`") {
+		return "", false
+	}
+	if !strings.Contains(formatted, "\n") {
+		return "`" + formatted + "`", true
+	}
+	inner := indent + "\t"
+	return "`\n" + inner + sqlfmt.IndentLines(formatted, inner, config) + "\n" + indent + "`", true
+}
+
+// startsStatement reports whether the first token of query other than white
+// space, comments and opening parentheses starts a SQL statement.
+func startsStatement(query string, config *sqlfmt.Config) bool {
+	for _, tok := range sqlfmt.Tokenize(query, config) {
+		switch {
+		case tok.Type == types.TokenTypeWhitespace, tok.Type == types.TokenTypeLineComment,
+			tok.Type == types.TokenTypeBlockComment, tok.Type == types.TokenTypeOpenParen && tok.Value == "(":
+			continue
+		}
+		words := strings.Fields(tok.Value)
+		return len(words) > 0 && statementKeywords[strings.ToUpper(words[0])]
+	}
+	return false
+}
+
+// templated reports whether query contains fmt verbs or text/template actions
+// outside of SQL strings and comments.
+func templated(query string, config *sqlfmt.Config) bool {
+	var code strings.Builder
+	for _, tok := range sqlfmt.Tokenize(query, config) {
+		switch tok.Type {
+		case types.TokenTypeString, types.TokenTypeLineComment, types.TokenTypeBlockComment:
+			code.WriteString(" ")
+		default:
+			code.WriteString(tok.Value)
+		}
+	}
+	return templatePattern.MatchString(code.String())
+}
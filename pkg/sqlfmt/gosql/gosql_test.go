@@ -0,0 +1,137 @@
+package gosql
+
+import (
+	"testing"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name: "query method argument",
+			src: "package p\n\nfunc f() {\n\trows, err := db.QueryContext(ctx, `select a from t where id = $1`, id)\n" +
+				"\t_ = db.Exec(\"select 1\")\n}\n",
+			expected: "package p\n\nfunc f() {\n\trows, err := db.QueryContext(ctx, `\n\t\tselect\n\t\t  a\n\t\tfrom\n" +
+				"\t\t  t\n\t\twhere\n\t\t  id = $1\n\t`, id)\n\t_ = db.Exec(\"select 1\")\n}\n",
+		},
+		{
+			name:     "query names",
+			src:      "package p\n\nconst userQuery = `select a from t`\n\nvar insertSQL = `insert into t (a) values (1)`\n",
+			expected: "package p\n\nconst userQuery = `\n\tselect\n\t  a\n\tfrom\n\t  t\n`\n\nvar insertSQL = `\n\tinsert into\n\t  t (a)\n\tvalues\n\t  (1)\n`\n",
+		},
+		{
+			name:     "assignments",
+			src:      "package p\n\nfunc f() {\n\tquery := `select a from t`\n\tname := `select b from u`\n}\n",
+			expected: "package p\n\nfunc f() {\n\tquery := `\n\t\tselect\n\t\t  a\n\t\tfrom\n\t\t  t\n\t`\n\tname := `select b from u`\n}\n",
+		},
+		{
+			name: "query method names on other receivers",
+			src: "package p\n\nfunc f() {\n\tresp, err := http.Get(`https://example.com/a?b=c&d=e`)\n" +
+				"\tdoc.Select(`div > p`)\n}\n",
+			expected: "package p\n\nfunc f() {\n\tresp, err := http.Get(`https://example.com/a?b=c&d=e`)\n" +
+				"\tdoc.Select(`div > p`)\n}\n",
+		},
+		{
+			name:     "query names holding other text",
+			src:      "package p\n\nvar searchQuery = `red shoes -sale`\n\nconst countQuery = `  (select count(*) from t)`\n",
+			expected: "package p\n\nvar searchQuery = `red shoes -sale`\n\nconst countQuery = `\n\t(\n\t  select\n\t    count(*)\n\t  from\n\t    t\n\t)\n`\n",
+		},
+		{
+			name:     "sql comment",
+			src:      "package p\n\nvar q = /* sql */ `select a from t`\n",
+			expected: "package p\n\nvar q = /* sql */ `\n\tselect\n\t  a\n\tfrom\n\t  t\n`\n",
+		},
+		{
+			name:     "directive with dialect",
+			src:      "package p\n\n//sqlfmt:mysql\nconst q = `select a from t where b = #c\n1`\n",
+			expected: "package p\n\n//sqlfmt:mysql\nconst q = `\n\tselect\n\t  a\n\tfrom\n\t  t\n\twhere\n\t  b = #c\n\t  1\n`\n",
+		},
+		{
+			name:     "directive on group",
+			src:      "package p\n\n//sqlfmt:sql\nconst (\n\ta = `select 1, 2`\n\tb = `begin`\n)\n",
+			expected: "package p\n\n//sqlfmt:sql\nconst (\n\ta = `\n\t\tselect\n\t\t  1,\n\t\t  2\n\t`\n\tb = `begin`\n)\n",
+		},
+		{
+			name:     "ignore directive",
+			src:      "package p\n\nconst graphQLQuery = `{ user { id } }` //sqlfmt:ignore\n",
+			expected: "package p\n\nconst graphQLQuery = `{ user { id } }` //sqlfmt:ignore\n",
+		},
+		{
+			name: "trailing directives stay on their line",
+			src: "package p\n\nfunc f() {\n\taQuery := `{ user { id } }` //sqlfmt:ignore\n\tbQuery := `select a from t`\n" +
+				"\tlabel := `ok` //sqlfmt:sql\n\tnote := `a-b`\n}\n",
+			expected: "package p\n\nfunc f() {\n\taQuery := `{ user { id } }` //sqlfmt:ignore\n\tbQuery := `\n\t\tselect\n\t\t  a\n" +
+				"\t\tfrom\n\t\t  t\n\t`\n\tlabel := `ok` //sqlfmt:sql\n\tnote := `a-b`\n}\n",
+		},
+		{
+			name:     "fmt verbs and templates are left alone",
+			src:      "package p\n\nconst listQuery = `select a from %s where b = '%d'`\nconst tmplQuery = `select a from {{.T}}`\n",
+			expected: "package p\n\nconst listQuery = `select a from %s where b = '%d'`\nconst tmplQuery = `select a from {{.T}}`\n",
+		},
+		{
+			name:     "percent signs in SQL strings",
+			src:      "package p\n\nconst likeQuery = `select a from t where b like '%s%'`\n",
+			expected: "package p\n\nconst likeQuery = `\n\tselect\n\t  a\n\tfrom\n\t  t\n\twhere\n\t  b like '%s%'\n`\n",
+		},
+		{
+			name:     "multi-line strings keep their content",
+			src:      "package p\n\nconst q = /* sql */ `select 'a\nb' from t`\n",
+			expected: "package p\n\nconst q = /* sql */ `\n\tselect\n\t  'a\nb'\n\tfrom\n\t  t\n`\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format("p.go", []byte(tt.src), Options{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(got))
+
+			// Formatting is stable
+			again, err := Format("p.go", got, Options{})
+			require.NoError(t, err)
+			assert.Equal(t, string(got), string(again))
+		})
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	src := "package p\n\nconst userQuery = `select a from t`\n"
+	got, err := Format("p.go", []byte(src), Options{
+		Config: sqlfmt.NewDefaultConfig().WithKeywordCase(sqlfmt.KeywordCaseUppercase).WithLineEnding(sqlfmt.LineEndingCRLF),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "package p\n\nconst userQuery = `\n\tSELECT\n\t  a\n\tFROM\n\t  t\n`\n", string(got))
+
+	got, err = Format("p.go", []byte("package p\n\nconst userQuery = `select a from t where b = #c\n1`\n"), Options{
+		Language: func(query string) (sqlfmt.Language, bool) { return sqlfmt.MySQL, true },
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "\t  b = #c\n\t  1\n")
+}
+
+func TestFormatUnchangedSource(t *testing.T) {
+	// Source that is not gofmt-formatted stays as it is without SQL changes
+	src := "package p\nconst  x = 1\n"
+	got, err := Format("p.go", []byte(src), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, src, string(got))
+}
+
+func TestFormatErrors(t *testing.T) {
+	_, err := Format("p.go", []byte("package p\nfunc {"), Options{})
+	assert.Error(t, err)
+
+	_, err = Format("p.go", []byte("package p\n\n//sqlfmt:cobol\nconst q = `select 1`\n"), Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "p.go:3:1: invalid sqlfmt directive: unknown language: cobol")
+
+	_, err = Format("p.go", []byte("package p\n\n//sqlfmt:\nconst q = `select 1`\n"), Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing sqlfmt directive, use //sqlfmt:sql")
+}
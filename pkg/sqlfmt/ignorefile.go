@@ -2,146 +2,203 @@ package sqlfmt
 
 import (
 	"bufio"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
-// IgnoreFile represents a .sqlfmtignore file with patterns to exclude.
+// ignoreFileName is the name of the files listing paths that are not formatted.
+const ignoreFileName = ".sqlfmtignore"
+
+// IgnoreFile decides which paths are excluded by .sqlfmtignore files. The
+// files use .gitignore syntax: patterns are relative to the directory of the
+// file that contains them, nested files add to the patterns of the files
+// above them, and the last matching pattern decides, so that "!" can
+// re-include paths.
 type IgnoreFile struct {
-	patterns []string
+	// rules caches the parsed ignore file of each directory; directories
+	// without one map to nil
+	rules map[string][]ignoreRule
+}
+
+// ignoreRule is one pattern of an ignore file.
+type ignoreRule struct {
+	segments []string // slash-separated path.Match patterns; "**" spans directories
+	negate   bool     // "!" re-includes matching paths
+	dirOnly  bool     // a trailing "/" only matches directories
 }
 
-// LoadIgnoreFile attempts to load .sqlfmtignore from the current directory and parent directories.
+// LoadIgnoreFile loads the .sqlfmtignore files of the current directory and
+// its parents up to the git root. Ignore files in the directories of checked
+// paths below the current directory are loaded as they are needed.
 func LoadIgnoreFile() (*IgnoreFile, error) {
 	dir, err := os.Getwd()
 	if err != nil {
 		return nil, err
 	}
 
-	// Search for .sqlfmtignore in current directory and parents
-	for {
-		ignorePath := filepath.Join(dir, ".sqlfmtignore")
-		if content, err := os.ReadFile(ignorePath); err == nil {
-			var patterns []string
-			scanner := bufio.NewScanner(strings.NewReader(string(content)))
-			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				// Skip empty lines and comments
-				if line == "" || strings.HasPrefix(line, "#") {
-					continue
-				}
-				patterns = append(patterns, line)
-			}
-			return &IgnoreFile{patterns: patterns}, nil
+	ig := &IgnoreFile{rules: map[string][]ignoreRule{}}
+	for _, dir := range ignoreSearchDirs(dir) {
+		if err := ig.load(dir); err != nil {
+			return ig, err
 		}
-
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			break
-		}
-		dir = parent
 	}
-
-	// No ignore file found
-	return &IgnoreFile{}, nil
+	return ig, nil
 }
 
-// ShouldIgnore checks if a file path should be ignored based on the patterns.
+// ShouldIgnore reports whether filePath is excluded by the ignore files in
+// its directory and the directories above it, up to the git root. A path is
+// also excluded when one of its parent directories is, in which case
+// negated patterns cannot re-include it.
 func (ig *IgnoreFile) ShouldIgnore(filePath string) bool {
-	if len(ig.patterns) == 0 {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
 		return false
 	}
 
-	// Convert to relative path for pattern matching
-	relPath, err := filepath.Rel(".", filePath)
-	if err != nil {
-		relPath = filePath
+	dirs := ignoreSearchDirs(filepath.Dir(absPath))
+	top := dirs[len(dirs)-1]
+	rel, err := filepath.Rel(top, absPath)
+	if err != nil || rel == "." {
+		return false
 	}
-
-	// Normalize path separators
-	relPath = filepath.ToSlash(relPath)
-
-	for _, pattern := range ig.patterns {
-		if ig.matchPattern(relPath, pattern) {
+	elements := strings.Split(filepath.ToSlash(rel), "/")
+
+	current := top
+	for i, element := range elements {
+		current = filepath.Join(current, element)
+		isDir := i < len(elements)-1
+		if !isDir {
+			if info, err := os.Stat(current); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if ig.ignored(current, isDir) {
 			return true
 		}
 	}
-
 	return false
 }
 
-// matchPattern checks if a path matches a glob pattern (simplified gitignore-style matching).
-func (ig *IgnoreFile) matchPattern(path, pattern string) bool {
-	// Handle directory patterns (ending with /)
-	if strings.HasSuffix(pattern, "/") {
-		pattern = strings.TrimSuffix(pattern, "/")
-		if strings.HasPrefix(path, pattern+"/") || path == pattern {
-			return true
+// ignored applies the ignore files of the directories above absPath to it.
+func (ig *IgnoreFile) ignored(absPath string, isDir bool) bool {
+	dirs := ignoreSearchDirs(filepath.Dir(absPath))
+
+	ignored := false
+	// Deeper ignore files are applied last and so take precedence
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		_ = ig.load(dir)
+		rel, err := filepath.Rel(dir, absPath)
+		if err != nil {
+			continue
+		}
+		name := strings.Split(filepath.ToSlash(rel), "/")
+		for _, rule := range ig.rules[dir] {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if matchSegments(rule.segments, name, true) {
+				ignored = !rule.negate
+			}
 		}
 	}
+	return ignored
+}
 
-	// Simple glob matching
-	matched, err := filepath.Match(pattern, filepath.Base(path))
-	if err == nil && matched {
-		return true
+// load parses the ignore file of dir unless it has been loaded before.
+func (ig *IgnoreFile) load(dir string) error {
+	if ig.rules == nil {
+		ig.rules = map[string][]ignoreRule{}
 	}
-
-	// Check if pattern matches full path
-	matched, err = filepath.Match(pattern, path)
-	if err == nil && matched {
-		return true
+	if _, ok := ig.rules[dir]; ok {
+		return nil
 	}
+	ig.rules[dir] = nil
 
-	// Handle ** for recursive directory matching
-	if strings.Contains(pattern, "**") {
-		return ig.matchGlobstar(path, pattern)
+	content, err := os.ReadFile(filepath.Join(dir, ignoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", filepath.Join(dir, ignoreFileName), err)
 	}
+	ig.rules[dir] = parseIgnoreRules(string(content))
+	return nil
+}
 
-	return false
+// ignoreSearchDirs lists dir and its parents up to the git root, or up to the
+// file system root outside a git repository, nearest first.
+func ignoreSearchDirs(dir string) []string {
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir || isGitRoot(dir) {
+			return dirs
+		}
+		dir = parent
+	}
 }
 
-// matchGlobstar handles ** patterns for recursive directory matching.
-func (ig *IgnoreFile) matchGlobstar(path, pattern string) bool {
-	parts := strings.Split(pattern, "**")
-	if len(parts) != 2 {
-		return false
+// parseIgnoreRules parses the lines of an ignore file. Invalid patterns are
+// skipped, like git does.
+func parseIgnoreRules(content string) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
 	}
+	return rules
+}
 
-	prefix := parts[0]
-	suffix := parts[1]
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var rule ignoreRule
 
-	// Remove trailing slash from prefix if present
-	prefix = strings.TrimSuffix(prefix, "/")
-	// Remove leading slash from suffix if present
-	suffix = strings.TrimPrefix(suffix, "/")
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are ignored unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	// Skip empty lines and comments
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
 
-	// If prefix is not empty, path must start with prefix/
-	if prefix != "" && !strings.HasPrefix(path, prefix+"/") {
-		return false
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, "\\!"), strings.HasPrefix(line, "\\#"):
+		line = line[1:]
 	}
 
-	// If suffix is empty, just check prefix match
-	if suffix == "" {
-		return prefix == "" || strings.HasPrefix(path, prefix+"/")
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
 	}
 
-	// Calculate remaining path after prefix
-	var remaining string
-	if prefix != "" {
-		remaining = strings.TrimPrefix(path, prefix+"/")
-	} else {
-		remaining = path
+	// A slash at the start or in the middle anchors the pattern to the
+	// directory of the ignore file; otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return rule, false
 	}
 
-	// Check suffix match
-	if strings.Contains(suffix, "/") {
-		// Complex pattern like **/subdir/*.sql
-		matched, err := filepath.Match(suffix, remaining)
-		return err == nil && matched
-	} else {
-		// Simple pattern like **/*.sql
-		matched, err := filepath.Match(suffix, filepath.Base(remaining))
-		return err == nil && matched
+	for _, segment := range strings.Split(line, "/") {
+		segment = strings.ReplaceAll(segment, "[!", "[^")
+		if _, err := path.Match(segment, ""); err != nil {
+			return rule, false
+		}
+		rule.segments = append(rule.segments, segment)
+	}
+	if !anchored {
+		rule.segments = append([]string{"**"}, rule.segments...)
 	}
+	return rule, true
 }
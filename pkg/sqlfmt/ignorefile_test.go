@@ -3,11 +3,21 @@ package sqlfmt
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 )
 
+// chdirTest changes into dir for the rest of the test.
+func chdirTest(t *testing.T, dir string) {
+	t.Helper()
+	oldWd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(oldWd) })
+}
+
 func TestIgnoreFile_LoadIgnoreFile(t *testing.T) {
 	// Create a temporary directory
 	tempDir, err := os.MkdirTemp("", "sqlfmt-ignore-test")
@@ -25,7 +35,7 @@ func TestIgnoreFile_LoadIgnoreFile(t *testing.T) {
 	// Test with no ignore file
 	ignoreFile, err := LoadIgnoreFile()
 	require.NoError(t, err)
-	require.Empty(t, ignoreFile.patterns)
+	require.False(t, ignoreFile.ShouldIgnore("file.tmp"))
 
 	// Create .sqlfmtignore file
 	ignoreContent := `# This is a comment
@@ -39,16 +49,20 @@ dir/**/*.sql
 	// Load ignore file
 	ignoreFile, err = LoadIgnoreFile()
 	require.NoError(t, err)
-	require.Len(t, ignoreFile.patterns, 3)
-	require.Contains(t, ignoreFile.patterns, "*.tmp")
-	require.Contains(t, ignoreFile.patterns, "test/")
-	require.Contains(t, ignoreFile.patterns, "dir/**/*.sql")
+	require.Len(t, ignoreFile.rules[tempDir], 3)
+	require.True(t, ignoreFile.ShouldIgnore("file.tmp"))
+	require.True(t, ignoreFile.ShouldIgnore("test/file.sql"))
+	require.True(t, ignoreFile.ShouldIgnore("dir/sub/file.sql"))
+	require.False(t, ignoreFile.ShouldIgnore("# This is a comment"))
 }
 
 func TestIgnoreFile_ShouldIgnore(t *testing.T) {
-	ignoreFile := &IgnoreFile{
-		patterns: []string{"*.tmp", "test/", "dir/**/*.sql"},
-	}
+	chdirTest(t, newTestRepo(t, map[string]string{
+		".sqlfmtignore": "*.tmp\ntest/\ndir/**/*.sql\n",
+		"dir/.keep":     "",
+	}))
+	ignoreFile, err := LoadIgnoreFile()
+	require.NoError(t, err)
 
 	tests := []struct {
 		filePath string
@@ -73,29 +87,47 @@ func TestIgnoreFile_ShouldIgnore(t *testing.T) {
 }
 
 func TestIgnoreFile_matchPattern(t *testing.T) {
-	ignoreFile := &IgnoreFile{}
-
 	tests := []struct {
 		path     string
 		pattern  string
+		isDir    bool
 		expected bool
 	}{
-		{"file.sql", "*.sql", true},
-		{"file.txt", "*.sql", false},
-		{"test/file.sql", "test/", true},
-		{"test", "test/", true},
-		{"other/file.sql", "test/", false},
-		{"dir/sub/file.sql", "dir/**/*.sql", true},
-		{"dir/file.sql", "dir/**/*.sql", true},
-		{"other/file.sql", "dir/**/*.sql", false},
+		{"file.sql", "*.sql", false, true},
+		{"file.txt", "*.sql", false, false},
+		{"test", "test/", true, true},
+		{"test", "test/", false, false},
+		{"a/test", "test/", true, true},
+		{"other/file.sql", "test/", false, false},
+		{"dir/sub/file.sql", "dir/**/*.sql", false, true},
+		{"dir/file.sql", "dir/**/*.sql", false, true},
+		{"other/file.sql", "dir/**/*.sql", false, false},
+		{"a/dir/file.sql", "dir/*.sql", false, false},
+		{"a/dir/file.sql", "**/dir/*.sql", false, true},
+		{"file.sql", "/file.sql", false, true},
+		{"a/file.sql", "/file.sql", false, false},
+		{"a/file.sql", "file.sql", false, true},
+		{"logs", "logs/**", true, false},
+		{"logs/a/b.sql", "logs/**", false, true},
+		{"v1.sql", "v[!0].sql", false, true},
+		{"v0.sql", "v[!0].sql", false, false},
+		{"#notes.sql", "\\#notes.sql", false, true},
+		{"trailing.sql", "trailing.sql   ", false, true},
 	}
 
 	for _, test := range tests {
 		t.Run(test.path+"_"+test.pattern, func(t *testing.T) {
-			result := ignoreFile.matchPattern(test.path, test.pattern)
+			rule, ok := parseIgnoreRule(test.pattern)
+			require.True(t, ok)
+			result := (!rule.dirOnly || test.isDir) && matchSegments(rule.segments, strings.Split(test.path, "/"), true)
 			require.Equal(t, test.expected, result)
 		})
 	}
+
+	for _, line := range []string{"", "   ", "# comment", "!", "/", "[z-a"} {
+		_, ok := parseIgnoreRule(line)
+		require.False(t, ok, line)
+	}
 }
 
 func TestIgnoreFile_LoadIgnoreFile_InParentDir(t *testing.T) {
@@ -130,10 +162,73 @@ func TestIgnoreFile_LoadIgnoreFile_InParentDir(t *testing.T) {
 	// Load ignore file from child directory (should find parent .sqlfmtignore)
 	ignoreFile, err := LoadIgnoreFile()
 	require.NoError(t, err)
-	require.Len(t, ignoreFile.patterns, 1)
-	require.Equal(t, "*.tmp", ignoreFile.patterns[0])
+	require.Len(t, ignoreFile.rules[parentDir], 1)
 
 	// Test that it correctly ignores files
 	require.True(t, ignoreFile.ShouldIgnore("file.tmp"))
 	require.False(t, ignoreFile.ShouldIgnore("file.sql"))
 }
+
+// TestIgnoreFile_Negation tests that the last matching pattern decides.
+func TestIgnoreFile_Negation(t *testing.T) {
+	chdirTest(t, newTestRepo(t, map[string]string{
+		".sqlfmtignore": "generated/*\n!generated/keep.sql\nbuild/\n!build/keep.sql\n*.gen.sql\n!important.gen.sql\n",
+	}))
+	ignoreFile, err := LoadIgnoreFile()
+	require.NoError(t, err)
+
+	require.True(t, ignoreFile.ShouldIgnore("generated/schema.sql"))
+	require.False(t, ignoreFile.ShouldIgnore("generated/keep.sql"))
+	// Files in an excluded directory cannot be re-included
+	require.True(t, ignoreFile.ShouldIgnore("build/keep.sql"))
+	require.True(t, ignoreFile.ShouldIgnore("queries/report.gen.sql"))
+	require.False(t, ignoreFile.ShouldIgnore("queries/important.gen.sql"))
+	require.False(t, ignoreFile.ShouldIgnore("queries/report.sql"))
+}
+
+// TestIgnoreFile_Nested tests that nested ignore files combine, with patterns
+// anchored to their own directory.
+func TestIgnoreFile_Nested(t *testing.T) {
+	root := newTestRepo(t, map[string]string{
+		".sqlfmtignore":            "*.tmp.sql\n/top.sql\n",
+		"app/.sqlfmtignore":        "/local.sql\nfixtures/\n!keep.tmp.sql\n",
+		"app/sub/.sqlfmtignore":    "*.sql\n",
+		"app/sub/.keep":            "",
+		"other/fixtures/data.sql":  "",
+		"app/fixtures/data.sql":    "",
+		"app/nested/fixtures/a.sq": "",
+	})
+	chdirTest(t, root)
+	ignoreFile, err := LoadIgnoreFile()
+	require.NoError(t, err)
+
+	tests := []struct {
+		filePath string
+		expected bool
+	}{
+		{"top.sql", true},
+		{"app/top.sql", false},
+		{"a.tmp.sql", true},
+		{"app/a.tmp.sql", true},
+		{"app/keep.tmp.sql", false},
+		{"local.sql", false},
+		{"app/local.sql", true},
+		{"app/x/local.sql", false},
+		{"app/fixtures/data.sql", true},
+		{"app/nested/fixtures/a.sq", true},
+		{"other/fixtures/data.sql", false},
+		{"app/sub/query.sql", true},
+		{"app/query.sql", false},
+	}
+	for _, test := range tests {
+		t.Run(test.filePath, func(t *testing.T) {
+			require.Equal(t, test.expected, ignoreFile.ShouldIgnore(test.filePath))
+		})
+	}
+
+	// Absolute paths and paths from other directories resolve the same way
+	require.True(t, ignoreFile.ShouldIgnore(filepath.Join(root, "app", "local.sql")))
+	require.NoError(t, os.Chdir(filepath.Join(root, "app")))
+	require.True(t, ignoreFile.ShouldIgnore("local.sql"))
+	require.True(t, ignoreFile.ShouldIgnore("../top.sql"))
+}
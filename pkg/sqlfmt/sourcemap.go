@@ -0,0 +1,84 @@
+package sqlfmt
+
+// SourceMapping links a token of formatted output to the token of the input
+// it was formatted from. Offsets and lengths are in bytes. The lengths differ
+// when the formatter changes a token, e.g. when it collapses the whitespace
+// in "LEFT   JOIN" or substitutes a placeholder.
+type SourceMapping struct {
+	Output       int `json:"output"`
+	OutputLength int `json:"outputLength"`
+	Input        int `json:"input"`
+	InputLength  int `json:"inputLength"`
+}
+
+// SourceMap links formatted output to its input. It has a mapping for every
+// token except whitespace, in order.
+type SourceMap []SourceMapping
+
+// FormatWithSourceMap formats query like Format and also returns the source
+// map of the result. With a ColorConfig, escape codes count as part of the
+// tokens they color.
+func FormatWithSourceMap(query string, cfg ...*Config) (string, SourceMap) {
+	return formatMapped(query, false, sourceMapConfig(cfg...))
+}
+
+// FormatDocumentWithSourceMap formats content like FormatDocument and also
+// returns the source map of the result.
+func FormatDocumentWithSourceMap(content string, cfg ...*Config) (string, SourceMap) {
+	return formatMapped(content, true, sourceMapConfig(cfg...))
+}
+
+func sourceMapConfig(cfg ...*Config) *Config {
+	if len(cfg) > 1 {
+		panic("cannot have more than one config")
+	}
+	if len(cfg) == 1 {
+		return cfg[0]
+	}
+	return nil
+}
+
+// InputOffset translates a byte offset in the formatted output to the input,
+// e.g. the position of an error that a database reported for the formatted
+// query. An offset inside a token keeps its place in the token, and an offset
+// between tokens translates to the end of the token before it.
+func (m SourceMap) InputOffset(output int) int {
+	return m.translate(output, func(mapping SourceMapping) (span, span) {
+		return span{mapping.Output, mapping.Output + mapping.OutputLength},
+			span{mapping.Input, mapping.Input + mapping.InputLength}
+	})
+}
+
+// OutputOffset translates a byte offset in the input to the formatted output,
+// e.g. the position of a cursor. An offset inside a token keeps its place in
+// the token, and an offset between tokens translates to the end of the token
+// before it.
+func (m SourceMap) OutputOffset(input int) int {
+	return m.translate(input, func(mapping SourceMapping) (span, span) {
+		return span{mapping.Input, mapping.Input + mapping.InputLength},
+			span{mapping.Output, mapping.Output + mapping.OutputLength}
+	})
+}
+
+// translate translates offset with the token spans that spans returns for
+// each mapping, first the one offset is in and then the one to translate to.
+func (m SourceMap) translate(offset int, spans func(SourceMapping) (span, span)) int {
+	if len(m) == 0 {
+		return 0
+	}
+
+	_, first := spans(m[0])
+	translated := first.start
+	for _, mapping := range m {
+		from, to := spans(mapping)
+		if offset < from.start {
+			break
+		}
+		if offset < from.end {
+			// Tokens the formatter shortened keep the offset within them
+			return to.start + min(offset-from.start, to.end-to.start)
+		}
+		translated = to.end
+	}
+	return translated
+}
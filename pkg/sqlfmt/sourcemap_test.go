@@ -0,0 +1,57 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatWithSourceMap(t *testing.T) {
+	query := "select a,   b from t left   join u on a=b -- note\nwhere x = :x;"
+	cfg := NewDefaultConfig().WithKeywordCase(KeywordCaseUppercase)
+
+	formatted, sourceMap := FormatWithSourceMap(query, cfg)
+	assert.Equal(t, Format(query, cfg), formatted)
+
+	var pairs []string
+	for _, m := range sourceMap {
+		pairs = append(pairs,
+			query[m.Input:m.Input+m.InputLength]+"="+formatted[m.Output:m.Output+m.OutputLength])
+	}
+	assert.Equal(t, []string{
+		"select=SELECT", "a=a", ",=,", "b=b", "from=FROM", "t=t", "left   join=LEFT JOIN", "u=u",
+		"on=ON", "a=a", "===", "b=b", "-- note\n=-- note", "where=WHERE", "x=x", "===", ":x=:x", ";=;",
+	}, pairs)
+}
+
+func TestFormatDocumentWithSourceMap(t *testing.T) {
+	content := utf8BOM + "select 'a\r\nb', c\r\nfrom t;\r\n"
+
+	formatted, sourceMap := FormatDocumentWithSourceMap(content)
+	assert.Equal(t, FormatDocument(content), formatted)
+	require.NotEmpty(t, sourceMap)
+	for _, m := range sourceMap {
+		assert.Equal(t, content[m.Input:m.Input+m.InputLength], formatted[m.Output:m.Output+m.OutputLength])
+	}
+
+	formatted, sourceMap = FormatWithSourceMap("  \n")
+	assert.Empty(t, formatted)
+	assert.NotNil(t, sourceMap)
+}
+
+func TestSourceMapOffsets(t *testing.T) {
+	query := "select id,\n  name from users where id = $1"
+	formatted, sourceMap := FormatWithSourceMap(query, NewDefaultConfig().WithLang(PostgreSQL))
+
+	// A database error at "users" in the formatted query
+	assert.Equal(t, strings.Index(query, "users"), sourceMap.InputOffset(strings.Index(formatted, "users")))
+	assert.Equal(t, strings.Index(formatted, "sers"), sourceMap.OutputOffset(strings.Index(query, "sers")))
+
+	// Offsets between tokens go to the end of the token before them
+	assert.Equal(t, strings.Index(query, " from"), sourceMap.InputOffset(strings.Index(formatted, "\nfrom")))
+	assert.Equal(t, len(query), sourceMap.InputOffset(len(formatted)))
+	assert.Equal(t, 0, sourceMap.InputOffset(0))
+	assert.Equal(t, 0, SourceMap{}.OutputOffset(5))
+}
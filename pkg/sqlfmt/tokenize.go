@@ -0,0 +1,112 @@
+package sqlfmt
+
+import (
+	"strings"
+
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/dialects"
+	"github.com/MeKo-Christian/go-sqlfmt/pkg/sqlfmt/types"
+)
+
+// TokenType classifies tokens, e.g. "reserved-top-level" or "string".
+type TokenType = types.TokenType
+
+// Token is a token of a SQL query as the formatter sees it.
+type Token struct {
+	Type   TokenType `json:"type"`
+	Value  string    `json:"value"`
+	Offset int       `json:"offset"` // byte offset of Value in the query
+}
+
+// Diagnostic describes a problem in a SQL query. Start and End are byte
+// offsets in the query.
+type Diagnostic struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Message string `json:"message"`
+}
+
+// Tokenize splits query into the tokens of the dialect of an optional config.
+// Whitespace and comments are tokens too, so the values add up to query,
+// except for a leading byte order mark.
+func Tokenize(query string, cfg ...*Config) []Token {
+	var c *Config
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+
+	trimmed := strings.TrimPrefix(query, utf8BOM)
+	offset := len(query) - len(trimmed)
+
+	internal := dialects.Tokenize(convertToInternalConfig(c), trimmed)
+	tokens := make([]Token, 0, len(internal))
+	for _, tok := range internal {
+		tokens = append(tokens, Token{Type: tok.Type, Value: tok.Value, Offset: offset})
+		offset += len(tok.Value)
+	}
+	return tokens
+}
+
+// Diagnose reports the problems the tokenizer finds in query: unterminated
+// strings and block comments, and unbalanced parentheses. The formatter
+// still formats such queries, but usually not as intended.
+func Diagnose(query string, cfg ...*Config) []Diagnostic {
+	var (
+		diagnostics []Diagnostic
+		open        []Token
+	)
+	for _, tok := range Tokenize(query, cfg...) {
+		end := tok.Offset + len(tok.Value)
+		switch {
+		case tok.Type == types.TokenTypeString && !isTerminatedString(tok.Value):
+			diagnostics = append(diagnostics, Diagnostic{tok.Offset, end, "unterminated quoted string"})
+		case tok.Type == types.TokenTypeBlockComment &&
+			(len(tok.Value) < len("/**/") || !strings.HasSuffix(tok.Value, "*/")):
+			diagnostics = append(diagnostics, Diagnostic{tok.Offset, end, "unterminated block comment"})
+		case tok.Type == types.TokenTypeOpenParen && tok.Value == "(":
+			open = append(open, tok)
+		case tok.Type == types.TokenTypeCloseParen && tok.Value == ")":
+			if len(open) == 0 {
+				diagnostics = append(diagnostics, Diagnostic{tok.Offset, end, "unmatched closing parenthesis"})
+			} else {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	for _, tok := range open {
+		diagnostics = append(diagnostics, Diagnostic{tok.Offset, tok.Offset + 1, "unclosed parenthesis"})
+	}
+	return diagnostics
+}
+
+// isTerminatedString reports whether a string token ends with the delimiter
+// that opens it. The tokenizer lets unterminated strings run to the end of
+// the input.
+func isTerminatedString(value string) bool {
+	if strings.HasPrefix(value, "$") {
+		tag := value[:strings.IndexByte(value[1:], '$')+2]
+		return len(value) >= 2*len(tag) && strings.HasSuffix(value, tag)
+	}
+
+	// N'', X'' and B'' strings
+	if len(value) > 1 && value[1] == '\'' && strings.ContainsRune("NnXxBb", rune(value[0])) {
+		value = value[1:]
+	}
+	if len(value) < 2 {
+		return false
+	}
+
+	closing := value[0]
+	if closing == '[' {
+		closing = ']'
+	}
+	if value[len(value)-1] != closing {
+		return false
+	}
+
+	// Backslashes escape quotes, so the closing quote must not be escaped
+	backslashes := 0
+	for i := len(value) - 2; i > 0 && value[i] == '\\'; i-- {
+		backslashes++
+	}
+	return closing == '`' || closing == ']' || backslashes%2 == 0
+}
@@ -0,0 +1,54 @@
+package sqlfmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenize(t *testing.T) {
+	query := utf8BOM + "SELECT a, 'x' FROM t;"
+	tokens := Tokenize(query)
+
+	var values strings.Builder
+	values.WriteString(utf8BOM)
+	for _, tok := range tokens {
+		assert.Equal(t, tok.Value, query[tok.Offset:tok.Offset+len(tok.Value)])
+		values.WriteString(tok.Value)
+	}
+	assert.Equal(t, query, values.String())
+
+	require.NotEmpty(t, tokens)
+	assert.Equal(t, Token{Type: "reserved-top-level", Value: "SELECT", Offset: len(utf8BOM)}, tokens[0])
+	assert.Contains(t, tokens, Token{Type: "string", Value: "'x'", Offset: len(utf8BOM) + 10})
+
+	// The dialect decides how input is split
+	assert.Equal(t, TokenType("string"), Tokenize("$$body$$", NewDefaultConfig().WithLang(PostgreSQL))[0].Type)
+}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		lang     Language
+		expected []Diagnostic
+	}{
+		{"valid", "SELECT (a + 'b') FROM t /* c */", StandardSQL, nil},
+		{"escaped quote", `SELECT 'it\'s'`, StandardSQL, nil},
+		{"unterminated string", "SELECT 'abc", StandardSQL, []Diagnostic{{7, 11, "unterminated quoted string"}}},
+		{"escaped closing quote", `SELECT 'abc\'`, StandardSQL, []Diagnostic{{7, 13, "unterminated quoted string"}}},
+		{"unterminated identifier", "SELECT `abc", MySQL, []Diagnostic{{7, 11, "unterminated quoted string"}}},
+		{"unterminated dollar quote", "SELECT $fn$ abc $", PostgreSQL, []Diagnostic{{7, 17, "unterminated quoted string"}}},
+		{"dollar quote", "SELECT $fn$ abc $fn$", PostgreSQL, nil},
+		{"unterminated comment", "SELECT 1 /* abc", StandardSQL, []Diagnostic{{9, 15, "unterminated block comment"}}},
+		{"unmatched parenthesis", "SELECT a) FROM t", StandardSQL, []Diagnostic{{8, 9, "unmatched closing parenthesis"}}},
+		{"unclosed parenthesis", "SELECT (a FROM t", StandardSQL, []Diagnostic{{7, 8, "unclosed parenthesis"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Diagnose(tt.query, NewDefaultConfig().WithLang(tt.lang)))
+		})
+	}
+}
@@ -59,6 +59,84 @@ const (
 	BgColorBrightWhite  ANSIFormatOption = "\033[107m"
 )
 
+// ansiFormatNames maps the names accepted in configuration files to format options.
+var ansiFormatNames = map[string]ANSIFormatOption{
+	"none":      NoFormatting,
+	"bold":      FormatBold,
+	"dim":       FormatDim,
+	"underline": FormatUnderline,
+	"blink":     FormatBlink,
+	"reverse":   FormatReverse,
+	"hidden":    FormatHidden,
+
+	"red":    ColorRed,
+	"orange": ColorOrange,
+	"yellow": ColorYellow,
+	"green":  ColorGreen,
+	"blue":   ColorBlue,
+	"purple": ColorPurple,
+	"cyan":   ColorCyan,
+	"white":  ColorWhite,
+	"gray":   ColorGray,
+
+	"bg-red":    BgColorRed,
+	"bg-orange": BgColorOrange,
+	"bg-green":  BgColorGreen,
+	"bg-yellow": BgColorYellow,
+	"bg-blue":   BgColorBlue,
+	"bg-purple": BgColorPurple,
+	"bg-cyan":   BgColorCyan,
+	"bg-white":  BgColorWhite,
+	"bg-gray":   BgColorGray,
+
+	"bright-red":    ColorBrightRed,
+	"bright-green":  ColorBrightGreen,
+	"bright-yellow": ColorBrightYellow,
+	"bright-blue":   ColorBrightBlue,
+	"bright-purple": ColorBrightPurple,
+	"bright-cyan":   ColorBrightCyan,
+	"bright-white":  ColorBrightWhite,
+
+	"bg-bright-red":    BgColorBrightRed,
+	"bg-bright-green":  BgColorBrightGreen,
+	"bg-bright-yellow": BgColorBrightYellow,
+	"bg-bright-blue":   BgColorBrightBlue,
+	"bg-bright-purple": BgColorBrightPurple,
+	"bg-bright-cyan":   BgColorBrightCyan,
+	"bg-bright-white":  BgColorBrightWhite,
+}
+
+// ansiFormatAliases maps alternative spellings to the names in ansiFormatNames.
+var ansiFormatAliases = map[string]string{
+	"grey":    "gray",
+	"bg-grey": "bg-gray",
+}
+
+// ParseANSIFormatOption resolves a color or style name such as "bright-blue"
+// or "bold" to its format option. Underscores may be used instead of dashes.
+func ParseANSIFormatOption(name string) (ANSIFormatOption, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	if alias, ok := ansiFormatAliases[normalized]; ok {
+		normalized = alias
+	}
+	option, ok := ansiFormatNames[normalized]
+	if !ok {
+		return NoFormatting, fmt.Errorf("unknown color: %s", name)
+	}
+	return option, nil
+}
+
+// ANSIFormatOptionName returns the name ParseANSIFormatOption accepts for
+// option, or false if the option has no name.
+func ANSIFormatOptionName(option ANSIFormatOption) (string, bool) {
+	for name, candidate := range ansiFormatNames {
+		if candidate == option {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 func AddANSIFormats(options []ANSIFormatOption, s string) string {
 	for _, o := range options {
 		s = addANSIFormat(o, s)
@@ -437,3 +437,46 @@ func TestVisibleLength_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestParseANSIFormatOption(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected ANSIFormatOption
+	}{
+		{"red", ColorRed},
+		{"Bold", FormatBold},
+		{"bright-blue", ColorBrightBlue},
+		{"bright_blue", ColorBrightBlue},
+		{"bg-bright-cyan", BgColorBrightCyan},
+		{"grey", ColorGray},
+		{"none", NoFormatting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			option, err := ParseANSIFormatOption(tt.name)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, option)
+		})
+	}
+
+	_, err := ParseANSIFormatOption("mauve")
+	require.EqualError(t, err, "unknown color: mauve")
+}
+
+func TestANSIFormatOptionName(t *testing.T) {
+	for _, option := range []ANSIFormatOption{FormatBold, ColorGray, BgColorGray, ColorBrightBlue, BgColorBrightWhite} {
+		name, ok := ANSIFormatOptionName(option)
+		require.True(t, ok)
+
+		parsed, err := ParseANSIFormatOption(name)
+		require.NoError(t, err)
+		require.Equal(t, option, parsed)
+	}
+
+	name, _ := ANSIFormatOptionName(ColorGray)
+	require.Equal(t, "gray", name)
+
+	_, ok := ANSIFormatOptionName(ANSIFormatOption("\033[38;5;99m"))
+	require.False(t, ok)
+}